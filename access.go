@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const accessCheckInterval = 5 * time.Minute
+
+type ChatAccess struct {
+	cfg   *Config
+	botID int64
+
+	mu       sync.Mutex
+	lost     bool
+	restored bool
+}
+
+func newChatAccess(cfg *Config) *ChatAccess {
+	return &ChatAccess{cfg: cfg}
+}
+
+func (a *ChatAccess) Allowed() bool {
+	if a == nil {
+		return true
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return !a.lost
+}
+
+func (a *ChatAccess) Report(err error) {
+	if a == nil {
+		return
+	}
+	var tgErr *TelegramError
+	if errors.As(err, &tgErr) && tgErr.NoAccess() {
+		a.set(false, tgErr.Description)
+	}
+}
+
+func (a *ChatAccess) HandleUpdate(ctx context.Context, u TelegramUpdate) {
+	m := u.MyChatMember
+	if m == nil || m.Chat.ID != *a.cfg.Telegram.ChatID {
+		return
+	}
+	slog.Info("bot membership changed", "chat_id", m.Chat.ID, "from", m.OldChatMember.Status, "to", m.NewChatMember.Status)
+	if m.NewChatMember.CanPost() {
+		a.set(true, "")
+	} else {
+		a.set(false, "bot status changed to "+m.NewChatMember.Status)
+	}
+}
+
+func (a *ChatAccess) takeRestored() bool {
+	if a == nil {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	restored := a.restored
+	a.restored = false
+	return restored
+}
+
+func (a *ChatAccess) Run(ctx context.Context) {
+	ticker := time.NewTicker(accessCheckInterval)
+	defer ticker.Stop()
+	for {
+		a.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *ChatAccess) Check(ctx context.Context) {
+	cfg := a.cfg
+	if a.botID == 0 {
+		a.botID = getBotUserID(ctx, cfg.Telegram.BotToken)
+		if a.botID == 0 {
+			return
+		}
+	}
+
+	member, err := getChatMember(cfg.Telegram.BotToken, *cfg.Telegram.ChatID, a.botID)
+	var tgErr *TelegramError
+	switch {
+	case errors.As(err, &tgErr) && tgErr.NoAccess():
+		a.set(false, tgErr.Description)
+	case err != nil:
+		slog.Warn("chat permission check failed", "error", err)
+	case !member.CanPost():
+		a.set(false, "bot status is "+member.Status+" without permission to post")
+	default:
+		a.set(true, "")
+	}
+}
+
+func (a *ChatAccess) set(allowed bool, reason string) {
+	a.mu.Lock()
+	changed := a.lost == allowed
+	a.lost = !allowed
+	if changed {
+		a.restored = allowed
+	}
+	a.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	chatID := *a.cfg.Telegram.ChatID
+	if allowed {
+		slog.Info("bot can post to the chat again", "chat_id", chatID)
+		notifyAdmin(a.cfg, fmt.Sprintf("✅ Bot can post to chat <code>%d</code> again, notifications resumed.", chatID))
+		return
+	}
+	slog.Warn("bot lost access to the chat, pausing notifications", "chat_id", chatID, "reason", reason)
+	notifyAdmin(a.cfg, fmt.Sprintf(
+		"⚠️ Bot can no longer post to chat <code>%d</code>: %s\nNotifications are paused until access is restored.",
+		chatID, escapeHTML(reason),
+	))
+}
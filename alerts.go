@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type AlertConfig struct {
+	Condition string `json:"condition"`
+	Viewers   int    `json:"viewers"`
+	Minutes   int    `json:"minutes"`
+}
+
+var alertConditions = []string{"viewers_below", "viewers_above", "uptime"}
+
+type AlertMonitor struct {
+	cfg    *Config
+	active []bool
+}
+
+func newAlertMonitor(cfg *Config) *AlertMonitor {
+	return &AlertMonitor{cfg: cfg, active: make([]bool, len(cfg.Alerts))}
+}
+
+func (m *AlertMonitor) Handle(ctx context.Context, ev Event) {
+	switch ev.Type {
+	case EventStreamStarted, EventStreamEnded:
+		clear(m.active)
+		return
+	case EventStreamUpdated:
+	default:
+		return
+	}
+
+	for i, alert := range m.cfg.Alerts {
+		triggered := alertTriggered(alert, ev)
+		if triggered && !m.active[i] {
+			text := formatAlert(alert, ev)
+			slog.Info("threshold alert", "condition", alert.Condition, "viewers", ev.Info.Viewers)
+			notifyAdmin(m.cfg, text)
+		}
+		m.active[i] = triggered
+	}
+}
+
+func alertTriggered(alert AlertConfig, ev Event) bool {
+	window := time.Duration(alert.Minutes) * time.Minute
+	switch alert.Condition {
+	case "uptime":
+		return ev.Time.Sub(ev.Session.StartTime) >= window
+	case "viewers_below":
+		return viewersHeld(ev.Session.ViewerHistory, ev.Time, window, func(n int) bool { return n < alert.Viewers })
+	case "viewers_above":
+		return viewersHeld(ev.Session.ViewerHistory, ev.Time, window, func(n int) bool { return n > alert.Viewers })
+	}
+	return false
+}
+
+func viewersHeld(history []ViewerDataPoint, now time.Time, window time.Duration, cond func(int) bool) bool {
+	if len(history) == 0 || now.Sub(history[0].Timestamp) < window {
+		return false
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		p := history[i]
+		if !cond(p.Count) {
+			return false
+		}
+		if now.Sub(p.Timestamp) >= window {
+			return true
+		}
+	}
+	return true
+}
+
+func formatAlert(alert AlertConfig, ev Event) string {
+	channel := escapeHTML(ev.Channel)
+	switch alert.Condition {
+	case "uptime":
+		return fmt.Sprintf("⏰ <b>%s</b> has been live for %s", channel, time.Duration(alert.Minutes)*time.Minute)
+	case "viewers_below":
+		return fmt.Sprintf("📉 <b>%s</b>: viewers below %d for %d min (now %d)", channel, alert.Viewers, alert.Minutes, ev.Info.Viewers)
+	default:
+		return fmt.Sprintf("📈 <b>%s</b>: viewers above %d for %d min (now %d)", channel, alert.Viewers, alert.Minutes, ev.Info.Viewers)
+	}
+}
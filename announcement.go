@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+func announcementKey(cfg *Config, channel string) string {
+	key := fmt.Sprintf("%s/%d", strings.ToLower(channel), *cfg.Telegram.ChatID)
+	if cfg.Telegram.ThreadID != nil {
+		key += fmt.Sprintf("/%d", *cfg.Telegram.ThreadID)
+	}
+	return key
+}
+
+func (n *TelegramNotifier) resumeAnnouncement(ctx context.Context, ev Event) bool {
+	if stateStore == nil {
+		return false
+	}
+	var stored StoredAnnouncement
+	var ok bool
+	stateStore.View(func(st *State) {
+		if stored, ok = st.Announcements[announcementKey(n.cfg, ev.Channel)]; !ok {
+			stored, ok = st.Announcements[strings.ToLower(ev.Channel)]
+			ok = ok && stored.ChatID == *n.cfg.Telegram.ChatID
+		}
+	})
+	if !ok {
+		return false
+	}
+	if stored.ChatID != *n.cfg.Telegram.ChatID || !stored.StartedAt.Equal(ev.Session.StartTime) {
+		slog.Info("stored announcement belongs to another stream, ignoring it", "message_id", stored.MessageID)
+		forgetAnnouncement(n.cfg, ev)
+		return false
+	}
+
+	slog.Info("resuming announcement posted before restart", "message_id", stored.MessageID)
+	ev.Session.MessageID = stored.MessageID
+	ev.Session.Bot = stored.Bot
+	ev.Session.ThreadID = stored.ThreadID
+	n.queueUpdate(ctx, ev)
+	return true
+}
+
+func rememberAnnouncement(cfg *Config, ev Event) {
+	if stateStore == nil {
+		return
+	}
+	err := stateStore.Update(func(st *State) {
+		if st.Announcements == nil {
+			st.Announcements = map[string]StoredAnnouncement{}
+		}
+		dropLegacyAnnouncement(cfg, st, ev.Channel)
+		st.Announcements[announcementKey(cfg, ev.Channel)] = StoredAnnouncement{
+			ChatID:    *cfg.Telegram.ChatID,
+			MessageID: ev.Session.MessageID,
+			Bot:       ev.Session.Bot,
+			ThreadID:  ev.Session.ThreadID,
+			StartedAt: ev.Session.StartTime,
+		}
+	})
+	if err != nil {
+		slog.Warn("failed to save announcement", "error", err)
+	}
+}
+
+func forgetAnnouncement(cfg *Config, ev Event) {
+	if stateStore == nil {
+		return
+	}
+	err := stateStore.Update(func(st *State) {
+		delete(st.Announcements, announcementKey(cfg, ev.Channel))
+		dropLegacyAnnouncement(cfg, st, ev.Channel)
+	})
+	if err != nil {
+		slog.Warn("failed to save announcement", "error", err)
+	}
+}
+
+func dropLegacyAnnouncement(cfg *Config, st *State, channel string) {
+	legacy := strings.ToLower(channel)
+	if stored, ok := st.Announcements[legacy]; ok && stored.ChatID == *cfg.Telegram.ChatID {
+		delete(st.Announcements, legacy)
+	}
+}
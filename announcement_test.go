@@ -0,0 +1,51 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAnnouncementKeyedByTarget(t *testing.T) {
+	store, err := openStateStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(old *StateStore) { stateStore = old }(stateStore)
+	stateStore = store
+
+	thread := 7
+	chatA, chatB := int64(-100), int64(-200)
+	notifier := func(chatID int64, threadID *int) *TelegramNotifier {
+		cfg := &Config{}
+		cfg.Telegram.ChatID, cfg.Telegram.ThreadID = &chatID, threadID
+		return &TelegramNotifier{cfg: cfg}
+	}
+	a, b, c := notifier(chatA, nil), notifier(chatB, nil), notifier(chatB, &thread)
+
+	started := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	store.Update(func(st *State) {
+		st.Announcements = map[string]StoredAnnouncement{"somechannel": {ChatID: chatA, MessageID: 1, StartedAt: started}}
+	})
+	for i, n := range []*TelegramNotifier{a, b, c} {
+		rememberAnnouncement(n.cfg, Event{Channel: "SomeChannel", Session: &StreamSession{MessageID: 10 + i, StartTime: started}})
+	}
+
+	var got map[string]StoredAnnouncement
+	store.View(func(st *State) { got = st.Announcements })
+	want := map[string]int{"somechannel/-100": 10, "somechannel/-200": 11, "somechannel/-200/7": 12}
+	if len(got) != len(want) {
+		t.Fatalf("announcements = %v", got)
+	}
+	for key, id := range want {
+		if got[key].MessageID != id {
+			t.Fatalf("%s: message %d, want %d", key, got[key].MessageID, id)
+		}
+	}
+
+	forgetAnnouncement(b.cfg, Event{Channel: "somechannel"})
+	store.View(func(st *State) { got = st.Announcements })
+	if _, ok := got["somechannel/-200"]; ok || len(got) != 2 {
+		t.Fatalf("forget removed the wrong entries: %v", got)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type approvalState int
+
+const (
+	approvalNone approvalState = iota
+	approvalPending
+	approvalApproved
+	approvalSkipped
+)
+
+type ApprovalGate struct {
+	cfg     *Config
+	control *MonitorControl
+
+	mu        sync.Mutex
+	key       string
+	state     approvalState
+	messageID int
+	caption   string
+}
+
+func newApprovalGate(cfg *Config, control *MonitorControl) *ApprovalGate {
+	return &ApprovalGate{cfg: cfg, control: control}
+}
+
+func (g *ApprovalGate) Approved(ev Event, photoURL, caption string) bool {
+	if g == nil {
+		return true
+	}
+	key := strconv.FormatInt(ev.Session.StartTime.Unix(), 10)
+
+	g.mu.Lock()
+	if g.key != key {
+		g.key = key
+		g.state = approvalNone
+		g.messageID = 0
+		g.caption = ""
+	}
+	state := g.state
+	g.mu.Unlock()
+
+	switch state {
+	case approvalApproved:
+		return true
+	case approvalNone:
+		g.request(key, photoURL, caption)
+	}
+	return false
+}
+
+func (g *ApprovalGate) request(key, photoURL, caption string) {
+	cfg := g.cfg
+	keyboard := [][]InlineButton{{
+		{Text: "✅ Approve", CallbackData: "approve:" + key},
+		{Text: "⏭ Skip", CallbackData: "skip:" + key},
+	}}
+	messageID, err := sendPhotoMessage(cfg.Telegram.BotToken, *cfg.Telegram.AdminChatID, nil, photoURL, caption, keyboard, SendOptions{})
+	if err != nil {
+		slog.Error("failed to send announcement for approval", "error", err)
+		return
+	}
+	slog.Info("start announcement waiting for approval", "message_id", messageID)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.key == key {
+		g.state = approvalPending
+		g.messageID = messageID
+		g.caption = caption
+	}
+}
+
+func (g *ApprovalGate) HandleUpdate(ctx context.Context, u TelegramUpdate) {
+	q := u.CallbackQuery
+	if q == nil || q.Message == nil {
+		return
+	}
+	action, key, ok := strings.Cut(q.Data, ":")
+	if !ok || (action != "approve" && action != "skip") {
+		return
+	}
+	cfg := g.cfg
+	if !isAdmin(cfg, q.Message.Chat.ID, &q.From) {
+		g.answer(q.ID, "Not allowed")
+		return
+	}
+
+	g.mu.Lock()
+	current := g.key == key && g.state == approvalPending
+	caption := g.caption
+	if current {
+		g.state = approvalApproved
+		if action == "skip" {
+			g.state = approvalSkipped
+		}
+	}
+	g.mu.Unlock()
+
+	if !current {
+		g.answer(q.ID, "This announcement is no longer pending")
+		return
+	}
+
+	status := "✅ Approved"
+	if action == "skip" {
+		status = "⏭ Skipped"
+	}
+	slog.Info("start announcement reviewed", "action", action, "user_id", q.From.ID)
+	g.answer(q.ID, status)
+	caption += fmt.Sprintf("\n\n<i>%s by %s</i>", status, escapeHTML(userLabel(q.From)))
+	if err := editMessageCaption(cfg.Telegram.BotToken, q.Message.Chat.ID, q.Message.MessageID, caption, nil); err != nil {
+		slog.Warn("failed to update approval message", "error", err)
+	}
+	if action == "approve" {
+		g.control.Wake()
+	}
+}
+
+func (g *ApprovalGate) answer(queryID, text string) {
+	if err := answerCallbackQuery(g.cfg.Telegram.BotToken, queryID, text); err != nil {
+		slog.Warn("failed to answer callback query", "error", err)
+	}
+}
+
+func userLabel(u TelegramUser) string {
+	if u.Username != "" {
+		return "@" + u.Username
+	}
+	if u.FirstName != "" {
+		return u.FirstName
+	}
+	return strconv.FormatInt(u.ID, 10)
+}
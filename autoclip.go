@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type AutoClipConfig struct {
+	Enabled         bool `json:"enabled"`
+	SpikePercent    int  `json:"spike_percent"`
+	WindowMinutes   int  `json:"window_minutes"`
+	MinViewers      int  `json:"min_viewers"`
+	CooldownMinutes int  `json:"cooldown_minutes"`
+}
+
+type AutoClipper struct {
+	cfg      *Config
+	format   MessageFormat
+	lastClip time.Time
+}
+
+func newAutoClipper(cfg *Config) *AutoClipper {
+	return &AutoClipper{cfg: cfg, format: newMessageFormat(cfg)}
+}
+
+func (a *AutoClipper) Handle(ctx context.Context, ev Event) {
+	if ev.Type != EventStreamUpdated {
+		return
+	}
+	ac := a.cfg.AutoClip
+	if ev.Time.Sub(a.lastClip) < time.Duration(ac.CooldownMinutes)*time.Minute {
+		return
+	}
+
+	base, ok := viewerBaseline(ev.Session.ViewerHistory, ev.Time, time.Duration(ac.WindowMinutes)*time.Minute)
+	if !ok || base < ac.MinViewers {
+		return
+	}
+	growth := (ev.Info.Viewers - base) * 100 / base
+	if growth < ac.SpikePercent {
+		return
+	}
+
+	slog.Info("viewer spike detected, creating clip", "from", base, "to", ev.Info.Viewers, "growth", growth)
+	clipID, err := createClip(ctx, a.cfg, ev.Session.BroadcasterID)
+	if err != nil {
+		slog.Error("failed to create clip", "error", err)
+		return
+	}
+	a.lastClip = ev.Time
+
+	text := fmt.Sprintf("🎬 <b>%s</b> • %s +%d%%", escapeHTML(ev.Channel), a.format.ViewerSpike, growth)
+	go a.post(ctx, clipID, text)
+}
+
+func (a *AutoClipper) post(ctx context.Context, clipID, text string) {
+	cfg := a.cfg
+	for range 6 {
+		sleep(ctx, 10*time.Second)
+		if ctx.Err() != nil {
+			return
+		}
+		clip, err := getClip(ctx, clipID, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+		if err != nil {
+			slog.Warn("failed to check created clip", "id", clipID, "error", err)
+			continue
+		}
+		if clip == nil {
+			continue
+		}
+		text += "\n\n" + formatLink(clip.URL, clip.Title)
+		if err := sendTextMessage(cfg.Telegram.BotToken, *cfg.Telegram.ChatID, cfg.Telegram.ThreadID, text); err != nil {
+			slog.Error("failed to post spike clip", "error", err)
+		} else {
+			slog.Info("spike clip posted", "url", clip.URL)
+		}
+		return
+	}
+	slog.Warn("created clip never became available", "id", clipID)
+}
+
+func viewerBaseline(history []ViewerDataPoint, now time.Time, window time.Duration) (int, bool) {
+	if len(history) < 2 || now.Sub(history[0].Timestamp) < window {
+		return 0, false
+	}
+	base := -1
+	for _, p := range history[:len(history)-1] {
+		if now.Sub(p.Timestamp) > window {
+			continue
+		}
+		if base < 0 || p.Count < base {
+			base = p.Count
+		}
+	}
+	return base, base > 0
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	highlightGap     = 5 * time.Minute
+	highlightVODLead = 30 * time.Second
+)
+
+type AutoHighlightsConfig struct {
+	Enabled       bool    `json:"enabled"`
+	ChatFactor    float64 `json:"chat_factor"`
+	MinMessages   int     `json:"min_messages"`
+	ViewerPercent int     `json:"viewer_percent"`
+	Max           int     `json:"max"`
+}
+
+type Highlight struct {
+	Time       time.Time `json:"time"`
+	Messages   int       `json:"messages"`
+	ViewerGain int       `json:"viewer_gain,omitempty"`
+	URL        string    `json:"url,omitempty"`
+	score      float64
+}
+
+type HighlightDetector struct {
+	cfg *Config
+
+	mu      sync.Mutex
+	live    bool
+	minutes map[time.Time]int
+}
+
+func newHighlightDetector(cfg *Config) *HighlightDetector {
+	return &HighlightDetector{cfg: cfg, minutes: map[time.Time]int{}}
+}
+
+func (d *HighlightDetector) OnMessage(msg ChatMessage) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.live {
+		d.minutes[msg.Time.Truncate(time.Minute)]++
+	}
+}
+
+func (d *HighlightDetector) Handle(ctx context.Context, ev Event) {
+	switch ev.Type {
+	case EventStreamStarted:
+		d.mu.Lock()
+		d.live = true
+		d.minutes = map[time.Time]int{}
+		d.mu.Unlock()
+	case EventStreamUpdated:
+		d.mu.Lock()
+		d.live = true
+		d.mu.Unlock()
+	case EventStreamEnded:
+		d.mu.Lock()
+		d.live = false
+		minutes := d.minutes
+		d.minutes = map[time.Time]int{}
+		d.mu.Unlock()
+
+		highlights := detectHighlights(minutes, ev.Session.ViewerHistory, d.cfg.AutoHighlights)
+		if len(highlights) == 0 {
+			return
+		}
+		d.linkVOD(ctx, ev.Session, highlights)
+		slog.Info("chat highlights detected", "count", len(highlights))
+		ev.Session.Highlights = highlights
+	}
+}
+
+func (d *HighlightDetector) linkVOD(ctx context.Context, session *StreamSession, highlights []Highlight) {
+	cfg := d.cfg
+	if upstream != nil {
+		return
+	}
+	vod, err := getLatestArchive(ctx, session.BroadcasterID, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+	if err != nil {
+		slog.Warn("failed to get stream VOD", "error", err)
+		return
+	}
+	if vod == nil || vod.CreatedAt.Sub(session.StartTime).Abs() > 10*time.Minute {
+		slog.Info("no VOD found for the stream, highlights are listed without links")
+		return
+	}
+	for i := range highlights {
+		offset := highlights[i].Time.Sub(vod.CreatedAt) - highlightVODLead
+		highlights[i].URL = fmt.Sprintf("%s?t=%s", vod.URL, vodTimestamp(offset))
+	}
+}
+
+func detectHighlights(minutes map[time.Time]int, history []ViewerDataPoint, hc AutoHighlightsConfig) []Highlight {
+	if len(minutes) < 5 {
+		return nil
+	}
+	counts := make([]int, 0, len(minutes))
+	for _, c := range minutes {
+		counts = append(counts, c)
+	}
+	slices.Sort(counts)
+	median := float64(max(counts[len(counts)/2], 1))
+
+	var candidates []Highlight
+	for t, c := range minutes {
+		if c < hc.MinMessages || float64(c) < hc.ChatFactor*median {
+			continue
+		}
+		h := Highlight{Time: t, Messages: c, score: float64(c) / median}
+		if before, after, ok := viewersAround(history, t); ok && before > 0 {
+			if gain := after - before; gain*100/before >= hc.ViewerPercent {
+				h.ViewerGain = gain
+				h.score *= 1 + float64(gain)/float64(before)
+			}
+		}
+		candidates = append(candidates, h)
+	}
+	slices.SortFunc(candidates, func(a, b Highlight) int {
+		if a.score != b.score {
+			if a.score > b.score {
+				return -1
+			}
+			return 1
+		}
+		return a.Time.Compare(b.Time)
+	})
+
+	var picked []Highlight
+	for _, c := range candidates {
+		if len(picked) >= hc.Max {
+			break
+		}
+		if slices.ContainsFunc(picked, func(p Highlight) bool { return c.Time.Sub(p.Time).Abs() < highlightGap }) {
+			continue
+		}
+		picked = append(picked, c)
+	}
+	slices.SortFunc(picked, func(a, b Highlight) int { return a.Time.Compare(b.Time) })
+	return picked
+}
+
+func viewersAround(history []ViewerDataPoint, t time.Time) (before, after int, ok bool) {
+	before = -1
+	for _, p := range history {
+		switch {
+		case !p.Timestamp.After(t):
+			before = p.Count
+		case p.Timestamp.Sub(t) <= highlightGap:
+			after = max(after, p.Count)
+		}
+	}
+	return before, after, before >= 0 && after > 0
+}
+
+type HighlightEntry struct {
+	At         string
+	URL        string
+	Messages   int
+	ViewerGain int
+}
+
+func highlightEntries(highlights []Highlight, start time.Time, lang string) []HighlightEntry {
+	entries := make([]HighlightEntry, 0, len(highlights))
+	for _, h := range highlights {
+		entries = append(entries, HighlightEntry{
+			At:         formatDuration(h.Time.Sub(start), lang),
+			URL:        h.URL,
+			Messages:   h.Messages,
+			ViewerGain: h.ViewerGain,
+		})
+	}
+	return entries
+}
+
+func formatHighlights(highlights []HighlightEntry, mf MessageFormat) string {
+	if len(highlights) == 0 {
+		return ""
+	}
+	lines := []string{"🔥 " + mf.Highlights + ":"}
+	for _, h := range highlights {
+		at := h.At
+		if h.URL != "" {
+			at = formatLink(h.URL, h.At)
+		}
+		line := fmt.Sprintf("%s — %d %s", at, h.Messages, mf.PerMinute)
+		if h.ViewerGain > 0 {
+			line += fmt.Sprintf(", +%s %s", formatViewers(h.ViewerGain), mf.Viewers)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
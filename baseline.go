@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+const (
+	baselineHourWindow = 2
+	minutesPerWeek     = 7 * 24 * 60
+)
+
+type BaselineConfig struct {
+	Enabled     bool    `json:"enabled"`
+	Weeks       int     `json:"weeks"`
+	MinSessions int     `json:"min_sessions"`
+	Factor      float64 `json:"factor"`
+}
+
+type BaselineTracker struct {
+	cfg     *Config
+	archive *SessionArchive
+}
+
+func newBaselineTracker(cfg *Config, archive *SessionArchive) *BaselineTracker {
+	return &BaselineTracker{cfg: cfg, archive: archive}
+}
+
+func (b *BaselineTracker) Handle(ctx context.Context, ev Event) {
+	if ev.Type != EventStreamEnded {
+		return
+	}
+	records, err := b.archive.Load(ctx)
+	if err != nil {
+		slog.Warn("failed to load sessions for baseline", "error", err)
+		return
+	}
+	bc := b.cfg.Baseline
+	since := ev.Time.AddDate(0, 0, -7*bc.Weeks)
+	typical, n := channelBaseline(records, ev.Session.StartTime, since)
+	if n < bc.MinSessions {
+		slog.Debug("not enough sessions for baseline", "sessions", n)
+		return
+	}
+	ev.Session.Baseline = typical
+	slog.Info("channel baseline", "typical", typical, "sessions", n)
+}
+
+func channelBaseline(records []SessionRecord, start, since time.Time) (int, int) {
+	at := minuteOfWeek(start)
+	var avgs []int
+	for _, rec := range records {
+		if rec.StartTime.Before(since) || rec.AvgViewers == 0 {
+			continue
+		}
+		d := abs(minuteOfWeek(rec.StartTime) - at)
+		if min(d, minutesPerWeek-d) > baselineHourWindow*60 {
+			continue
+		}
+		avgs = append(avgs, rec.AvgViewers)
+	}
+	if len(avgs) == 0 {
+		return 0, 0
+	}
+	slices.Sort(avgs)
+	return avgs[len(avgs)/2], len(avgs)
+}
+
+func minuteOfWeek(t time.Time) int {
+	t = t.Local()
+	return (int(t.Weekday())*24+t.Hour())*60 + t.Minute()
+}
+
+type BaselineEntry struct {
+	Ratio   float64
+	Weekday time.Weekday
+}
+
+func baselineEntry(session *StreamSession, avg int, factor float64) *BaselineEntry {
+	if session.Baseline <= 0 || avg <= 0 {
+		return nil
+	}
+	ratio := float64(avg) / float64(session.Baseline)
+	if ratio < factor && ratio > 1/factor {
+		return nil
+	}
+	return &BaselineEntry{Ratio: ratio, Weekday: session.StartTime.Local().Weekday()}
+}
+
+func formatBaseline(b *BaselineEntry, mf MessageFormat) string {
+	if b == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.1f× %s", b.Ratio, fmt.Sprintf(mf.UsualAudience, mf.Weekdays[b.Weekday]))
+}
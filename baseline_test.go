@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChannelBaseline(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 1, day, hour, minute, 0, 0, time.Local)
+	}
+	start := at(10, 23, 30)
+	since := at(1, 0, 0)
+	records := []SessionRecord{
+		{StartTime: at(3, 22, 0), AvgViewers: 100},
+		{StartTime: at(4, 1, 0), AvgViewers: 300},
+		{StartTime: at(9, 23, 0), AvgViewers: 900},
+		{StartTime: at(3, 23, 45), AvgViewers: 200},
+		{StartTime: at(6, 23, 30), AvgViewers: 400},
+		{StartTime: at(3, 20, 0), AvgViewers: 50},
+		{StartTime: at(4, 2, 0), AvgViewers: 50},
+		{StartTime: at(3, 23, 0), AvgViewers: 0},
+		{StartTime: time.Date(2025, 12, 27, 23, 30, 0, 0, time.Local), AvgViewers: 50},
+	}
+
+	typical, n := channelBaseline(records, start, since)
+	if n != 3 || typical != 200 {
+		t.Fatalf("channelBaseline() = %d from %d sessions, want 200 from 3", typical, n)
+	}
+
+	typical, n = channelBaseline(records[:2], start, since)
+	if n != 2 || typical != 300 {
+		t.Fatalf("channelBaseline() = %d from %d sessions, want 300 from 2", typical, n)
+	}
+
+	typical, n = channelBaseline([]SessionRecord{{StartTime: at(3, 23, 30), AvgViewers: 70}}, at(11, 0, 30), since)
+	if n != 1 || typical != 70 {
+		t.Fatalf("Saturday night session for a Sunday stream = %d from %d sessions", typical, n)
+	}
+}
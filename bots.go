@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+const failoverRetryAfter = 60
+
+type failoverError struct {
+	error
+}
+
+func (failoverError) Permanent() bool {
+	return true
+}
+
+func (e failoverError) Unwrap() error {
+	return e.error
+}
+
+type BotPool struct {
+	tokens []string
+
+	mu      sync.Mutex
+	current int
+}
+
+func newBotPool(cfg *Config) *BotPool {
+	tokens := []string{cfg.Telegram.BotToken}
+	if cfg.Telegram.BackupBotToken != "" {
+		tokens = append(tokens, cfg.Telegram.BackupBotToken)
+	}
+	return &BotPool{tokens: tokens}
+}
+
+func (p *BotPool) Token(bot int) string {
+	if bot < 0 || bot >= len(p.tokens) {
+		return p.tokens[0]
+	}
+	return p.tokens[bot]
+}
+
+func (p *BotPool) Current() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.current
+}
+
+func (p *BotPool) guard(err error) error {
+	var tgErr *TelegramError
+	if len(p.tokens) < 2 || !errors.As(err, &tgErr) {
+		return err
+	}
+	if tgErr.Code == http.StatusUnauthorized || (tgErr.Code == http.StatusTooManyRequests && tgErr.RetryAfter >= failoverRetryAfter) {
+		return failoverError{err}
+	}
+	return err
+}
+
+func (p *BotPool) Failover(bot int, err error) bool {
+	var fe failoverError
+	if !errors.As(err, &fe) {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.current == bot {
+		p.current = (bot + 1) % len(p.tokens)
+		slog.Warn("telegram bot unavailable, switching to the other token", "from", bot, "to", p.current, "error", fe.error)
+	}
+	return true
+}
+
+func (p *BotPool) Send(ctx context.Context, policy RetryConfig, name string, send func(token string) error) (int, error) {
+	bot := p.Current()
+	var err error
+	for range p.tokens {
+		bot = p.Current()
+		err = retryWithBackoff(ctx, policy, func() error {
+			return p.guard(send(p.tokens[bot]))
+		}, name)
+		if !p.Failover(bot, err) {
+			break
+		}
+	}
+	return bot, err
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+type CaptionState struct {
+	mu        sync.Mutex
+	startTime time.Time
+	note      string
+	onBreak   bool
+}
+
+func (c *CaptionState) session(start time.Time) {
+	if !c.startTime.Equal(start) {
+		c.startTime = start
+		c.note = ""
+		c.onBreak = false
+	}
+}
+
+func (c *CaptionState) SetNote(start time.Time, note string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.session(start)
+	c.note = note
+}
+
+func (c *CaptionState) SetBreak(start time.Time, onBreak bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.session(start)
+	changed := c.onBreak != onBreak
+	c.onBreak = onBreak
+	return changed
+}
+
+func (c *CaptionState) Get(start time.Time) (note string, onBreak bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.startTime.Equal(start) {
+		return "", false
+	}
+	return c.note, c.onBreak
+}
+
+func repliesTo(reply *TelegramMessage, chatID int64, messageID int) bool {
+	if reply == nil || messageID == 0 {
+		return false
+	}
+	if reply.Chat.ID == chatID && reply.MessageID == messageID {
+		return true
+	}
+	origin := reply.ForwardOrigin
+	return origin != nil && origin.Chat != nil && origin.Chat.ID == chatID && origin.MessageID == messageID
+}
+
+func formatNote(note string) string {
+	if note == "" {
+		return ""
+	}
+	return "📌 " + escapeHTML(note)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	channelCheckInterval = 10 * time.Minute
+	channelMissThreshold = 3
+)
+
+type ChannelGuard struct {
+	cfg           *Config
+	broadcasterID string
+	lastCheck     time.Time
+	misses        int
+	alerted       bool
+}
+
+func newChannelGuard(cfg *Config) *ChannelGuard {
+	return &ChannelGuard{cfg: cfg}
+}
+
+func (g *ChannelGuard) Check(ctx context.Context, now time.Time) {
+	if upstream != nil || now.Sub(g.lastCheck) < channelCheckInterval {
+		return
+	}
+	g.lastCheck = now
+
+	cfg := g.cfg
+	user, err := getTwitchUser(ctx, cfg.Twitch.Channel, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+	if err != nil && !errors.Is(err, errBroadcasterNotFound) {
+		slog.Warn("channel lookup failed", "error", err)
+		return
+	}
+
+	if user != nil {
+		if g.alerted {
+			slog.Info("channel found again", "channel", cfg.Twitch.Channel)
+			notifyAdmin(cfg, fmt.Sprintf("Channel <b>%s</b> is available on Twitch again.", escapeHTML(cfg.Twitch.Channel)))
+		}
+		g.broadcasterID = user.ID
+		g.misses = 0
+		g.alerted = false
+		return
+	}
+
+	g.misses++
+	slog.Warn("channel not found", "channel", cfg.Twitch.Channel, "misses", g.misses)
+	if g.misses < channelMissThreshold || g.alerted {
+		return
+	}
+	g.alerted = true
+
+	if g.broadcasterID != "" {
+		renamed, err := getTwitchUserByID(ctx, g.broadcasterID, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+		if err == nil && renamed != nil {
+			slog.Warn("channel renamed", "from", cfg.Twitch.Channel, "to", renamed.Login)
+			notifyAdmin(cfg, fmt.Sprintf(
+				"Channel <b>%s</b> was renamed to <b>%s</b>.\nUpdate <code>twitch.channel</code> in config.json and restart the bot.",
+				escapeHTML(cfg.Twitch.Channel), escapeHTML(renamed.Login),
+			))
+			return
+		}
+	}
+
+	notifyAdmin(cfg, fmt.Sprintf(
+		"Channel <b>%s</b> can no longer be found on Twitch. It may have been renamed, banned or deactivated.\nCheck the channel and update <code>twitch.channel</code> in config.json if needed.",
+		escapeHTML(cfg.Twitch.Channel),
+	))
+}
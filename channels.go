@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type ChannelTarget struct {
+	Channel  string `json:"channel"`
+	ChatID   int64  `json:"chat_id"`
+	ThreadID *int   `json:"thread_id"`
+}
+
+func formatTarget(t ChannelTarget) string {
+	if t.ThreadID != nil {
+		return fmt.Sprintf("%d (thread %d)", t.ChatID, *t.ThreadID)
+	}
+	return fmt.Sprintf("%d", t.ChatID)
+}
+
+func targetConfig(cfg *Config, t ChannelTarget) *Config {
+	c := *cfg
+	c.Twitch.Channel = t.Channel
+	chatID := t.ChatID
+	c.Telegram.ChatID = &chatID
+	c.Telegram.ThreadID = t.ThreadID
+	ext := filepath.Ext(cfg.HistoryFile)
+	c.HistoryFile = fmt.Sprintf("%s-%s-%d%s", strings.TrimSuffix(cfg.HistoryFile, ext), strings.ToLower(t.Channel), t.ChatID, ext)
+	return &c
+}
+
+func runChannelTarget(ctx context.Context, cfg *Config, archive *SessionArchive, access *ChatAccess, live *LiveState, feed *EventFeed) {
+	bus := &EventBus{}
+	go access.Run(ctx)
+	bus.Subscribe(newTelegramNotifier(cfg, nil, access, nil, nil).Handle)
+	bus.Subscribe(archive.Handle)
+	if live != nil {
+		bus.Subscribe(live.Handle)
+		bus.Subscribe(feed.Handle)
+	}
+	bus.Subscribe(logStreamStats)
+	monitorLoop(ctx, cfg, streamSourceFor(cfg), bus, newMonitorControl())
+}
+
+func monitoredChannels(cfg *Config) []string {
+	channels := []string{strings.ToLower(cfg.Twitch.Channel)}
+	for _, t := range cfg.Channels {
+		channels = append(channels, strings.ToLower(t.Channel))
+	}
+	slices.Sort(channels)
+	return slices.Compact(channels)
+}
+
+func validateChannelTargets(targets []ChannelTarget) error {
+	for i, t := range targets {
+		if t.Channel == "" || t.ChatID == 0 {
+			return fmt.Errorf("channels[%d]: channel and chat_id are required", i)
+		}
+	}
+	return nil
+}
+
+func startChannelTargets(ctx context.Context, cfg *Config, store Storage, poller *UpdatePoller, feed *EventFeed) (map[string]*SessionArchive, map[string]*LiveState) {
+	archives := map[string]*SessionArchive{}
+	lives := map[string]*LiveState{}
+	for _, t := range cfg.Channels {
+		slog.Info("starting monitor for additional channel", "channel", t.Channel, "chat_id", t.ChatID)
+		target := targetConfig(cfg, t)
+		access := newChatAccess(target)
+		poller.Subscribe(access.HandleUpdate, "my_chat_member")
+		archive := newSessionArchive(store, target.HistoryFile, nil)
+		var live *LiveState
+		if channel := strings.ToLower(t.Channel); archives[channel] == nil {
+			archives[channel] = archive
+			if channel != strings.ToLower(cfg.Twitch.Channel) {
+				live = &LiveState{}
+				lives[channel] = live
+			}
+		}
+		go runChannelTarget(ctx, target, archive, access, live, feed)
+	}
+	return archives, lives
+}
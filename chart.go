@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+const (
+	chartWidth   = 1000
+	chartHeight  = 500
+	chartPadding = 40
+)
+
+var (
+	chartBackground = color.RGBA{0x18, 0x18, 0x1b, 0xff}
+	chartGrid       = color.RGBA{0x3a, 0x3a, 0x3d, 0xff}
+	chartFill       = color.RGBA{0x45, 0x2a, 0x7a, 0xff}
+	chartLine       = color.RGBA{0x91, 0x46, 0xff, 0xff}
+	chartPeak       = color.RGBA{0xff, 0xd3, 0x4f, 0xff}
+	chartMark       = color.RGBA{0x00, 0xdb, 0x84, 0xff}
+)
+
+func renderViewerChart(history []ViewerDataPoint, marks []StreamMark) ([]byte, error) {
+	if len(history) < 2 {
+		return nil, errors.New("not enough viewer samples for a chart")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, chartHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(chartBackground), image.Point{}, draw.Src)
+
+	plot := image.Rect(chartPadding, chartPadding, chartWidth-chartPadding, chartHeight-chartPadding)
+	for i := 0; i <= 4; i++ {
+		y := plot.Max.Y - i*plot.Dy()/4
+		draw.Draw(img, image.Rect(plot.Min.X, y, plot.Max.X, y+1), image.NewUniform(chartGrid), image.Point{}, draw.Src)
+	}
+
+	peak := getMaxViewers(history)
+	top := max(peak.Count, 1)
+	start, end := history[0].Timestamp, history[len(history)-1].Timestamp
+	span := max(end.Sub(start), 1)
+
+	points := make([]image.Point, len(history))
+	for i, p := range history {
+		points[i] = image.Point{
+			X: plot.Min.X + int(float64(plot.Dx())*float64(p.Timestamp.Sub(start))/float64(span)),
+			Y: plot.Max.Y - plot.Dy()*p.Count/top,
+		}
+	}
+
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		for x := a.X; x <= b.X; x++ {
+			y := a.Y
+			if b.X != a.X {
+				y = a.Y + (b.Y-a.Y)*(x-a.X)/(b.X-a.X)
+			}
+			draw.Draw(img, image.Rect(x, y, x+1, plot.Max.Y), image.NewUniform(chartFill), image.Point{}, draw.Src)
+		}
+	}
+	for _, m := range marks {
+		if m.Time.Before(start) {
+			continue
+		}
+		offset := min(m.Time.Sub(start), span)
+		x := plot.Min.X + int(float64(plot.Dx())*float64(offset)/float64(span))
+		for y := plot.Min.Y; y < plot.Max.Y; y += 12 {
+			draw.Draw(img, image.Rect(x-1, y, x+1, min(y+6, plot.Max.Y)), image.NewUniform(chartMark), image.Point{}, draw.Src)
+		}
+	}
+	for i := 1; i < len(points); i++ {
+		drawChartLine(img, points[i-1], points[i], 3, chartLine)
+	}
+	for i, p := range history {
+		if p == peak {
+			c := points[i]
+			draw.Draw(img, image.Rect(c.X-6, c.Y-6, c.X+6, c.Y+6), image.NewUniform(chartPeak), image.Point{}, draw.Src)
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func drawChartLine(img *image.RGBA, a, b image.Point, width int, c color.Color) {
+	steps := max(abs(b.X-a.X), abs(b.Y-a.Y), 1)
+	half := width / 2
+	for i := 0; i <= steps; i++ {
+		x := a.X + (b.X-a.X)*i/steps
+		y := a.Y + (b.Y-a.Y)*i/steps
+		draw.Draw(img, image.Rect(x-half, y-half, x-half+width, y-half+width), image.NewUniform(c), image.Point{}, draw.Src)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
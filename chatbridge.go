@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+type ChatBridge struct {
+	cfg           *Config
+	broadcasterID string
+	senderID      string
+}
+
+func newChatBridge(cfg *Config) *ChatBridge {
+	return &ChatBridge{cfg: cfg}
+}
+
+func (b *ChatBridge) Say(ctx context.Context, msg *TelegramMessage, args string) (string, error) {
+	if args == "" {
+		return "Usage: /say &lt;message&gt;", nil
+	}
+	if err := b.send(ctx, args); err != nil {
+		return "", err
+	}
+	slog.Info("message sent to twitch chat", "text", args)
+	return "✅", nil
+}
+
+func (b *ChatBridge) send(ctx context.Context, text string) error {
+	cfg := b.cfg
+	if b.broadcasterID == "" {
+		id, err := getBroadcasterID(ctx, cfg.Twitch.Channel, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+		if err != nil {
+			return err
+		}
+		b.broadcasterID = id
+	}
+	if b.senderID == "" {
+		id, err := getTokenUserID(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		b.senderID = id
+	}
+
+	var resp struct {
+		Data []struct {
+			IsSent     bool `json:"is_sent"`
+			DropReason *struct {
+				Message string `json:"message"`
+			} `json:"drop_reason"`
+		} `json:"data"`
+	}
+	body := map[string]string{
+		"broadcaster_id": b.broadcasterID,
+		"sender_id":      b.senderID,
+		"message":        text,
+	}
+	if err := twitchUserRequest(ctx, cfg, "POST", helixAPI+"/chat/messages", body, &resp); err != nil {
+		return err
+	}
+	if len(resp.Data) == 0 {
+		return errors.New("twitch did not confirm the message")
+	}
+	if d := resp.Data[0]; !d.IsSent {
+		if d.DropReason != nil {
+			return fmt.Errorf("message dropped: %s", d.DropReason.Message)
+		}
+		return errors.New("message dropped")
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+type ChatModesConfig struct {
+	Enabled bool `json:"enabled"`
+	Caption bool `json:"caption"`
+}
+
+type ChatModes struct {
+	Shield      bool
+	EmoteOnly   bool
+	SubOnly     bool
+	Unique      bool
+	FollowersOn bool
+	Followers   int
+	Slow        int
+}
+
+type ChatModeWatcher struct {
+	cfg         *Config
+	format      MessageFormat
+	moderatorID string
+	last        *ChatModes
+}
+
+func newChatModeWatcher(cfg *Config) *ChatModeWatcher {
+	return &ChatModeWatcher{cfg: cfg, format: newMessageFormat(cfg)}
+}
+
+func (w *ChatModeWatcher) Handle(ctx context.Context, ev Event) {
+	switch ev.Type {
+	case EventStreamStarted:
+		w.last = nil
+	case EventStreamUpdated:
+	default:
+		return
+	}
+
+	modes, err := w.fetch(ctx, ev.Session.BroadcasterID)
+	if err != nil {
+		slog.Warn("failed to get chat modes", "error", err)
+		if w.last != nil && w.cfg.ChatModes.Caption {
+			ev.Info.ChatModes = *w.last
+		}
+		return
+	}
+	if w.cfg.ChatModes.Caption {
+		ev.Info.ChatModes = modes
+	}
+
+	if w.last != nil && *w.last != modes {
+		text := formatChatModes(modes, w.format)
+		if text == "" {
+			text = "—"
+		}
+		slog.Info("chat modes changed", "modes", text)
+		notifyAdmin(w.cfg, fmt.Sprintf("💬 <b>%s</b> chat: %s", escapeHTML(ev.Channel), text))
+	}
+	w.last = &modes
+}
+
+func (w *ChatModeWatcher) fetch(ctx context.Context, broadcasterID string) (ChatModes, error) {
+	cfg := w.cfg
+	var modes ChatModes
+
+	settings, err := getChatSettings(ctx, broadcasterID, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+	if err != nil {
+		return modes, err
+	}
+	modes.EmoteOnly = settings.EmoteMode
+	modes.SubOnly = settings.SubscriberMode
+	modes.Unique = settings.UniqueChatMode
+	modes.FollowersOn = settings.FollowerMode
+	if settings.FollowerModeDuration != nil {
+		modes.Followers = *settings.FollowerModeDuration
+	}
+	if settings.SlowMode && settings.SlowModeWaitTime != nil {
+		modes.Slow = *settings.SlowModeWaitTime
+	}
+
+	if cfg.Twitch.UserToken == "" {
+		return modes, nil
+	}
+	if w.moderatorID == "" {
+		if w.moderatorID, err = getTokenUserID(ctx, cfg); err != nil {
+			return modes, err
+		}
+	}
+	var shield struct {
+		Data []struct {
+			IsActive bool `json:"is_active"`
+		} `json:"data"`
+	}
+	url := fmt.Sprintf("%s/moderation/shield_mode?broadcaster_id=%s&moderator_id=%s", helixAPI, broadcasterID, w.moderatorID)
+	if err := twitchUserRequest(ctx, cfg, "GET", url, nil, &shield); err != nil {
+		return modes, err
+	}
+	modes.Shield = len(shield.Data) > 0 && shield.Data[0].IsActive
+	return modes, nil
+}
+
+func getTokenUserID(ctx context.Context, cfg *Config) (string, error) {
+	var resp struct {
+		Data []TwitchUser `json:"data"`
+	}
+	if err := twitchUserRequest(ctx, cfg, "GET", helixAPI+"/users", nil, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Data) == 0 {
+		return "", errors.New("user token has no associated user")
+	}
+	return resp.Data[0].ID, nil
+}
+
+func formatChatModes(m ChatModes, mf MessageFormat) string {
+	var parts []string
+	if m.Shield {
+		parts = append(parts, "🛡 "+mf.ShieldMode)
+	}
+	if m.EmoteOnly {
+		parts = append(parts, "😀 "+mf.EmoteOnly)
+	}
+	if m.SubOnly {
+		parts = append(parts, "💎 "+mf.SubOnly)
+	}
+	if m.FollowersOn {
+		p := "👥 " + mf.FollowersOnly
+		if m.Followers > 0 {
+			p += fmt.Sprintf(" %d min", m.Followers)
+		}
+		parts = append(parts, p)
+	}
+	if m.Slow > 0 {
+		parts = append(parts, fmt.Sprintf("🐢 %s %ds", mf.SlowMode, m.Slow))
+	}
+	if m.Unique {
+		parts = append(parts, "🔁 "+mf.UniqueChat)
+	}
+	return strings.Join(parts, " · ")
+}
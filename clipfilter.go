@@ -0,0 +1,75 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+type ClipFilterConfig struct {
+	Languages  []string `json:"languages"`
+	Creators   []string `json:"creators"`
+	CheckTitle bool     `json:"check_title"`
+}
+
+var languageScripts = map[string]*unicode.RangeTable{
+	"ru": unicode.Cyrillic,
+	"uk": unicode.Cyrillic,
+	"be": unicode.Cyrillic,
+	"bg": unicode.Cyrillic,
+	"ja": unicode.Han,
+	"zh": unicode.Han,
+	"ko": unicode.Hangul,
+	"ar": unicode.Arabic,
+	"el": unicode.Greek,
+	"th": unicode.Thai,
+}
+
+func (f ClipFilterConfig) Apply(clips []ClipInfo) []ClipInfo {
+	if len(f.Languages) == 0 && len(f.Creators) == 0 {
+		return clips
+	}
+	filtered := make([]ClipInfo, 0, len(clips))
+	for _, c := range clips {
+		if f.allowed(c) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+func (f ClipFilterConfig) allowed(c ClipInfo) bool {
+	if slices.ContainsFunc(f.Creators, func(name string) bool { return strings.EqualFold(name, c.Creator) }) {
+		return true
+	}
+	if len(f.Languages) == 0 {
+		return false
+	}
+	for _, lang := range f.Languages {
+		if c.Language != "" && !strings.EqualFold(lang, c.Language) {
+			continue
+		}
+		if !f.CheckTitle || titleMatchesScript(c.Title, lang) {
+			return true
+		}
+	}
+	return false
+}
+
+func titleMatchesScript(title, lang string) bool {
+	script, ok := languageScripts[strings.ToLower(lang)]
+	if !ok {
+		script = unicode.Latin
+	}
+	var letters, matching int
+	for _, r := range title {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(script, r) || (script == unicode.Han && unicode.In(r, unicode.Hiragana, unicode.Katakana)) {
+			matching++
+		}
+	}
+	return letters == 0 || matching*2 >= letters
+}
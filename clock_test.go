@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func useManualClock(t *testing.T, now time.Time) *manualClock {
+	t.Helper()
+	c := newManualClock(now)
+	old := clock
+	clock = c
+	t.Cleanup(func() { clock = old })
+	return c
+}
+
+func TestManualClock(t *testing.T) {
+	start := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	c := newManualClock(start)
+
+	short, long := c.After(time.Minute), c.After(time.Hour)
+	c.BlockUntil(2)
+	c.Advance(30 * time.Second)
+	select {
+	case <-short:
+		t.Fatal("timer fired early")
+	default:
+	}
+	c.Advance(30 * time.Second)
+	if at := <-short; !at.Equal(start.Add(time.Minute)) {
+		t.Fatalf("fired at %v", at)
+	}
+	select {
+	case <-long:
+		t.Fatal("long timer fired early")
+	default:
+	}
+
+	done := make(chan struct{})
+	go func() {
+		<-c.After(time.Second)
+		close(done)
+	}()
+	c.BlockUntil(2)
+	c.Advance(time.Second)
+	<-done
+
+	if at := <-c.After(0); !at.Equal(c.Now()) {
+		t.Fatalf("zero timer fired at %v", at)
+	}
+}
+
+func TestThumbnailURLUsesClock(t *testing.T) {
+	c := useManualClock(t, time.Unix(1700000000, 0))
+	if url := getThumbnailURL("somechannel"); !strings.HasSuffix(url, "?t=1700000000") {
+		t.Fatalf("url = %s", url)
+	}
+	c.Advance(time.Minute)
+	if url := getThumbnailURL("somechannel"); !strings.HasSuffix(url, "?t=1700000060") {
+		t.Fatalf("url = %s", url)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+type CommandHandler func(ctx context.Context, msg *TelegramMessage, args string) (string, error)
+
+type Command struct {
+	Name    string
+	Admin   bool
+	Handler CommandHandler
+}
+
+type CommandRouter struct {
+	cfg      *Config
+	commands []Command
+}
+
+func newCommandRouter(cfg *Config) *CommandRouter {
+	return &CommandRouter{cfg: cfg}
+}
+
+func (r *CommandRouter) Handle(cmd Command) {
+	r.commands = append(r.commands, cmd)
+}
+
+func (r *CommandRouter) Active() bool {
+	return len(r.commands) > 0
+}
+
+func (r *CommandRouter) lookup(name string) (Command, bool) {
+	for _, c := range r.commands {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Command{}, false
+}
+
+func (r *CommandRouter) HandleUpdate(ctx context.Context, u TelegramUpdate) {
+	msg := u.Message
+	if msg == nil || !strings.HasPrefix(msg.Text, "/") {
+		return
+	}
+	name, args, _ := strings.Cut(msg.Text[1:], " ")
+	name, _, _ = strings.Cut(name, "@")
+	cmd, ok := r.lookup(strings.ToLower(name))
+	if !ok {
+		return
+	}
+	if cmd.Admin && !isAdmin(r.cfg, msg.Chat.ID, msg.From) {
+		slog.Warn("ignoring command from non-admin", "command", name, "chat_id", msg.Chat.ID)
+		return
+	}
+
+	reply, err := cmd.Handler(ctx, msg, strings.TrimSpace(args))
+	if err != nil {
+		slog.Error("command failed", "command", name, "error", err)
+		reply = "⚠️ " + replyLocalization(r.cfg, msg).CommandFailed + ": " + escapeHTML(err.Error())
+	}
+	if reply == "" {
+		return
+	}
+	if err := sendReply(r.cfg.Telegram.BotToken, msg.Chat.ID, msg.MessageID, reply); err != nil {
+		slog.Error("failed to reply to command", "command", name, "error", err)
+	}
+}
+
+func isAdmin(cfg *Config, chatID int64, from *TelegramUser) bool {
+	if admins := cfg.Telegram.Admins; len(admins) > 0 {
+		return from != nil && slices.Contains(admins, from.ID)
+	}
+	admin := cfg.Telegram.AdminChatID
+	if admin == nil {
+		return false
+	}
+	return chatID == *admin || (from != nil && from.ID == *admin)
+}
+
+func (r *CommandRouter) Help(ctx context.Context, msg *TelegramMessage, args string) (string, error) {
+	admin := isAdmin(r.cfg, msg.Chat.ID, msg.From)
+	loc := replyLocalization(r.cfg, msg)
+	var lines []string
+	for _, c := range r.commands {
+		if c.Admin && !admin {
+			continue
+		}
+		lines = append(lines, "/"+c.Name+" — "+escapeHTML(loc.Descriptions[c.Name]))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (r *CommandRouter) Register() {
+	r.register("", getReplyLocalization(r.cfg.CommandLanguage))
+	for _, lang := range replyLanguages {
+		r.register(lang, getReplyLocalization(lang))
+	}
+}
+
+func (r *CommandRouter) register(languageCode string, loc ReplyLocalization) {
+	cfg := r.cfg
+	var public, all []map[string]string
+	for _, c := range r.commands {
+		entry := map[string]string{"command": c.Name, "description": loc.Descriptions[c.Name]}
+		all = append(all, entry)
+		if !c.Admin {
+			public = append(public, entry)
+		}
+	}
+
+	type scope struct {
+		chatID   *int64
+		commands []map[string]string
+	}
+	scopes := []scope{
+		{cfg.Telegram.ChatID, public},
+		{cfg.Telegram.AdminChatID, all},
+	}
+	for _, id := range cfg.Telegram.Admins {
+		scopes = append(scopes, scope{&id, all})
+	}
+	for _, s := range scopes {
+		if s.chatID == nil || len(s.commands) == 0 {
+			continue
+		}
+		if err := setMyCommands(cfg.Telegram.BotToken, *s.chatID, languageCode, s.commands); err != nil {
+			slog.Warn("failed to register bot commands", "chat_id", *s.chatID, "language", languageCode, "error", err)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+type ConfigIssue struct {
+	Line    int
+	Column  int
+	Path    string
+	Message string
+}
+
+type ConfigErrors struct {
+	File   string
+	Issues []ConfigIssue
+}
+
+func (e *ConfigErrors) Error() string {
+	lines := make([]string, 0, len(e.Issues)+1)
+	lines = append(lines, fmt.Sprintf("invalid config %s:", e.File))
+	for _, issue := range e.Issues {
+		loc := e.File
+		if issue.Line > 0 {
+			loc = fmt.Sprintf("%s:%d:%d", e.File, issue.Line, issue.Column)
+		}
+		if issue.Path != "" {
+			lines = append(lines, fmt.Sprintf("  %s: %s: %s", loc, issue.Path, issue.Message))
+		} else {
+			lines = append(lines, fmt.Sprintf("  %s: %s", loc, issue.Message))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (i ConfigIssue) String() string {
+	msg := i.Message
+	if i.Path != "" {
+		msg = i.Path + ": " + msg
+	}
+	if i.Line > 0 {
+		return fmt.Sprintf("line %d:%d: %s", i.Line, i.Column, msg)
+	}
+	return msg
+}
+
+func loadConfigLenient(path string) (*Config, []ConfigIssue, error) {
+	cfg, err := loadConfig(path)
+	if err == nil || errors.Is(err, os.ErrNotExist) {
+		return cfg, nil, err
+	}
+	data, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return nil, nil, err
+	}
+
+	var issues []ConfigIssue
+	var cfgErr *ConfigErrors
+	if errors.As(err, &cfgErr) {
+		issues = cfgErr.Issues
+	} else {
+		issues = []ConfigIssue{{Message: err.Error()}}
+	}
+	var raw Config
+	json.Unmarshal(data, &raw)
+	if err := resolveSecretRefs(&raw); err != nil {
+		issues = append(issues, ConfigIssue{Message: err.Error()})
+	}
+	return &raw, issues, nil
+}
+
+type configChecker struct {
+	data    []byte
+	dec     *json.Decoder
+	keys    map[string]int64
+	issues  []ConfigIssue
+	errored bool
+}
+
+func checkConfigJSON(data []byte) *configChecker {
+	c := &configChecker{data: data, keys: make(map[string]int64)}
+	c.dec = json.NewDecoder(bytes.NewReader(data))
+	c.dec.UseNumber()
+	c.walk(reflect.TypeOf(Config{}), "")
+	if !c.errored {
+		if _, err := c.dec.Token(); !errors.Is(err, io.EOF) {
+			c.addAt(c.dec.InputOffset(), "", "unexpected data after the top-level object")
+		}
+	}
+	return c
+}
+
+func (c *configChecker) position(offset int64) (int, int) {
+	for offset < int64(len(c.data)) && strings.IndexByte(" \t\r\n,:", c.data[offset]) >= 0 {
+		offset++
+	}
+	offset = min(offset, int64(len(c.data)))
+	line := 1 + bytes.Count(c.data[:offset], []byte("\n"))
+	col := int(offset) - bytes.LastIndexByte(c.data[:offset], '\n')
+	return line, col
+}
+
+func (c *configChecker) addAt(offset int64, path, format string, args ...any) {
+	line, col := c.position(offset)
+	c.issues = append(c.issues, ConfigIssue{Line: line, Column: col, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *configChecker) add(path, format string, args ...any) {
+	if offset, ok := c.keys[path]; ok {
+		c.addAt(offset, path, format, args...)
+		return
+	}
+	c.issues = append(c.issues, ConfigIssue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *configChecker) token() (json.Token, int64, bool) {
+	start := c.dec.InputOffset()
+	tok, err := c.dec.Token()
+	if err != nil {
+		var syntax *json.SyntaxError
+		offset := c.dec.InputOffset()
+		if errors.As(err, &syntax) {
+			offset = syntax.Offset - 1
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = errors.New("unexpected end of file")
+		}
+		c.addAt(offset, "", "%v", err)
+		c.errored = true
+		return nil, start, false
+	}
+	return tok, start, true
+}
+
+func (c *configChecker) walk(t reflect.Type, path string) {
+	tok, start, ok := c.token()
+	if !ok {
+		return
+	}
+	if tok == nil {
+		return
+	}
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if delim, isDelim := tok.(json.Delim); isDelim {
+		switch {
+		case delim == '{' && t != nil && t.Kind() == reflect.Struct:
+			c.walkStruct(t, path)
+		case delim == '{' && t != nil && t.Kind() == reflect.Map:
+			c.walkObject(func(string) (reflect.Type, bool) { return t.Elem(), true }, path)
+		case delim == '[' && t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+			c.walkArray(t.Elem(), path)
+		default:
+			if t != nil && t.Kind() != reflect.Interface && t != reflect.TypeOf(json.RawMessage{}) {
+				kind := "an object"
+				if delim == '[' {
+					kind = "a list"
+				}
+				c.addAt(start, path, "expected %s, got %s", typeName(t), kind)
+			}
+			if delim == '{' {
+				c.walkObject(func(string) (reflect.Type, bool) { return nil, true }, path)
+			} else {
+				c.walkArray(nil, path)
+			}
+		}
+		return
+	}
+
+	if t == nil || t.Kind() == reflect.Interface {
+		return
+	}
+	var got string
+	switch v := tok.(type) {
+	case string:
+		if t.Kind() == reflect.String {
+			return
+		}
+		got = fmt.Sprintf("string %q", v)
+	case bool:
+		if t.Kind() == reflect.Bool {
+			return
+		}
+		got = fmt.Sprintf("%t", v)
+	case json.Number:
+		switch t.Kind() {
+		case reflect.Float32, reflect.Float64:
+			return
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if _, err := v.Int64(); err == nil {
+				return
+			}
+			got = "number " + v.String()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if n, err := v.Int64(); err == nil && n >= 0 {
+				return
+			}
+			got = "number " + v.String()
+		default:
+			got = "number " + v.String()
+		}
+	}
+	c.addAt(start, path, "expected %s, got %s", typeName(t), got)
+}
+
+func (c *configChecker) walkStruct(t reflect.Type, path string) {
+	fields := jsonFields(t)
+	c.walkObject(func(key string) (reflect.Type, bool) {
+		if f, ok := fields[key]; ok {
+			return f, true
+		}
+		for name, f := range fields {
+			if strings.EqualFold(name, key) {
+				return f, true
+			}
+		}
+		return nil, false
+	}, path)
+}
+
+func (c *configChecker) walkObject(field func(string) (reflect.Type, bool), path string) {
+	for !c.errored && c.dec.More() {
+		tok, start, ok := c.token()
+		if !ok {
+			return
+		}
+		key, _ := tok.(string)
+		child := key
+		if path != "" {
+			child = path + "." + key
+		}
+		t, known := field(key)
+		if !known {
+			c.addAt(start, child, "unknown key%s", c.suggest(path, key))
+		} else {
+			c.keys[child] = start
+		}
+		c.walk(t, child)
+	}
+	if !c.errored {
+		c.token()
+	}
+}
+
+func (c *configChecker) walkArray(elem reflect.Type, path string) {
+	for i := 0; !c.errored && c.dec.More(); i++ {
+		c.walk(elem, fmt.Sprintf("%s[%d]", path, i))
+	}
+	if !c.errored {
+		c.token()
+	}
+}
+
+func (c *configChecker) suggest(path, key string) string {
+	t := reflect.TypeOf(Config{})
+	if path != "" {
+		for _, part := range strings.Split(path, ".") {
+			part, _, _ = strings.Cut(part, "[")
+			for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+				t = t.Elem()
+			}
+			if t.Kind() != reflect.Struct {
+				return ""
+			}
+			next, ok := jsonFields(t)[part]
+			if !ok {
+				return ""
+			}
+			t = next
+		}
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+	}
+	if t.Kind() != reflect.Struct {
+		return ""
+	}
+
+	best, bestDist := "", 4
+	for name := range jsonFields(t) {
+		d := editDistance(strings.ToLower(key), name)
+		if d < bestDist || d == bestDist && name < best {
+			best, bestDist = name, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				for k, v := range jsonFields(f.Type) {
+					fields[k] = v
+				}
+				continue
+			}
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a non-negative integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	default:
+		return "an object"
+	}
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func (c *configChecker) checkRanges(cfg *Config) {
+	type bound struct {
+		path     string
+		value    int
+		min, max int
+	}
+	for _, b := range []bound{
+		{"check_interval_seconds", cfg.CheckInterval, 5, 3600},
+		{"update_interval_minutes", cfg.UpdateInterval, 1, 1440},
+		{"viewer_sample_interval_seconds", cfg.SampleInterval, 1, 86400},
+		{"announce_delay_seconds", cfg.AnnounceDelay, 0, 3600},
+		{"thumbnail_update_interval_minutes", cfg.ThumbnailUpdateInterval, 1, 1440},
+		{"hook.timeout_seconds", cfg.Hook.Timeout, 1, 300},
+		{"plugins.timeout_seconds", cfg.Plugins.Timeout, 1, 300},
+		{"screenshots.interval_minutes", cfg.Screenshots.Interval, 1, 1440},
+		{"teaser.minutes_before", cfg.Teaser.MinutesBefore, 1, 1440},
+	} {
+		if _, set := c.keys[b.path]; !set {
+			continue
+		}
+		if b.value < b.min || b.value > b.max {
+			c.add(b.path, "%d is out of range (allowed %d–%d)", b.value, b.min, b.max)
+		}
+	}
+	if len(c.issues) > 0 {
+		return
+	}
+	if cfg.UpdateInterval*60 < cfg.CheckInterval {
+		c.add("update_interval_minutes", "update interval (%d min) is shorter than check_interval_seconds (%d s)", cfg.UpdateInterval, cfg.CheckInterval)
+	}
+	if cfg.SampleInterval < cfg.CheckInterval {
+		c.add("viewer_sample_interval_seconds", "%d is shorter than check_interval_seconds (%d); viewers are only sampled on checks", cfg.SampleInterval, cfg.CheckInterval)
+	}
+	if cfg.Retry.Jitter < 0 || cfg.Retry.Jitter > 1 {
+		c.add("retry.jitter", "%g is out of range (allowed 0–1)", cfg.Retry.Jitter)
+	}
+}
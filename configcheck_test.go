@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigLenient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"twitch": {"channel": "somechannel", "client_id": 5}, "hook": {"command": ["a"]}, "telegram": {"bot_token": "123:abc"}}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, issues, err := loadConfigLenient(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Twitch.Channel != "somechannel" || cfg.Telegram.BotToken != "123:abc" {
+		t.Fatalf("valid settings were not loaded: %+v", cfg.Twitch)
+	}
+	paths := map[string]bool{}
+	for _, issue := range issues {
+		paths[issue.Path] = true
+	}
+	if !paths["twitch.client_id"] || !paths["hook.command"] {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+
+	if _, _, err := loadConfigLenient(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected ErrNotExist, got %v", err)
+	}
+}
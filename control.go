@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type MonitorControl struct {
+	mu     sync.Mutex
+	paused bool
+	forced bool
+	wake   chan struct{}
+}
+
+func newMonitorControl() *MonitorControl {
+	return &MonitorControl{wake: make(chan struct{}, 1)}
+}
+
+func (c *MonitorControl) Paused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+func (c *MonitorControl) SetPaused(paused bool) {
+	c.mu.Lock()
+	changed := c.paused != paused
+	c.paused = paused
+	c.mu.Unlock()
+	if changed {
+		slog.Info("monitor pause changed", "paused", paused)
+		c.Wake()
+	}
+}
+
+func (c *MonitorControl) ForceUpdate() {
+	c.mu.Lock()
+	c.forced = true
+	c.mu.Unlock()
+	c.Wake()
+}
+
+func (c *MonitorControl) takeForced() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	forced := c.forced
+	c.forced = false
+	return forced
+}
+
+func (c *MonitorControl) Wake() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (c *MonitorControl) Sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-clock.After(d):
+	case <-c.wake:
+	}
+}
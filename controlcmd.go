@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+type ControlCommands struct {
+	cfg     *Config
+	control *MonitorControl
+	live    *LiveState
+	caption *CaptionState
+	marks   *MarkLog
+}
+
+func newControlCommands(cfg *Config, control *MonitorControl, live *LiveState, caption *CaptionState, marks *MarkLog) *ControlCommands {
+	return &ControlCommands{cfg: cfg, control: control, live: live, caption: caption, marks: marks}
+}
+
+func (c *ControlCommands) Register(r *CommandRouter) {
+	r.Handle(Command{Name: "pause", Admin: true, Handler: c.Pause})
+	r.Handle(Command{Name: "resume", Admin: true, Handler: c.Resume})
+	r.Handle(Command{Name: "update", Admin: true, Handler: c.Update})
+	r.Handle(Command{Name: "caption", Admin: true, Handler: c.Caption})
+	r.Handle(Command{Name: "brb", Admin: true, Handler: c.Break})
+	r.Handle(Command{Name: "back", Admin: true, Handler: c.Back})
+	r.Handle(Command{Name: "mark", Admin: true, Handler: c.Mark})
+}
+
+func (c *ControlCommands) Pause(ctx context.Context, msg *TelegramMessage, args string) (string, error) {
+	c.control.SetPaused(true)
+	return replyLocalization(c.cfg, msg).Paused, nil
+}
+
+func (c *ControlCommands) Resume(ctx context.Context, msg *TelegramMessage, args string) (string, error) {
+	c.control.SetPaused(false)
+	return replyLocalization(c.cfg, msg).Resumed, nil
+}
+
+func (c *ControlCommands) Update(ctx context.Context, msg *TelegramMessage, args string) (string, error) {
+	c.control.ForceUpdate()
+	return replyLocalization(c.cfg, msg).UpdateQueued, nil
+}
+
+func (c *ControlCommands) Caption(ctx context.Context, msg *TelegramMessage, args string) (string, error) {
+	loc := replyLocalization(c.cfg, msg)
+	snap, ok := c.live.Snapshot()
+	if !ok {
+		return c.offline(loc), nil
+	}
+	if !repliesTo(msg.ReplyToMessage, *c.cfg.Telegram.ChatID, snap.MessageID) {
+		return loc.CaptionUsage, nil
+	}
+	c.caption.SetNote(snap.StartTime, args)
+	c.control.ForceUpdate()
+	if args == "" {
+		return loc.CaptionCleared, nil
+	}
+	return loc.CaptionSet, nil
+}
+
+func (c *ControlCommands) Mark(ctx context.Context, msg *TelegramMessage, args string) (string, error) {
+	loc := replyLocalization(c.cfg, msg)
+	snap, ok := c.live.Snapshot()
+	if !ok {
+		return c.offline(loc), nil
+	}
+	if args == "" {
+		return loc.MarkUsage, nil
+	}
+	now := clock.Now()
+	c.marks.Add(snap.StartTime, StreamMark{Time: now, Text: args})
+	return fmt.Sprintf("📍 %s %s", loc.MarkAdded, formatDuration(now.Sub(snap.StartTime), loc.Lang)), nil
+}
+
+func (c *ControlCommands) Break(ctx context.Context, msg *TelegramMessage, args string) (string, error) {
+	return c.setBreak(msg, true)
+}
+
+func (c *ControlCommands) Back(ctx context.Context, msg *TelegramMessage, args string) (string, error) {
+	return c.setBreak(msg, false)
+}
+
+func (c *ControlCommands) setBreak(msg *TelegramMessage, onBreak bool) (string, error) {
+	loc := replyLocalization(c.cfg, msg)
+	snap, ok := c.live.Snapshot()
+	if !ok {
+		return c.offline(loc), nil
+	}
+	if !c.caption.SetBreak(snap.StartTime, onBreak) {
+		if onBreak {
+			return loc.AlreadyOnBreak, nil
+		}
+		return loc.NotOnBreak, nil
+	}
+	c.control.ForceUpdate()
+	if onBreak {
+		return loc.BreakStarted, nil
+	}
+	return loc.BreakEnded, nil
+}
+
+func (c *ControlCommands) offline(loc ReplyLocalization) string {
+	return fmt.Sprintf("<b>%s</b> — %s", escapeHTML(c.cfg.Twitch.Channel), loc.Offline)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+type discussionPost struct {
+	chatID    int64
+	messageID int
+}
+
+type DiscussionTracker struct {
+	channelID int64
+
+	mu       sync.Mutex
+	forwards map[int]discussionPost
+}
+
+func newDiscussionTracker(channelID int64) *DiscussionTracker {
+	return &DiscussionTracker{channelID: channelID, forwards: map[int]discussionPost{}}
+}
+
+func (d *DiscussionTracker) HandleUpdate(ctx context.Context, u TelegramUpdate) {
+	m := u.Message
+	if m == nil || !m.IsAutomaticForward || m.ForwardOrigin == nil || m.ForwardOrigin.Chat == nil {
+		return
+	}
+	if m.ForwardOrigin.Chat.ID != d.channelID {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.forwards[m.ForwardOrigin.MessageID] = discussionPost{chatID: m.Chat.ID, messageID: m.MessageID}
+}
+
+func (d *DiscussionTracker) Lookup(channelMessageID int) (discussionPost, bool) {
+	if d == nil {
+		return discussionPost{}, false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	post, ok := d.forwards[channelMessageID]
+	return post, ok
+}
+
+func (d *DiscussionTracker) Forget(channelMessageID int) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.forwards, channelMessageID)
+}
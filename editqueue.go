@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+type messageMove struct {
+	startTime time.Time
+	messageID int
+	bot       int
+}
+
+type EditQueue struct {
+	run  func(ctx context.Context, ev Event)
+	once sync.Once
+	wake chan struct{}
+
+	mu      sync.Mutex
+	pending *Event
+	cancel  context.CancelFunc
+	done    chan struct{}
+	moved   *messageMove
+}
+
+func newEditQueue(run func(ctx context.Context, ev Event)) *EditQueue {
+	return &EditQueue{run: run, wake: make(chan struct{}, 1)}
+}
+
+func (q *EditQueue) Push(ctx context.Context, ev Event) {
+	q.once.Do(func() { go q.loop(ctx) })
+
+	session := *ev.Session
+	session.ViewerHistory = slices.Clone(session.ViewerHistory)
+	session.TitleHistory = slices.Clone(session.TitleHistory)
+	session.TagHistory = slices.Clone(session.TagHistory)
+	info := *ev.Info
+	snap := ev
+	snap.Session = &session
+	snap.Info = &info
+
+	q.mu.Lock()
+	if q.pending != nil {
+		slog.Info("previous stream info update still queued, replacing it")
+	}
+	q.pending = &snap
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *EditQueue) loop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		}
+
+		q.mu.Lock()
+		ev := q.pending
+		q.pending = nil
+		if ev == nil {
+			q.mu.Unlock()
+			continue
+		}
+		jobCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		q.cancel, q.done = cancel, done
+		original := ev.Session.MessageID
+		q.mu.Unlock()
+
+		q.run(jobCtx, *ev)
+		cancel()
+
+		q.mu.Lock()
+		if ev.Session.MessageID != original {
+			q.moved = &messageMove{startTime: ev.Session.StartTime, messageID: ev.Session.MessageID, bot: ev.Session.Bot}
+		}
+		q.cancel, q.done = nil, nil
+		q.mu.Unlock()
+		close(done)
+	}
+}
+
+func (q *EditQueue) Apply(session *StreamSession) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.moved == nil {
+		return
+	}
+	if q.moved.startTime.Equal(session.StartTime) {
+		session.MessageID = q.moved.messageID
+		session.Bot = q.moved.bot
+	}
+	q.moved = nil
+}
+
+func (q *EditQueue) Flush(session *StreamSession) {
+	q.mu.Lock()
+	q.pending = nil
+	cancel, done := q.cancel, q.done
+	q.mu.Unlock()
+	if cancel != nil {
+		slog.Info("cancelling stream info update in progress")
+		cancel()
+		<-done
+	}
+	q.Apply(session)
+}
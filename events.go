@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventStreamStarted EventType = "stream_started"
+	EventStreamUpdated EventType = "stream_updated"
+	EventGameChanged   EventType = "game_changed"
+	EventTagsChanged   EventType = "tags_changed"
+	EventStreamEnded   EventType = "stream_ended"
+)
+
+type Event struct {
+	Type         EventType
+	Time         time.Time
+	Channel      string
+	Info         *StreamInfo
+	Session      *StreamSession
+	PreviousGame string
+	PreviousTags []string
+	Forced       bool
+}
+
+type EventHandler func(ctx context.Context, ev Event)
+
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers []EventHandler
+}
+
+func (b *EventBus) Subscribe(h EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+func (b *EventBus) Publish(ctx context.Context, ev Event) {
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(ctx, ev)
+	}
+}
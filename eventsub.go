@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+const eventSubURL = "wss://eventsub.wss.twitch.tv/ws"
+
+type EventSubSubscription struct {
+	Type      string            `json:"type"`
+	Version   string            `json:"version"`
+	Condition map[string]string `json:"condition"`
+}
+
+type EventSubHandler func(subType string, event json.RawMessage)
+
+type EventSubClient struct {
+	cfg     *Config
+	subs    []EventSubSubscription
+	handler EventSubHandler
+}
+
+type eventSubMessage struct {
+	Metadata struct {
+		MessageType      string `json:"message_type"`
+		SubscriptionType string `json:"subscription_type"`
+	} `json:"metadata"`
+	Payload struct {
+		Session struct {
+			ID                      string `json:"id"`
+			KeepaliveTimeoutSeconds int    `json:"keepalive_timeout_seconds"`
+			ReconnectURL            string `json:"reconnect_url"`
+		} `json:"session"`
+		Event json.RawMessage `json:"event"`
+	} `json:"payload"`
+}
+
+func newEventSubClient(cfg *Config, subs []EventSubSubscription, handler EventSubHandler) *EventSubClient {
+	return &EventSubClient{cfg: cfg, subs: subs, handler: handler}
+}
+
+func (c *EventSubClient) Run(ctx context.Context) {
+	delay := time.Second
+	for ctx.Err() == nil {
+		err := c.session(ctx, eventSubURL)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("eventsub connection lost, reconnecting", "error", err, "in", delay)
+		sleep(ctx, delay)
+		delay = min(delay*2, time.Minute)
+	}
+}
+
+func (c *EventSubClient) session(ctx context.Context, url string) error {
+	subscribe := true
+	for {
+		conn, err := dialWebSocket(url, nil, 10*time.Second)
+		if err != nil {
+			return err
+		}
+		next, err := c.read(ctx, conn, subscribe)
+		conn.Close()
+		if next == "" {
+			return err
+		}
+		slog.Info("eventsub reconnect requested")
+		url, subscribe = next, false
+	}
+}
+
+func (c *EventSubClient) read(ctx context.Context, conn *wsConn, subscribe bool) (string, error) {
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	keepalive := 30 * time.Second
+	for {
+		conn.SetReadDeadline(time.Now().Add(keepalive + 10*time.Second))
+		data, err := conn.ReadMessage()
+		if err != nil {
+			return "", err
+		}
+		var msg eventSubMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			slog.Warn("invalid eventsub message", "error", err)
+			continue
+		}
+
+		switch msg.Metadata.MessageType {
+		case "session_welcome":
+			if t := msg.Payload.Session.KeepaliveTimeoutSeconds; t > 0 {
+				keepalive = time.Duration(t) * time.Second
+			}
+			if subscribe {
+				if err := c.subscribe(ctx, msg.Payload.Session.ID); err != nil {
+					return "", err
+				}
+				slog.Info("eventsub connected", "subscriptions", len(c.subs))
+			}
+		case "session_reconnect":
+			return msg.Payload.Session.ReconnectURL, nil
+		case "notification":
+			c.handler(msg.Metadata.SubscriptionType, msg.Payload.Event)
+		case "revocation":
+			slog.Warn("eventsub subscription revoked", "type", msg.Metadata.SubscriptionType)
+		}
+	}
+}
+
+func (c *EventSubClient) subscribe(ctx context.Context, sessionID string) error {
+	for _, sub := range c.subs {
+		body := map[string]any{
+			"type":      sub.Type,
+			"version":   sub.Version,
+			"condition": sub.Condition,
+			"transport": map[string]string{"method": "websocket", "session_id": sessionID},
+		}
+		err := twitchUserRequest(ctx, c.cfg, "POST", helixAPI+"/eventsub/subscriptions", body, nil)
+		var apiErr *TwitchAPIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == 409 {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
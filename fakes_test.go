@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeHelix struct {
+	mu         sync.Mutex
+	users      map[string]TwitchUser
+	streams    map[string]TwitchStream
+	sharedChat map[string][]string
+	clips      map[string][]TwitchClip
+	labels     map[string][]string
+	requests   []string
+}
+
+func newFakeHelix(t *testing.T) *fakeHelix {
+	h := &fakeHelix{
+		users:      map[string]TwitchUser{},
+		streams:    map[string]TwitchStream{},
+		sharedChat: map[string][]string{},
+		clips:      map[string][]TwitchClip{},
+		labels:     map[string][]string{},
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	oldHelix, oldAuth, oldCDN := helixAPI, twitchAuthAPI, previewsCDN
+	configureEndpoints(APIEndpoints{Helix: srv.URL + "/helix", TwitchAuth: srv.URL, Previews: srv.URL})
+	tokenMu.Lock()
+	oldTokens := tokenCache
+	tokenCache = map[string]*twitchToken{}
+	tokenMu.Unlock()
+	t.Cleanup(func() {
+		helixAPI, twitchAuthAPI, previewsCDN = oldHelix, oldAuth, oldCDN
+		tokenMu.Lock()
+		tokenCache = oldTokens
+		tokenMu.Unlock()
+	})
+	return h
+}
+
+func (h *fakeHelix) AddUser(id, login string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.users[login] = TwitchUser{ID: id, Login: login, DisplayName: login}
+}
+
+func (h *fakeHelix) SetLive(login string, s TwitchStream) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s.UserLogin = login
+	s.UserID = h.users[login].ID
+	h.streams[login] = s
+}
+
+func (h *fakeHelix) SetOffline(login string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.streams, login)
+}
+
+func (h *fakeHelix) SetSharedChat(broadcasterID string, participants ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sharedChat[broadcasterID] = participants
+}
+
+func (h *fakeHelix) SetClips(broadcasterID string, clips ...TwitchClip) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clips[broadcasterID] = clips
+}
+
+func (h *fakeHelix) SetContentLabels(broadcasterID string, labels ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.labels[broadcasterID] = labels
+}
+
+func (h *fakeHelix) Requests(path string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var out []string
+	for _, r := range h.requests {
+		if strings.HasPrefix(r, path) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (h *fakeHelix) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.requests = append(h.requests, r.URL.RequestURI())
+
+	q := r.URL.Query()
+	var data []any
+	if strings.HasPrefix(r.URL.Path, "/helix/") && r.Header.Get("Authorization") != "Bearer fake-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"Unauthorized","status":401,"message":"Invalid OAuth token"}`))
+		return
+	}
+	switch r.URL.Path {
+	case "/oauth2/token":
+		json.NewEncoder(w).Encode(TwitchAuthResponse{AccessToken: "fake-token", ExpiresIn: 3600})
+		return
+	case "/helix/streams":
+		for _, login := range q["user_login"] {
+			if s, ok := h.streams[login]; ok {
+				data = append(data, s)
+			}
+		}
+	case "/helix/shared_chat/session":
+		if ids := h.sharedChat[q.Get("broadcaster_id")]; len(ids) > 0 {
+			var participants []map[string]string
+			for _, id := range ids {
+				participants = append(participants, map[string]string{"broadcaster_id": id})
+			}
+			data = append(data, map[string]any{"participants": participants})
+		}
+	case "/helix/clips":
+		for _, c := range h.clips[q.Get("broadcaster_id")] {
+			data = append(data, c)
+		}
+	case "/helix/channels":
+		data = append(data, map[string]any{"content_classification_labels": h.labels[q.Get("broadcaster_id")]})
+	case "/helix/schedule":
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"segments": []any{}}})
+		return
+	case "/helix/users":
+		for _, u := range h.users {
+			if slices.Contains(q["login"], u.Login) || slices.Contains(q["id"], u.ID) {
+				data = append(data, u)
+			}
+		}
+	default:
+		if strings.HasPrefix(r.URL.Path, "/previews-ttv/") {
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write([]byte("\xff\xd8\xff\xe0fake-jpeg\xff\xd9"))
+			return
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]any{"data": data})
+}
+
+type botCall struct {
+	Method string
+	Params map[string]string
+}
+
+type fakeBotAPI struct {
+	mu        sync.Mutex
+	nextID    int
+	calls     []botCall
+	editDelay time.Duration
+}
+
+func newFakeBotAPI(t *testing.T) *fakeBotAPI {
+	b := &fakeBotAPI{nextID: 100}
+	srv := httptest.NewServer(b)
+	t.Cleanup(srv.Close)
+	old := telegramAPI
+	configureEndpoints(APIEndpoints{Telegram: srv.URL})
+	t.Cleanup(func() { telegramAPI = old })
+	return b
+}
+
+func (b *fakeBotAPI) SetEditDelay(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.editDelay = d
+}
+
+func (b *fakeBotAPI) Calls(methods ...string) []botCall {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var out []botCall
+	for _, c := range b.calls {
+		if len(methods) == 0 || slices.Contains(methods, c.Method) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (b *fakeBotAPI) WaitFor(t *testing.T, n int, methods ...string) []botCall {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		calls := b.Calls(methods...)
+		if len(calls) >= n {
+			return calls
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d %v calls, got %v", n, methods, b.Calls())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (b *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, method, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/bot"), "/")
+	params := map[string]string{}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		r.ParseMultipartForm(1 << 20)
+		for k, v := range r.MultipartForm.Value {
+			params[k] = v[0]
+		}
+		for k := range r.MultipartForm.File {
+			params[k] = "<file>"
+		}
+	} else {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		for k, v := range body {
+			if s, ok := v.(string); ok {
+				params[k] = s
+			} else {
+				raw, _ := json.Marshal(v)
+				params[k] = string(raw)
+			}
+		}
+	}
+
+	b.mu.Lock()
+	b.calls = append(b.calls, botCall{Method: method, Params: params})
+	delay := b.editDelay
+	b.mu.Unlock()
+	if strings.HasPrefix(method, "edit") && delay > 0 {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+
+	b.mu.Lock()
+	var result any = true
+	switch method {
+	case "sendPhoto", "sendMessage", "sendVideo", "sendDocument":
+		b.nextID++
+		result = map[string]any{"message_id": b.nextID}
+	case "getMe":
+		result = map[string]any{"id": 1, "is_bot": true, "username": "fakebot"}
+	case "getChatMember":
+		result = map[string]any{"status": "administrator"}
+	}
+	b.mu.Unlock()
+	fmt.Fprintf(w, `{"ok":true,"result":%s}`, mustJSON(result))
+}
+
+func mustJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
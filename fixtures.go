@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+var fixtureLanguages = []string{"en", "ru"}
+
+func fixtureConfig(lang string) *Config {
+	cfg := &Config{Language: lang, Layout: layoutSections}
+	cfg.Twitch.Channel = "examplestreamer"
+	cfg.Hashtags.Style = "camel"
+	return cfg
+}
+
+func fixtureFiles() map[string]string {
+	time.Local = time.UTC
+	now := time.Date(2025, time.January, 1, 20, 0, 0, 0, time.UTC)
+	files := map[string]string{}
+	for _, lang := range fixtureLanguages {
+		cfg := fixtureConfig(lang)
+		info, history, clips := sampleStream(cfg, now)
+		for _, msg := range renderStyles(cfg, info, history, clips) {
+			files[filepath.Join(lang, fmt.Sprintf("%s_%s.html", msg.Style, msg.Stage))] = msg.Text + "\n"
+		}
+	}
+	return files
+}
+
+func renderFixtures(dir string) error {
+	files := fixtureFiles()
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(files[name]), 0644); err != nil {
+			return err
+		}
+		fmt.Println(path)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata/fixtures")
+
+func TestFixturesGolden(t *testing.T) {
+	const dir = "testdata/fixtures"
+	defer func(local *time.Location) { time.Local = local }(time.Local)
+	files := fixtureFiles()
+	if *updateGolden {
+		if err := renderFixtures(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("%s: %v (run go test -run TestFixturesGolden -update)", name, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("%s differs from the rendered message (run go test -run TestFixturesGolden -update)\ngot:\n%s\nwant:\n%s", name, got, want)
+		}
+	}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		name, _ := filepath.Rel(dir, path)
+		if _, ok := files[name]; !ok {
+			t.Errorf("%s is not rendered by any style", name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+type FollowerGoalConfig struct {
+	Enabled bool  `json:"enabled"`
+	Targets []int `json:"targets"`
+}
+
+type FollowerGoal struct {
+	Followers int
+	Target    int
+}
+
+type FollowerGoalWatcher struct {
+	cfg  *Config
+	last int
+}
+
+func newFollowerGoalWatcher(cfg *Config) *FollowerGoalWatcher {
+	return &FollowerGoalWatcher{cfg: cfg}
+}
+
+func (w *FollowerGoalWatcher) Handle(ctx context.Context, ev Event) {
+	switch ev.Type {
+	case EventStreamStarted:
+		w.last = 0
+	case EventStreamUpdated:
+	default:
+		return
+	}
+
+	followers, err := getFollowerCount(ctx, ev.Session.BroadcasterID, w.cfg.Twitch.ClientID, w.cfg.Twitch.ClientSecret)
+	if err != nil {
+		slog.Warn("failed to get follower count", "error", err)
+		followers = w.last
+	}
+	if followers == 0 {
+		return
+	}
+	if target := nextFollowerGoal(followers, w.cfg.FollowerGoal.Targets); target > 0 {
+		ev.Info.Followers = &FollowerGoal{Followers: followers, Target: target}
+	}
+	w.last = followers
+}
+
+func nextFollowerGoal(followers int, targets []int) int {
+	if len(targets) > 0 {
+		for _, t := range targets {
+			if t > followers {
+				return t
+			}
+		}
+		return 0
+	}
+	for scale := 10; ; scale *= 10 {
+		for _, m := range []int{1, 2, 5} {
+			if t := m * scale; t > followers {
+				return t
+			}
+		}
+	}
+}
+
+func sortFollowerTargets(targets []int) []int {
+	targets = slices.Clone(targets)
+	slices.Sort(targets)
+	return slices.Compact(targets)
+}
+
+func formatFollowerGoal(g *FollowerGoal, mf MessageFormat) string {
+	if g == nil {
+		return ""
+	}
+	return fmt.Sprintf("👥 %s %s", formatViewers(g.Target-g.Followers), fmt.Sprintf(mf.FollowersTo, formatRoundNumber(g.Target)))
+}
+
+func formatRoundNumber(n int) string {
+	switch {
+	case n >= 1000000 && n%1000000 == 0:
+		return fmt.Sprintf("%dM", n/1000000)
+	case n >= 1000 && n%1000 == 0:
+		return fmt.Sprintf("%dK", n/1000)
+	}
+	return formatViewers(n)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var footerVarPattern = regexp.MustCompile(`\{[a-z_]+\}`)
+
+type FooterConfig struct {
+	Template string            `json:"template"`
+	Discord  string            `json:"discord"`
+	Donate   string            `json:"donate"`
+	Schedule string            `json:"schedule"`
+	Socials  map[string]string `json:"socials"`
+}
+
+var footerVars = []string{"{channel}", "{url}", "{description}", "{discord}", "{donate}", "{schedule}", "{socials}"}
+
+func footerValues(channel string, cfg FooterConfig) map[string]string {
+	values := map[string]string{
+		"{channel}":  escapeHTML(channel),
+		"{url}":      escapeAttr("https://twitch.tv/" + strings.ToLower(channel)),
+		"{discord}":  escapeAttr(cfg.Discord),
+		"{donate}":   escapeAttr(cfg.Donate),
+		"{schedule}": escapeHTML(cfg.Schedule),
+		"{socials}":  formatSocials(cfg.Socials),
+	}
+	if u, ok := cachedTwitchUser(strings.ToLower(channel)); ok {
+		values["{description}"] = escapeHTML(strings.TrimSpace(u.Description))
+	}
+	return values
+}
+
+func formatSocials(socials map[string]string) string {
+	names := make([]string, 0, len(socials))
+	for name := range socials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	links := make([]string, 0, len(names))
+	for _, name := range names {
+		links = append(links, formatLink(socials[name], name))
+	}
+	return strings.Join(links, " · ")
+}
+
+func formatFooter(channel string, mf MessageFormat) string {
+	if mf.Footer.Template == "" {
+		return ""
+	}
+	values := footerValues(channel, mf.Footer)
+	var lines []string
+	for _, line := range strings.Split(mf.Footer.Template, "\n") {
+		missing := false
+		line = footerVarPattern.ReplaceAllStringFunc(line, func(v string) string {
+			if values[v] == "" {
+				missing = true
+			}
+			return values[v]
+		})
+		if !missing {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func validateFooter(cfg FooterConfig) error {
+	for _, v := range footerVarPattern.FindAllString(cfg.Template, -1) {
+		if !slices.Contains(footerVars, v) {
+			return fmt.Errorf("unknown telegram.footer.template variable %s (available: %s)", v, strings.Join(footerVars, ", "))
+		}
+	}
+	return nil
+}
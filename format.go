@@ -2,9 +2,61 @@ package main
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 )
 
+type MessageFormat struct {
+	Localization
+	CategoryEmoji map[string]string
+	MatureBadge   string
+	HourlyGrowth  bool
+	Layout        []string
+	Hashtags      HashtagConfig
+	Footer        FooterConfig
+}
+
+var layoutSections = []string{"partners", "title", "note", "stats", "chat", "history", "marks", "highlights", "goals", "clips", "tags", "footer"}
+
+var defaultCategoryEmoji = map[string]string{
+	"just chatting":                 "🎙",
+	"talk shows & podcasts":         "🎙",
+	"art":                           "🎨",
+	"makers & crafting":             "🛠",
+	"music":                         "🎵",
+	"asmr":                          "🎧",
+	"software and game development": "💻",
+	"science & technology":          "🔬",
+	"food & drink":                  "🍳",
+	"travel & outdoors":             "🌍",
+	"sports":                        "⚽",
+	"chess":                         "♟",
+	"irl":                           "📷",
+	"*":                             "🎮",
+}
+
+func newMessageFormat(cfg *Config) MessageFormat {
+	mf := MessageFormat{Localization: getLocalization(cfg.Language)}
+	if cfg.Mature.Enabled {
+		mf.MatureBadge = cfg.Mature.Badge
+	}
+	mf.HourlyGrowth = cfg.ShowHourlyGrowth
+	mf.Layout = cfg.Layout
+	mf.Hashtags = cfg.Hashtags
+	mf.Footer = cfg.Telegram.Footer
+	if cfg.CategoryEmoji.Enabled {
+		mf.CategoryEmoji = make(map[string]string, len(defaultCategoryEmoji)+len(cfg.CategoryEmoji.Map))
+		for k, v := range defaultCategoryEmoji {
+			mf.CategoryEmoji[k] = v
+		}
+		for k, v := range cfg.CategoryEmoji.Map {
+			mf.CategoryEmoji[strings.ToLower(k)] = v
+		}
+	}
+	return mf
+}
+
 func escapeHTML(text string) string {
 	text = strings.ReplaceAll(text, "&", "&amp;")
 	text = strings.ReplaceAll(text, "<", "&lt;")
@@ -12,14 +64,29 @@ func escapeHTML(text string) string {
 	return text
 }
 
-func formatTags(tags []string) string {
-	var hashtags []string
-	for _, tag := range tags {
-		if tag != "" {
-			hashtags = append(hashtags, "#"+tag)
-		}
+func escapeAttr(text string) string {
+	return strings.ReplaceAll(escapeHTML(text), `"`, "&quot;")
+}
+
+func formatLink(url, text string) string {
+	return fmt.Sprintf(`<a href="%s">%s</a>`, escapeAttr(url), escapeHTML(text))
+}
+
+func formatGame(game string, mf MessageFormat) string {
+	if emoji := categoryEmoji(game, mf.CategoryEmoji); emoji != "" {
+		return emoji + " " + escapeHTML(game)
+	}
+	return escapeHTML(game)
+}
+
+func categoryEmoji(game string, mapping map[string]string) string {
+	if mapping == nil {
+		return ""
+	}
+	if emoji, ok := mapping[strings.ToLower(game)]; ok {
+		return emoji
 	}
-	return strings.Join(hashtags, " ")
+	return mapping["*"]
 }
 
 func formatClips(clips []ClipInfo) string {
@@ -28,7 +95,7 @@ func formatClips(clips []ClipInfo) string {
 	}
 	links := make([]string, 0, len(clips))
 	for _, c := range clips {
-		links = append(links, fmt.Sprintf("<a href=\"%s\">%s</a>", c.URL, escapeHTML(c.Title)))
+		links = append(links, formatLink(c.URL, c.Title))
 	}
 	return strings.Join(links, " · ")
 }
@@ -59,110 +126,338 @@ func viewerTrend(history []ViewerDataPoint, loc Localization) string {
 	}
 }
 
-func formatStartMessage(info *StreamInfo, loc Localization) string {
-	var b strings.Builder
+func formatStartMessage(info *StreamInfo, mf MessageFormat) string {
+	return renderLayout(formatHeader(info, mf.StartedStreaming, mf), map[string]string{
+		"partners": formatCoStream(info, mf),
+		"title":    formatTitle(info.Title),
+		"tags":     formatTags(info.Tags, mf),
+		"footer":   formatFooter(info.Channel, mf),
+	}, mf.Layout)
+}
 
-	line := fmt.Sprintf("<b>%s</b> • %s", escapeHTML(info.Channel), loc.StartedStreaming)
+func formatHeader(info *StreamInfo, status string, mf MessageFormat) string {
+	line := fmt.Sprintf("<b>%s</b> • %s", escapeHTML(info.Channel), status)
 	if info.Game != "" {
-		line += fmt.Sprintf(" • %s", escapeHTML(info.Game))
+		line += " • " + formatGame(info.Game, mf)
 	}
-	b.WriteString(line + "\n\n")
+	if info.Mature && mf.MatureBadge != "" {
+		line += " • " + mf.MatureBadge
+	}
+	return line
+}
 
-	if info.Title != "" {
-		b.WriteString(fmt.Sprintf("<i>%s</i>", escapeHTML(info.Title)))
+func formatTitle(title string) string {
+	if title == "" {
+		return ""
 	}
+	return fmt.Sprintf("<i>%s</i>", escapeHTML(title))
+}
 
-	if tags := formatTags(info.Tags); tags != "" {
-		b.WriteString("\n\n" + tags)
+func formatCoStream(info *StreamInfo, mf MessageFormat) string {
+	var lines []string
+	if p := formatPartners(info.Partners, mf); p != "" {
+		lines = append(lines, p)
+	}
+	if sq := formatSquad(info.Squad, mf); sq != "" {
+		lines = append(lines, sq)
 	}
+	return strings.Join(lines, "\n\n")
+}
 
-	return b.String()
+func renderLayout(header string, sections map[string]string, layout []string) string {
+	parts := []string{header}
+	for _, name := range layout {
+		if section := sections[name]; section != "" {
+			parts = append(parts, section)
+		}
+	}
+	return strings.Join(parts, "\n\n")
 }
 
-func formatUpdateMessage(info *StreamInfo, avgViewers int, history []ViewerDataPoint, loc Localization) string {
+func formatTeaserMessage(channel, title, game string, start time.Time, countdown string, mf MessageFormat) string {
 	var b strings.Builder
 
-	line := fmt.Sprintf("<b>%s</b> • %s", escapeHTML(info.Channel), loc.IsLive)
-	if info.Game != "" {
-		line += fmt.Sprintf(" • %s", escapeHTML(info.Game))
+	line := fmt.Sprintf("<b>%s</b> • %s", escapeHTML(channel), mf.StartingSoon)
+	if game != "" {
+		line += " • " + formatGame(game, mf)
 	}
 	b.WriteString(line + "\n\n")
 
-	if info.Title != "" {
-		b.WriteString(fmt.Sprintf("<i>%s</i>\n\n", escapeHTML(info.Title)))
+	if title != "" {
+		b.WriteString(fmt.Sprintf("<i>%s</i>\n\n", escapeHTML(title)))
+	}
+
+	b.WriteString(fmt.Sprintf("%s %s", mf.StartsAt, start.Local().Format("15:04")))
+	if countdown != "" {
+		b.WriteString(fmt.Sprintf(" · %s %s", mf.StartsIn, countdown))
+	}
+
+	return b.String()
+}
+
+func formatTopicName(start time.Time, title string) string {
+	name := start.Local().Format("02.01.2006")
+	if title != "" {
+		name += " — " + title
+	}
+	if r := []rune(name); len(r) > 128 {
+		name = string(r[:127]) + "…"
+	}
+	return name
+}
+
+func formatPartners(partners []string, mf MessageFormat) string {
+	if len(partners) == 0 {
+		return ""
+	}
+	links := make([]string, len(partners))
+	for i, p := range partners {
+		links[i] = formatLink("https://twitch.tv/"+p, p)
+	}
+	return fmt.Sprintf("🤝 %s %s", mf.CoStreamWith, strings.Join(links, ", "))
+}
+
+func formatSquad(members []string, mf MessageFormat) string {
+	if len(members) == 0 {
+		return ""
 	}
+	names := make([]string, len(members))
+	for i, m := range members {
+		names[i] = "<b>" + escapeHTML(m) + "</b>"
+	}
+	return fmt.Sprintf("👥 %s: %s", mf.Squad, strings.Join(names, ", "))
+}
 
+func formatLiveStats(info *StreamInfo, avgViewers int, history []ViewerDataPoint, mf MessageFormat) string {
 	var stats []string
 	if info.Uptime != "" {
 		stats = append(stats, info.Uptime)
 	}
 	if info.Viewers > 0 {
-		v := fmt.Sprintf("%s %s", formatViewers(info.Viewers), loc.Viewers)
+		v := fmt.Sprintf("%s %s", formatViewers(info.Viewers), mf.Viewers)
 		if avgViewers > 0 && avgViewers != info.Viewers {
-			v += fmt.Sprintf(", %s %s", formatViewers(avgViewers), loc.Avg)
+			v += fmt.Sprintf(", %s %s", formatViewers(avgViewers), mf.Avg)
 		}
-		if trend := viewerTrend(history, loc); trend != "" {
+		if trend := viewerTrend(history, mf.Localization); trend != "" {
 			v += " · " + trend
 		}
 		stats = append(stats, v)
 	}
 
-	b.WriteString(strings.Join(stats, " · "))
+	msg := strings.Join(stats, " · ")
+	if mf.HourlyGrowth {
+		if change, ok := viewerChange(history, time.Hour); ok {
+			msg += fmt.Sprintf("\n%s %s", formatViewerChange(change), mf.LastHour)
+		}
+	}
+	return msg
+}
+
+type LiveSummary struct {
+	Info       *StreamInfo
+	AvgViewers int
+	History    []ViewerDataPoint
+	Clips      []ClipInfo
+	Goals      []GoalProgress
+	Note       string
+	OnBreak    bool
+}
 
-	return b.String()
+func liveStatus(sum LiveSummary, mf MessageFormat) string {
+	if sum.OnBreak {
+		return mf.OnBreak
+	}
+	return mf.IsLive
 }
 
-func formatUpdateMessageWithClips(info *StreamInfo, avgViewers int, history []ViewerDataPoint, clips []ClipInfo, loc Localization) string {
-	msg := formatUpdateMessage(info, avgViewers, history, loc)
+func formatLiveMessage(sum LiveSummary, mf MessageFormat) string {
+	return renderLayout(formatHeader(sum.Info, liveStatus(sum, mf), mf), map[string]string{
+		"partners": formatCoStream(sum.Info, mf),
+		"title":    formatTitle(sum.Info.Title),
+		"note":     formatNote(sum.Note),
+		"stats":    formatLiveStats(sum.Info, sum.AvgViewers, sum.History, mf),
+		"chat":     formatChatModes(sum.Info.ChatModes, mf),
+		"goals":    formatGoals(sum.Goals, sum.Info.Followers, mf),
+		"clips":    formatClips(sum.Clips),
+		"tags":     formatTags(sum.Info.Tags, mf),
+		"footer":   formatFooter(sum.Info.Channel, mf),
+	}, mf.Layout)
+}
 
-	if c := formatClips(clips); c != "" {
-		msg += "\n\n" + c
+func formatGoals(goals []GoalProgress, followers *FollowerGoal, mf MessageFormat) string {
+	lines := make([]string, 0, len(goals)+1)
+	for _, g := range goals {
+		line := "🎯 "
+		if g.Label != "" {
+			line += escapeHTML(g.Label) + ": "
+		}
+		line += fmt.Sprintf("%s / %s", formatAmount(g.Current), formatAmount(g.Target))
+		if g.Currency != "" {
+			line += " " + escapeHTML(g.Currency)
+		}
+		if g.Target > 0 {
+			line += fmt.Sprintf(" (%.0f%%)", 100*g.Current/g.Target)
+		}
+		lines = append(lines, line)
 	}
-	if tags := formatTags(info.Tags); tags != "" {
-		msg += "\n\n" + tags
+	if f := formatFollowerGoal(followers, mf); f != "" {
+		lines = append(lines, f)
 	}
+	return strings.Join(lines, "\n")
+}
 
-	return msg
+func formatAmount(v float64) string {
+	if v == float64(int64(v)) {
+		return fmt.Sprintf("%d", int64(v))
+	}
+	return fmt.Sprintf("%.2f", v)
 }
 
-func formatEndMessage(channel, duration string, avgViewers, maxViewers int, game, title string, tags []string, clips []ClipInfo, loc Localization) string {
-	var b strings.Builder
+type EndSummary struct {
+	Channel    string
+	Duration   string
+	AvgViewers int
+	MaxViewers int
+	PeakAt     string
+	Retention  float64
+	Watched    float64
+	Baseline   *BaselineEntry
+	Game       string
+	Title      string
+	Tags       []string
+	Titles     []TitleEntry
+	Clips      []ClipInfo
+	History    []ViewerDataPoint
+	Marks      []MarkEntry
+	Highlights []HighlightEntry
+}
 
-	line := fmt.Sprintf("<b>%s</b> • %s", escapeHTML(channel), loc.StreamEnded)
-	if game != "" {
-		line += fmt.Sprintf(" • %s", escapeHTML(game))
+type TitleEntry struct {
+	At      string
+	Title   string
+	Viewers int
+}
+
+func formatEndMessage(sum EndSummary, mf MessageFormat) string {
+	header := fmt.Sprintf("<b>%s</b> • %s", escapeHTML(sum.Channel), mf.StreamEnded)
+	if sum.Game != "" {
+		header += " • " + formatGame(sum.Game, mf)
 	}
-	b.WriteString(line + "\n\n")
+	return renderLayout(header, map[string]string{
+		"title":      formatTitle(sum.Title),
+		"stats":      formatEndStats(sum, mf),
+		"history":    formatTitleHistory(sum.Titles, mf),
+		"marks":      formatMarks(sum.Marks, mf),
+		"highlights": formatHighlights(sum.Highlights, mf),
+		"clips":      formatEndClips(sum.Clips, mf),
+		"tags":       formatTags(sum.Tags, mf),
+		"footer":     formatFooter(sum.Channel, mf),
+	}, mf.Layout)
+}
 
-	if title != "" {
-		b.WriteString(fmt.Sprintf("<i>%s</i>\n\n", escapeHTML(title)))
+func formatEndClips(clips []ClipInfo, mf MessageFormat) string {
+	c := formatClips(clips)
+	if credits := formatClipCredits(clips, mf); c != "" && credits != "" {
+		c += "\n" + credits
 	}
+	return c
+}
 
+func formatEndStats(sum EndSummary, mf MessageFormat) string {
 	var stats []string
-	if duration != "" {
-		stats = append(stats, duration)
+	if sum.Duration != "" {
+		stats = append(stats, sum.Duration)
 	}
-	if avgViewers > 0 {
-		v := fmt.Sprintf("%s %s", formatViewers(avgViewers), loc.Avg)
-		if maxViewers > avgViewers {
-			v += fmt.Sprintf(", %s %s", formatViewers(maxViewers), loc.Peak)
+	if sum.AvgViewers > 0 {
+		v := fmt.Sprintf("%s %s", formatViewers(sum.AvgViewers), mf.Avg)
+		if sum.MaxViewers > sum.AvgViewers {
+			v += fmt.Sprintf(", %s %s", formatViewers(sum.MaxViewers), mf.Peak)
+			if sum.PeakAt != "" {
+				v += fmt.Sprintf(" %s %s", mf.PeakAt, sum.PeakAt)
+			}
 		}
 		stats = append(stats, v)
 	}
-	if len(clips) > 0 {
-		stats = append(stats, fmt.Sprintf("%d %s", len(clips), loc.Clips))
+	if b := formatBaseline(sum.Baseline, mf); b != "" {
+		stats = append(stats, b)
+	}
+	if sum.Retention > 0 {
+		stats = append(stats, fmt.Sprintf("%.0f%% %s", sum.Retention*100, mf.Retention))
+	}
+	if sum.Watched >= 1 {
+		stats = append(stats, fmt.Sprintf("%s %s", formatHours(sum.Watched), mf.HoursWatched))
 	}
+	if len(sum.Clips) > 0 {
+		stats = append(stats, fmt.Sprintf("%d %s", len(sum.Clips), mf.Clips))
+	}
+	return strings.Join(stats, " · ")
+}
 
-	b.WriteString(strings.Join(stats, " · "))
+func formatEndDetails(sum EndSummary, mf MessageFormat) string {
+	return renderLayout(formatEndStats(sum, mf), map[string]string{
+		"history":    formatTitleHistory(sum.Titles, mf),
+		"marks":      formatMarks(sum.Marks, mf),
+		"highlights": formatHighlights(sum.Highlights, mf),
+		"clips":      formatEndClips(sum.Clips, mf),
+	}, mf.Layout)
+}
 
-	if c := formatClips(clips); c != "" {
-		b.WriteString("\n\n" + c)
+func formatTitleHistory(titles []TitleEntry, mf MessageFormat) string {
+	if len(titles) < 2 {
+		return ""
 	}
-	if hashtags := formatTags(tags); hashtags != "" {
-		b.WriteString("\n\n" + hashtags)
+	lines := []string{"📝 " + mf.Titles + ":"}
+	for _, t := range titles {
+		line := fmt.Sprintf("%s — <i>%s</i>", t.At, escapeHTML(t.Title))
+		if t.Viewers > 0 {
+			line += fmt.Sprintf(" (%s)", formatViewers(t.Viewers))
+		}
+		lines = append(lines, line)
 	}
+	return strings.Join(lines, "\n")
+}
 
-	return b.String()
+func formatClipCredits(clips []ClipInfo, mf MessageFormat) string {
+	counts := map[string]int{}
+	var creators []string
+	for _, c := range clips {
+		if c.Creator == "" {
+			continue
+		}
+		if counts[c.Creator] == 0 {
+			creators = append(creators, c.Creator)
+		}
+		counts[c.Creator]++
+	}
+	if len(creators) == 0 {
+		return ""
+	}
+	sort.SliceStable(creators, func(i, j int) bool {
+		return counts[creators[i]] > counts[creators[j]]
+	})
+
+	names := make([]string, len(creators))
+	for i, name := range creators {
+		names[i] = escapeHTML(name)
+		if counts[name] > 1 {
+			names[i] += fmt.Sprintf(" (%d)", counts[name])
+		}
+	}
+	return fmt.Sprintf("🙌 %s: %s", mf.ClipThanks, strings.Join(names, ", "))
+}
+
+func formatViewerChange(n int) string {
+	if n < 0 {
+		return "−" + formatViewers(-n)
+	}
+	return "+" + formatViewers(n)
+}
+
+func formatHours(h float64) string {
+	if h < 10 {
+		return fmt.Sprintf("%.1f", h)
+	}
+	return formatViewers(int(h))
 }
 
 func formatViewers(n int) string {
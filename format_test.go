@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	telegramTags   = []string{"b", "strong", "i", "em", "u", "ins", "s", "strike", "del", "a", "code", "pre", "blockquote", "tg-spoiler", "span", "tg-emoji"}
+	telegramEntity = regexp.MustCompile(`^&(amp|lt|gt|quot|#[0-9]+|#x[0-9a-fA-F]+);`)
+	telegramAttr   = regexp.MustCompile(`^[a-z-]+="[^"<>]*"$`)
+)
+
+func checkTelegramHTML(s string) error {
+	var open []string
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '<':
+			end := strings.IndexByte(s[i:], '>')
+			if end < 0 {
+				return fmt.Errorf("unterminated tag at %d", i)
+			}
+			tag := s[i+1 : i+end]
+			if name, ok := strings.CutPrefix(tag, "/"); ok {
+				if len(open) == 0 || open[len(open)-1] != name {
+					return fmt.Errorf("unexpected </%s> at %d, open %v", name, i, open)
+				}
+				open = open[:len(open)-1]
+			} else {
+				name, attrs, _ := strings.Cut(tag, " ")
+				if !slices.Contains(telegramTags, name) {
+					return fmt.Errorf("unsupported tag <%s> at %d", name, i)
+				}
+				if attrs != "" && !telegramAttr.MatchString(attrs) {
+					return fmt.Errorf("malformed attributes %q at %d", attrs, i)
+				}
+				open = append(open, name)
+			}
+			i += end + 1
+		case '&':
+			m := telegramEntity.FindString(s[i:])
+			if m == "" {
+				return fmt.Errorf("raw & at %d", i)
+			}
+			i += len(m)
+		case '>':
+			return fmt.Errorf("raw > at %d", i)
+		default:
+			i++
+		}
+	}
+	if len(open) > 0 {
+		return fmt.Errorf("unclosed tags %v", open)
+	}
+	return nil
+}
+
+func fuzzMessageFormat(lang string, transliterate bool) MessageFormat {
+	cfg := &Config{Language: lang, Layout: layoutSections}
+	cfg.Hashtags.Transliterate = transliterate
+	cfg.CategoryEmoji.Enabled = true
+	cfg.Mature.Enabled = true
+	cfg.Mature.Badge = "🔞"
+	return newMessageFormat(cfg)
+}
+
+func TestCheckTelegramHTML(t *testing.T) {
+	for _, s := range []string{"", "plain", `<b>x</b> &amp; <a href="https://x?a=1&amp;b=2">y</a>`, "<i>a<b>b</b></i>"} {
+		if err := checkTelegramHTML(s); err != nil {
+			t.Errorf("%q: %v", s, err)
+		}
+	}
+	for _, s := range []string{"<b>x", "</b>", "<b><i>x</b></i>", "a & b", "a < b", "a > b", "<script>", `<a href="x"y">z</a>`} {
+		if checkTelegramHTML(s) == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func FuzzFormatClips(f *testing.F) {
+	f.Add("https://clips.twitch.tv/abc", "Funny moment", "https://clips.twitch.tv/def", "")
+	f.Add(`https://x/?a=1&b="2"`, "<b>&amp;</b>", "javascript:alert(1)", "a > b & c < d")
+	f.Fuzz(func(t *testing.T, url1, title1, url2, title2 string) {
+		out := formatClips([]ClipInfo{{URL: url1, Title: title1}, {URL: url2, Title: title2}})
+		if err := checkTelegramHTML(out); err != nil {
+			t.Fatalf("%q: %v", out, err)
+		}
+	})
+}
+
+func FuzzFormatTags(f *testing.F) {
+	f.Add("English", "Chill", "ru", false)
+	f.Add("Русский", "<script>&", "ru", true)
+	f.Add("", "   ", "en", true)
+	f.Fuzz(func(t *testing.T, tag1, tag2, lang string, transliterate bool) {
+		out := formatTags([]string{tag1, tag2}, fuzzMessageFormat(lang, transliterate))
+		if err := checkTelegramHTML(out); err != nil {
+			t.Fatalf("%q: %v", out, err)
+		}
+	})
+}
+
+func FuzzFormatStartMessage(f *testing.F) {
+	f.Add("somechannel", "Sample stream title", "Just Chatting", "English", "partner", false, "en")
+	f.Add("<b>", "a & b <i>", "Dota 2 <3", "&lt;", `"x"`, true, "ru")
+	f.Fuzz(func(t *testing.T, channel, title, game, tag, partner string, mature bool, lang string) {
+		info := &StreamInfo{
+			Channel:   channel,
+			URL:       "https://twitch.tv/" + channel,
+			Title:     title,
+			Game:      game,
+			Tags:      []string{tag},
+			StartedAt: time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC),
+			Mature:    mature,
+			Partners:  []string{partner},
+			Squad:     []string{partner},
+		}
+		out := formatStartMessage(info, fuzzMessageFormat(lang, true))
+		if err := checkTelegramHTML(out); err != nil {
+			t.Fatalf("%q: %v", out, err)
+		}
+	})
+}
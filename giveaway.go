@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+)
+
+type Giveaway struct {
+	cfg  *Config
+	loc  Localization
+	live *LiveState
+
+	mu        sync.Mutex
+	active    bool
+	prize     string
+	messageID int
+	entrants  []TelegramUser
+}
+
+func newGiveaway(cfg *Config, live *LiveState) *Giveaway {
+	return &Giveaway{cfg: cfg, loc: getLocalization(cfg.Language), live: live}
+}
+
+func (g *Giveaway) Register(r *CommandRouter) {
+	r.Handle(Command{Name: "giveaway", Admin: true, Handler: g.Command})
+}
+
+func (g *Giveaway) Command(ctx context.Context, msg *TelegramMessage, args string) (string, error) {
+	loc := replyLocalization(g.cfg, msg)
+	action, rest, _ := strings.Cut(args, " ")
+	switch strings.ToLower(action) {
+	case "start":
+		return g.start(loc, strings.TrimSpace(rest))
+	case "draw":
+		return g.draw(loc)
+	case "cancel":
+		return g.cancel(loc)
+	}
+	return loc.GiveawayUsage, nil
+}
+
+func (g *Giveaway) start(loc ReplyLocalization, prize string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.active {
+		return loc.GiveawayActive, nil
+	}
+
+	cfg := g.cfg
+	replyTo := 0
+	if snap, ok := g.live.Snapshot(); ok {
+		replyTo = snap.MessageID
+	}
+	g.prize = prize
+	g.entrants = nil
+	messageID, err := sendKeyboardMessage(cfg.Telegram.BotToken, *cfg.Telegram.ChatID, cfg.Telegram.ThreadID, replyTo, g.text(""), g.keyboard())
+	if err != nil {
+		return "", err
+	}
+	g.active = true
+	g.messageID = messageID
+	slog.Info("giveaway started", "message_id", messageID)
+	return loc.GiveawayPosted, nil
+}
+
+func (g *Giveaway) draw(loc ReplyLocalization) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.active {
+		return loc.NoGiveaway, nil
+	}
+	if len(g.entrants) == 0 {
+		return loc.NoEntrants, nil
+	}
+
+	cfg := g.cfg
+	winner := mentionUser(g.entrants[rand.IntN(len(g.entrants))])
+	g.active = false
+	slog.Info("giveaway drawn", "participants", len(g.entrants))
+	if err := editMessageText(cfg.Telegram.BotToken, *cfg.Telegram.ChatID, g.messageID, g.text(winner), nil); err != nil {
+		slog.Warn("failed to update giveaway message", "error", err)
+	}
+	if err := sendReply(cfg.Telegram.BotToken, *cfg.Telegram.ChatID, g.messageID, fmt.Sprintf("🏆 %s: %s", g.loc.Winner, winner)); err != nil {
+		slog.Warn("failed to announce giveaway winner", "error", err)
+	}
+	return fmt.Sprintf("%s: %s", loc.GiveawayWinner, winner), nil
+}
+
+func (g *Giveaway) cancel(loc ReplyLocalization) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.active {
+		return loc.NoGiveaway, nil
+	}
+	g.active = false
+	cfg := g.cfg
+	text := fmt.Sprintf("🎁 <b>%s</b>\n\n%s", g.loc.Giveaway, g.loc.GiveawayCanceled)
+	if err := editMessageText(cfg.Telegram.BotToken, *cfg.Telegram.ChatID, g.messageID, text, nil); err != nil {
+		slog.Warn("failed to update giveaway message", "error", err)
+	}
+	return loc.GiveawayCanceled, nil
+}
+
+func (g *Giveaway) HandleUpdate(ctx context.Context, u TelegramUpdate) {
+	q := u.CallbackQuery
+	if q == nil || q.Message == nil || q.Data != "giveaway:join" {
+		return
+	}
+	cfg := g.cfg
+
+	g.mu.Lock()
+	var reply string
+	joined := false
+	switch {
+	case !g.active || q.Message.MessageID != g.messageID:
+		reply = g.loc.GiveawayEnded
+	case slices.ContainsFunc(g.entrants, func(e TelegramUser) bool { return e.ID == q.From.ID }):
+		reply = g.loc.AlreadyJoined
+	default:
+		g.entrants = append(g.entrants, q.From)
+		reply = g.loc.GiveawayJoined
+		joined = true
+	}
+	text := g.text("")
+	g.mu.Unlock()
+
+	if err := answerCallbackQuery(cfg.Telegram.BotToken, q.ID, reply); err != nil {
+		slog.Warn("failed to answer callback query", "error", err)
+	}
+	if joined {
+		if err := editMessageText(cfg.Telegram.BotToken, q.Message.Chat.ID, q.Message.MessageID, text, g.keyboard()); err != nil {
+			slog.Warn("failed to update giveaway message", "error", err)
+		}
+	}
+}
+
+func (g *Giveaway) text(winner string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "🎁 <b>%s</b>", g.loc.Giveaway)
+	if g.prize != "" {
+		b.WriteString("\n\n" + escapeHTML(g.prize))
+	}
+	fmt.Fprintf(&b, "\n\n%s: %d", g.loc.Participants, len(g.entrants))
+	if winner != "" {
+		fmt.Fprintf(&b, "\n🏆 %s: %s", g.loc.Winner, winner)
+	}
+	return b.String()
+}
+
+func (g *Giveaway) keyboard() [][]InlineButton {
+	return [][]InlineButton{{{Text: g.loc.GiveawayJoin, CallbackData: "giveaway:join"}}}
+}
+
+func mentionUser(u TelegramUser) string {
+	return formatLink(fmt.Sprintf("tg://user?id=%d", u.ID), userLabel(u))
+}
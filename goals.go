@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type GoalConfig struct {
+	Provider  string  `json:"provider"`
+	Token     string  `json:"token"`
+	ChannelID string  `json:"channel_id"`
+	Label     string  `json:"label"`
+	Target    float64 `json:"target"`
+	Currency  string  `json:"currency"`
+	Since     string  `json:"since"`
+}
+
+type GoalProgress struct {
+	Label    string
+	Current  float64
+	Target   float64
+	Currency string
+}
+
+type GoalFetcher interface {
+	Fetch(ctx context.Context) (*GoalProgress, error)
+}
+
+func newGoalFetchers(configs []GoalConfig) []GoalFetcher {
+	var fetchers []GoalFetcher
+	for _, c := range configs {
+		switch c.Provider {
+		case "streamelements":
+			fetchers = append(fetchers, &streamElementsGoal{cfg: c})
+		case "donationalerts":
+			fetchers = append(fetchers, &donationAlertsGoal{cfg: c})
+		default:
+			slog.Warn("unknown goal provider", "provider", c.Provider)
+		}
+	}
+	return fetchers
+}
+
+func fetchGoals(ctx context.Context, fetchers []GoalFetcher) []GoalProgress {
+	var goals []GoalProgress
+	for _, f := range fetchers {
+		g, err := f.Fetch(ctx)
+		if err != nil {
+			slog.Warn("failed to fetch goal progress", "error", err)
+			continue
+		}
+		goals = append(goals, *g)
+	}
+	return goals
+}
+
+func goalGet(ctx context.Context, url, token string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := externalHTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("goal API error (%d): %s", resp.StatusCode, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type streamElementsGoal struct {
+	cfg GoalConfig
+}
+
+func (g *streamElementsGoal) Fetch(ctx context.Context) (*GoalProgress, error) {
+	url := fmt.Sprintf("https://api.streamelements.com/kappa/v2/sessions/%s", g.cfg.ChannelID)
+
+	var resp struct {
+		Data struct {
+			TipGoal struct {
+				Amount float64 `json:"amount"`
+			} `json:"tip-goal"`
+		} `json:"data"`
+	}
+	if err := goalGet(ctx, url, g.cfg.Token, &resp); err != nil {
+		return nil, err
+	}
+	return &GoalProgress{
+		Label:    g.cfg.Label,
+		Current:  resp.Data.TipGoal.Amount,
+		Target:   g.cfg.Target,
+		Currency: g.cfg.Currency,
+	}, nil
+}
+
+type donationAlertsGoal struct {
+	cfg GoalConfig
+}
+
+func (g *donationAlertsGoal) Fetch(ctx context.Context) (*GoalProgress, error) {
+	var since time.Time
+	if g.cfg.Since != "" {
+		t, err := time.Parse("2006-01-02", g.cfg.Since)
+		if err != nil {
+			return nil, fmt.Errorf("invalid goal start date %q: %w", g.cfg.Since, err)
+		}
+		since = t
+	}
+
+	var total float64
+	for page := 1; page <= 20; page++ {
+		url := fmt.Sprintf("https://www.donationalerts.com/api/v1/alerts/donations?page=%d", page)
+		var resp struct {
+			Data []struct {
+				Amount    float64 `json:"amount"`
+				Currency  string  `json:"currency"`
+				CreatedAt string  `json:"created_at"`
+			} `json:"data"`
+			Meta struct {
+				LastPage int `json:"last_page"`
+			} `json:"meta"`
+		}
+		if err := goalGet(ctx, url, g.cfg.Token, &resp); err != nil {
+			return nil, err
+		}
+
+		reachedStart := false
+		for _, d := range resp.Data {
+			created, err := time.Parse("2006-01-02 15:04:05", d.CreatedAt)
+			if err == nil && created.Before(since) {
+				reachedStart = true
+				break
+			}
+			if g.cfg.Currency == "" || d.Currency == g.cfg.Currency {
+				total += d.Amount
+			}
+		}
+		if reachedStart || page >= resp.Meta.LastPage {
+			break
+		}
+	}
+
+	return &GoalProgress{
+		Label:    g.cfg.Label,
+		Current:  total,
+		Target:   g.cfg.Target,
+		Currency: g.cfg.Currency,
+	}, nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+var grafanaMetrics = []string{"viewers", "avg_viewers", "peak_viewers"}
+
+type grafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+func (r grafanaRange) contains(t time.Time) bool {
+	return !t.Before(r.From) && !t.After(r.To)
+}
+
+type grafanaQuery struct {
+	Range   grafanaRange `json:"range"`
+	Targets []struct {
+		Target string `json:"target"`
+	} `json:"targets"`
+}
+
+type grafanaSeries struct {
+	Target     string     `json:"target"`
+	Datapoints [][2]int64 `json:"datapoints"`
+}
+
+type grafanaAnnotationQuery struct {
+	Range      grafanaRange `json:"range"`
+	Annotation struct {
+		Name  string `json:"name"`
+		Query string `json:"query"`
+	} `json:"annotation"`
+}
+
+type grafanaAnnotation struct {
+	Annotation any      `json:"annotation"`
+	Time       int64    `json:"time"`
+	TimeEnd    int64    `json:"timeEnd"`
+	IsRegion   bool     `json:"isRegion"`
+	Title      string   `json:"title"`
+	Text       string   `json:"text"`
+	Tags       []string `json:"tags"`
+}
+
+type GrafanaDatasource struct {
+	archives map[string]*SessionArchive
+	live     *LiveState
+	channel  string
+}
+
+func newGrafanaDatasource(cfg *Config, archives map[string]*SessionArchive, live *LiveState) *GrafanaDatasource {
+	return &GrafanaDatasource{archives: archives, live: live, channel: strings.ToLower(cfg.Twitch.Channel)}
+}
+
+func (g *GrafanaDatasource) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /grafana/{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("POST /grafana/search", g.search)
+	mux.HandleFunc("POST /grafana/query", g.query)
+	mux.HandleFunc("POST /grafana/annotations", g.annotations)
+}
+
+func (g *GrafanaDatasource) channels() []string {
+	channels := make([]string, 0, len(g.archives))
+	for ch := range g.archives {
+		channels = append(channels, ch)
+	}
+	slices.Sort(channels)
+	return channels
+}
+
+func (g *GrafanaDatasource) search(w http.ResponseWriter, r *http.Request) {
+	var targets []string
+	for _, ch := range g.channels() {
+		for _, m := range grafanaMetrics {
+			targets = append(targets, ch+"."+m)
+		}
+	}
+	writeJSON(w, targets)
+}
+
+func (g *GrafanaDatasource) query(w http.ResponseWriter, r *http.Request) {
+	var q grafanaQuery
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid query: %w", err))
+		return
+	}
+
+	series := []grafanaSeries{}
+	for _, t := range q.Targets {
+		channel, metric, ok := strings.Cut(strings.ToLower(t.Target), ".")
+		archive := g.archives[channel]
+		if !ok || archive == nil || !slices.Contains(grafanaMetrics, metric) {
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("unknown target %q", t.Target))
+			return
+		}
+		records, err := archive.Load(r.Context())
+		if err != nil {
+			slog.Error("failed to load sessions for grafana", "channel", channel, "error", err)
+			writeAPIError(w, http.StatusInternalServerError, fmt.Errorf("failed to load sessions"))
+			return
+		}
+
+		s := grafanaSeries{Target: t.Target, Datapoints: [][2]int64{}}
+		for _, rec := range records {
+			if !q.Range.contains(rec.StartTime) && !q.Range.contains(rec.EndTime) {
+				continue
+			}
+			switch metric {
+			case "viewers":
+				s.Datapoints = appendViewerPoints(s.Datapoints, rec.ViewerHistory, q.Range)
+			case "avg_viewers":
+				s.Datapoints = append(s.Datapoints, [2]int64{int64(rec.AvgViewers), rec.StartTime.UnixMilli()})
+			case "peak_viewers":
+				s.Datapoints = append(s.Datapoints, [2]int64{int64(rec.PeakViewers), rec.PeakAt.UnixMilli()})
+			}
+		}
+		if snap, live := g.live.Snapshot(); live && metric == "viewers" && channel == g.channel {
+			s.Datapoints = appendViewerPoints(s.Datapoints, snap.ViewerHistory, q.Range)
+		}
+		series = append(series, s)
+	}
+	writeJSON(w, series)
+}
+
+func appendViewerPoints(points [][2]int64, history []ViewerDataPoint, r grafanaRange) [][2]int64 {
+	for _, p := range history {
+		if r.contains(p.Timestamp) {
+			points = append(points, [2]int64{int64(p.Count), p.Timestamp.UnixMilli()})
+		}
+	}
+	return points
+}
+
+func (g *GrafanaDatasource) annotations(w http.ResponseWriter, r *http.Request) {
+	var q grafanaAnnotationQuery
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid annotation query: %w", err))
+		return
+	}
+
+	channels := g.channels()
+	if query := strings.ToLower(strings.TrimSpace(q.Annotation.Query)); query != "" {
+		channels = []string{query}
+	}
+
+	annotations := []grafanaAnnotation{}
+	for _, ch := range channels {
+		archive := g.archives[ch]
+		if archive == nil {
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("channel %q is not monitored", ch))
+			return
+		}
+		records, err := archive.Load(r.Context())
+		if err != nil {
+			slog.Error("failed to load sessions for grafana", "channel", ch, "error", err)
+			writeAPIError(w, http.StatusInternalServerError, fmt.Errorf("failed to load sessions"))
+			return
+		}
+		for _, rec := range records {
+			if rec.EndTime.Before(q.Range.From) || rec.StartTime.After(q.Range.To) {
+				continue
+			}
+			tags := []string{ch}
+			if rec.Game != "" {
+				tags = append(tags, rec.Game)
+			}
+			annotations = append(annotations, grafanaAnnotation{
+				Annotation: q.Annotation,
+				Time:       rec.StartTime.UnixMilli(),
+				TimeEnd:    rec.EndTime.UnixMilli(),
+				IsRegion:   true,
+				Title:      rec.Title,
+				Text:       fmt.Sprintf("%s: %d avg, %d peak", rec.Game, rec.AvgViewers, rec.PeakViewers),
+				Tags:       tags,
+			})
+			for _, m := range rec.Marks {
+				annotations = append(annotations, grafanaAnnotation{
+					Annotation: q.Annotation,
+					Time:       m.Time.UnixMilli(),
+					TimeEnd:    m.Time.UnixMilli(),
+					Title:      m.Text,
+					Text:       m.Text,
+					Tags:       []string{ch, "mark"},
+				})
+			}
+		}
+	}
+	writeJSON(w, annotations)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
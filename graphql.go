@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type gqlField struct {
+	Alias      string
+	Name       string
+	Args       map[string]any
+	Selections []gqlField
+}
+
+type gqlVariable string
+
+type gqlResolver func(ctx context.Context, args map[string]any) (any, error)
+
+type gqlObject map[string]gqlResolver
+
+type gqlResult []gqlEntry
+
+type gqlEntry struct {
+	Key   string
+	Value any
+}
+
+func (r gqlResult) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, e := range r {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(e.Key)
+		b.Write(key)
+		b.WriteByte(':')
+		value, err := json.Marshal(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+type gqlParser struct {
+	src  string
+	pos  int
+	vars map[string]any
+}
+
+func parseGraphQL(src string, vars map[string]any) ([]gqlField, error) {
+	p := &gqlParser{src: src, vars: vars}
+	p.skip()
+	if name := p.peekName(); name == "query" {
+		p.name()
+		p.skip()
+		if p.peekName() != "" {
+			p.name()
+		}
+		if p.peek() == '(' {
+			if err := p.variableDefinitions(); err != nil {
+				return nil, err
+			}
+		}
+	} else if name != "" {
+		return nil, fmt.Errorf("unsupported operation %q", name)
+	}
+	fields, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	p.skip()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos:p.pos+1])
+	}
+	return fields, nil
+}
+
+func (p *gqlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("syntax error at %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *gqlParser) skip() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		case c == ',' || unicode.IsSpace(rune(c)):
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *gqlParser) peek() byte {
+	p.skip()
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *gqlParser) expect(c byte) error {
+	if p.peek() != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+func isNameByte(c byte, first bool) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || !first && c >= '0' && c <= '9'
+}
+
+func (p *gqlParser) peekName() string {
+	p.skip()
+	end := p.pos
+	for end < len(p.src) && isNameByte(p.src[end], end == p.pos) {
+		end++
+	}
+	return p.src[p.pos:end]
+}
+
+func (p *gqlParser) name() (string, error) {
+	name := p.peekName()
+	if name == "" {
+		return "", p.errorf("expected name")
+	}
+	p.pos += len(name)
+	return name, nil
+}
+
+func (p *gqlParser) variableDefinitions() error {
+	p.pos++
+	for p.peek() != ')' {
+		if err := p.expect('$'); err != nil {
+			return err
+		}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(':'); err != nil {
+			return err
+		}
+		for p.peek() == '[' || p.peek() == ']' || p.peek() == '!' {
+			p.pos++
+		}
+		if _, err := p.name(); err != nil {
+			return err
+		}
+		for p.peek() == ']' || p.peek() == '!' {
+			p.pos++
+		}
+		if p.peek() == '=' {
+			p.pos++
+			def, err := p.value()
+			if err != nil {
+				return err
+			}
+			if _, ok := p.vars[name]; !ok {
+				if p.vars == nil {
+					p.vars = make(map[string]any)
+				}
+				p.vars[name] = def
+			}
+		}
+		if p.pos >= len(p.src) {
+			return p.errorf("unterminated variable definitions")
+		}
+	}
+	p.pos++
+	return nil
+}
+
+func (p *gqlParser) selectionSet() ([]gqlField, error) {
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+	var fields []gqlField
+	for p.peek() != '}' {
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unterminated selection set")
+		}
+		if strings.HasPrefix(p.src[p.pos:], "...") {
+			return nil, p.errorf("fragments are not supported")
+		}
+		f, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	p.pos++
+	return fields, nil
+}
+
+func (p *gqlParser) field() (gqlField, error) {
+	name, err := p.name()
+	if err != nil {
+		return gqlField{}, err
+	}
+	f := gqlField{Alias: name, Name: name}
+	if p.peek() == ':' {
+		p.pos++
+		if f.Name, err = p.name(); err != nil {
+			return gqlField{}, err
+		}
+	}
+	if p.peek() == '(' {
+		p.pos++
+		f.Args = make(map[string]any)
+		for p.peek() != ')' {
+			arg, err := p.name()
+			if err != nil {
+				return gqlField{}, err
+			}
+			if err := p.expect(':'); err != nil {
+				return gqlField{}, err
+			}
+			if f.Args[arg], err = p.value(); err != nil {
+				return gqlField{}, err
+			}
+		}
+		p.pos++
+	}
+	if p.peek() == '{' {
+		if f.Selections, err = p.selectionSet(); err != nil {
+			return gqlField{}, err
+		}
+	}
+	return f, nil
+}
+
+func (p *gqlParser) value() (any, error) {
+	c := p.peek()
+	switch {
+	case c == '$':
+		p.pos++
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		return p.vars[name], nil
+	case c == '"':
+		end := p.pos + 1
+		for end < len(p.src) && p.src[end] != '"' {
+			if p.src[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(p.src) {
+			return nil, p.errorf("unterminated string")
+		}
+		s, err := strconv.Unquote(p.src[p.pos : end+1])
+		if err != nil {
+			return nil, p.errorf("invalid string")
+		}
+		p.pos = end + 1
+		return s, nil
+	case c == '-' || c >= '0' && c <= '9':
+		end := p.pos + 1
+		for end < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[end]) >= 0 {
+			end++
+		}
+		lit := p.src[p.pos:end]
+		p.pos = end
+		if n, err := strconv.ParseInt(lit, 10, 64); err == nil {
+			return float64(n), nil
+		}
+		n, err := strconv.ParseFloat(lit, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", lit)
+		}
+		return n, nil
+	default:
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return name, nil
+	}
+}
+
+func executeGraphQL(ctx context.Context, obj gqlObject, fields []gqlField, path string) (gqlResult, error) {
+	result := make(gqlResult, 0, len(fields))
+	for _, f := range fields {
+		resolve, ok := obj[f.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q at %s", f.Name, path)
+		}
+		v, err := resolve(ctx, f.Args)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", path, f.Name, err)
+		}
+		v, err = completeGraphQL(ctx, v, f, path+"."+f.Alias)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, gqlEntry{Key: f.Alias, Value: v})
+	}
+	return result, nil
+}
+
+func completeGraphQL(ctx context.Context, v any, f gqlField, path string) (any, error) {
+	switch v := v.(type) {
+	case gqlObject:
+		if v == nil {
+			return nil, nil
+		}
+		if f.Selections == nil {
+			return nil, fmt.Errorf("field %s requires a selection set", path)
+		}
+		return executeGraphQL(ctx, v, f.Selections, path)
+	case []gqlObject:
+		if f.Selections == nil {
+			return nil, fmt.Errorf("field %s requires a selection set", path)
+		}
+		list := make([]gqlResult, 0, len(v))
+		for _, item := range v {
+			r, err := executeGraphQL(ctx, item, f.Selections, path)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, r)
+		}
+		return list, nil
+	default:
+		if f.Selections != nil {
+			return nil, fmt.Errorf("field %s is a scalar and has no fields", path)
+		}
+		return v, nil
+	}
+}
+
+func gqlValue(v any) gqlResolver {
+	return func(context.Context, map[string]any) (any, error) { return v, nil }
+}
+
+func gqlIntArg(args map[string]any, name string, def int) (int, error) {
+	v, ok := args[name]
+	if !ok || v == nil {
+		return def, nil
+	}
+	n, ok := v.(float64)
+	if !ok || n != float64(int(n)) {
+		return 0, fmt.Errorf("argument %q must be an integer", name)
+	}
+	return int(n), nil
+}
+
+type GraphQLHandler struct {
+	root func() gqlObject
+}
+
+func (h *GraphQLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query     string         `json:"query"`
+		Variables map[string]any `json:"variables"`
+	}
+	if r.Method == http.MethodGet {
+		req.Query = r.URL.Query().Get("query")
+		if v := r.URL.Query().Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeGraphQLError(w, http.StatusBadRequest, fmt.Errorf("invalid variables: %w", err))
+				return
+			}
+		}
+	} else if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeGraphQLError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+
+	fields, err := parseGraphQL(req.Query, req.Variables)
+	if err != nil {
+		writeGraphQLError(w, http.StatusBadRequest, err)
+		return
+	}
+	data, err := executeGraphQL(r.Context(), h.root(), fields, "query")
+	if err != nil {
+		writeGraphQLError(w, http.StatusOK, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"data": data})
+}
+
+func writeGraphQLError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"data":   nil,
+		"errors": []map[string]string{{"message": err.Error()}},
+	})
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "telegram-monitor/proto"
+)
+
+//go:generate protoc -I proto --go_out=proto --go_opt=paths=source_relative --go-grpc_out=proto --go-grpc_opt=paths=source_relative monitor.proto
+
+type GRPCConfig struct {
+	Listen string `json:"listen"`
+	Token  string `json:"token"`
+}
+
+type GRPCServer struct {
+	pb.UnimplementedMonitorServer
+
+	cfg     *Config
+	live    *LiveState
+	feed    *EventFeed
+	control *MonitorControl
+}
+
+func newGRPCServer(cfg *Config, live *LiveState, feed *EventFeed, control *MonitorControl) *GRPCServer {
+	return &GRPCServer{cfg: cfg, live: live, feed: feed, control: control}
+}
+
+func (s *GRPCServer) Run(ctx context.Context) {
+	lis, err := net.Listen("tcp", s.cfg.GRPC.Listen)
+	if err != nil {
+		slog.Error("grpc server failed", "error", err)
+		return
+	}
+	srv := s.newServer()
+	go func() {
+		<-ctx.Done()
+		stopped := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			srv.Stop()
+		}
+	}()
+
+	slog.Info("grpc server started", "addr", lis.Addr().String())
+	if err := srv.Serve(lis); err != nil {
+		slog.Error("grpc server failed", "error", err)
+	}
+}
+
+func (s *GRPCServer) newServer() *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := s.authorize(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := s.authorize(ss.Context()); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	)
+	pb.RegisterMonitorServer(srv, s)
+	return srv
+}
+
+func (s *GRPCServer) authorize(ctx context.Context) error {
+	if s.cfg.GRPC.Token == "" {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	got := md.Get("authorization")
+	if len(got) == 0 || subtle.ConstantTimeCompare([]byte(got[0]), []byte("Bearer "+s.cfg.GRPC.Token)) != 1 {
+		return status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return nil
+}
+
+func (s *GRPCServer) GetStatus(ctx context.Context, _ *pb.GetStatusRequest) (*pb.Status, error) {
+	st := &pb.Status{Channel: s.cfg.Twitch.Channel, Paused: s.control.Paused()}
+	if snap, ok := s.live.Snapshot(); ok {
+		st.Live = true
+		st.Title = snap.Info.Title
+		st.Game = snap.Info.Game
+		st.Viewers = int32(snap.Info.Viewers)
+		st.Tags = snap.Info.Tags
+		st.StartedAt = snap.StartTime.Unix()
+		st.AvgViewers = int32(snap.AvgViewers())
+		st.PeakViewers = int32(snap.PeakViewers())
+	}
+	return st, nil
+}
+
+func (s *GRPCServer) StreamEvents(_ *pb.StreamEventsRequest, stream grpc.ServerStreamingServer[pb.StreamEvent]) error {
+	events, unsubscribe := s.feed.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case ev := <-events:
+			if err := stream.Send(streamEventProto(ev)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *GRPCServer) ForceUpdate(ctx context.Context, _ *pb.ForceUpdateRequest) (*pb.ForceUpdateResponse, error) {
+	slog.Info("forced update requested over grpc")
+	s.control.ForceUpdate()
+	return &pb.ForceUpdateResponse{}, nil
+}
+
+func (s *GRPCServer) Pause(ctx context.Context, req *pb.PauseRequest) (*pb.PauseResponse, error) {
+	s.control.SetPaused(req.GetPaused())
+	return &pb.PauseResponse{Paused: s.control.Paused()}, nil
+}
+
+func streamEventProto(ev EventPayload) *pb.StreamEvent {
+	return &pb.StreamEvent{
+		Type:         string(ev.Type),
+		Time:         ev.Time.Unix(),
+		Channel:      ev.Channel,
+		Title:        ev.Title,
+		Game:         ev.Game,
+		Viewers:      int32(ev.Viewers),
+		Tags:         ev.Tags,
+		PreviousGame: ev.PreviousGame,
+		PreviousTags: ev.PreviousTags,
+	}
+}
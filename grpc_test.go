@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "telegram-monitor/proto"
+)
+
+func TestGRPCServer(t *testing.T) {
+	cfg := &Config{}
+	cfg.Twitch.Channel = "somechannel"
+	cfg.GRPC.Token = "secret"
+	feed := &EventFeed{}
+	s := newGRPCServer(cfg, &LiveState{}, feed, newMonitorControl())
+
+	lis := bufconn.Listen(1 << 16)
+	srv := s.newServer()
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := pb.NewMonitorClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.GetStatus(ctx, &pb.GetStatusRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("GetStatus without token: got %v, want Unauthenticated", err)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+	st, err := client.GetStatus(ctx, &pb.GetStatusRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.GetChannel() != "somechannel" || st.GetLive() || st.GetPaused() {
+		t.Fatalf("unexpected status %v", st)
+	}
+
+	resp, err := client.Pause(ctx, &pb.PauseRequest{Paused: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.GetPaused() || !s.control.Paused() {
+		t.Fatal("pause was not applied")
+	}
+
+	stream, err := client.StreamEvents(ctx, &pb.StreamEventsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &StreamInfo{Title: "Speedrun", Game: "Celeste", Viewers: 42, Tags: []string{"English"}}
+	go func() {
+		for ctx.Err() == nil {
+			feed.Handle(ctx, Event{Type: EventStreamStarted, Time: time.Unix(1700000000, 0), Channel: "somechannel", Info: info})
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+	ev, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.GetType() != string(EventStreamStarted) || ev.GetGame() != "Celeste" || ev.GetViewers() != 42 || ev.GetTime() != 1700000000 {
+		t.Fatalf("unexpected event %v", ev)
+	}
+}
+
+func TestGRPCTokenRequiredOffLoopback(t *testing.T) {
+	for listen, ok := range map[string]bool{
+		"127.0.0.1:9090": true,
+		"localhost:9090": true,
+		"[::1]:9090":     true,
+		":9090":          false,
+		"0.0.0.0:9090":   false,
+		"10.0.0.5:9090":  false,
+	} {
+		path := filepath.Join(t.TempDir(), "config.json")
+		data := `{"twitch": {"channel": "somechannel"}, "grpc": {"listen": "` + listen + `"}}`
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := loadConfig(path)
+		if ok && err != nil {
+			t.Errorf("%s: %v", listen, err)
+		}
+		if !ok && (err == nil || !strings.Contains(err.Error(), "grpc.token")) {
+			t.Errorf("%s: got %v, want a grpc.token error", listen, err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type HashtagConfig struct {
+	Style         string   `json:"style"`
+	Transliterate bool     `json:"transliterate"`
+	Max           int      `json:"max"`
+	Custom        []string `json:"custom"`
+}
+
+var hashtagStyles = []string{"camel", "underscore"}
+
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g",
+}
+
+func transliterate(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		latin, ok := cyrillicToLatin[unicode.ToLower(r)]
+		if !ok {
+			b.WriteRune(r)
+			continue
+		}
+		if unicode.IsUpper(r) && latin != "" {
+			first, size := utf8.DecodeRuneInString(latin)
+			latin = string(unicode.ToUpper(first)) + latin[size:]
+		}
+		b.WriteString(latin)
+	}
+	return b.String()
+}
+
+func slugifyTag(tag string, cfg HashtagConfig) string {
+	if cfg.Transliterate {
+		tag = transliterate(tag)
+	}
+	words := strings.FieldsFunc(tag, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	if len(words) > 1 && cfg.Style != "underscore" {
+		for i, w := range words {
+			first, size := utf8.DecodeRuneInString(w)
+			words[i] = string(unicode.ToUpper(first)) + w[size:]
+		}
+	}
+	sep := ""
+	if cfg.Style == "underscore" {
+		sep = "_"
+	}
+	slug := strings.Join(words, sep)
+	if strings.IndexFunc(slug, unicode.IsLetter) < 0 {
+		return ""
+	}
+	return slug
+}
+
+func hashtagKey(slug string) string {
+	return strings.ToLower(strings.ReplaceAll(slug, "_", ""))
+}
+
+func formatTags(tags []string, mf MessageFormat) string {
+	cfg := mf.Hashtags
+	custom := cfg
+	custom.Transliterate = false
+
+	var hashtags []string
+	seen := map[string]bool{}
+	add := func(slug string) {
+		if slug == "" || seen[hashtagKey(slug)] || (cfg.Max > 0 && len(hashtags) >= cfg.Max) {
+			return
+		}
+		seen[hashtagKey(slug)] = true
+		hashtags = append(hashtags, "#"+slug)
+	}
+	for _, tag := range cfg.Custom {
+		add(slugifyTag(strings.TrimLeft(tag, "#"), custom))
+	}
+	for _, tag := range tags {
+		add(slugifyTag(tag, cfg))
+	}
+	return strings.Join(hashtags, " ")
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const twitchWebClientID = "kimne78kx3ncx6brgo4mv6wki5h1ko"
+
+type StreamHealthConfig struct {
+	Enabled   bool `json:"enabled"`
+	MinHeight int  `json:"min_height"`
+	MinFPS    int  `json:"min_fps"`
+}
+
+type StreamQuality struct {
+	Width     int
+	Height    int
+	FPS       float64
+	Bandwidth int
+}
+
+func (q StreamQuality) String() string {
+	return fmt.Sprintf("%dx%d@%.0f (%d kbps)", q.Width, q.Height, q.FPS, q.Bandwidth/1000)
+}
+
+type HealthMonitor struct {
+	cfg      *Config
+	baseline StreamQuality
+	degraded bool
+}
+
+func newHealthMonitor(cfg *Config) *HealthMonitor {
+	return &HealthMonitor{cfg: cfg}
+}
+
+func (m *HealthMonitor) Handle(ctx context.Context, ev Event) {
+	switch ev.Type {
+	case EventStreamStarted:
+		m.baseline = StreamQuality{}
+		m.degraded = false
+		return
+	case EventStreamUpdated:
+	default:
+		return
+	}
+
+	q, err := getSourceQuality(ctx, ev.Channel)
+	if err != nil {
+		slog.Warn("failed to check stream quality", "error", err)
+		return
+	}
+	if m.baseline.Height == 0 {
+		m.baseline = q
+		slog.Info("stream quality", "quality", q.String())
+		return
+	}
+
+	minHeight, minFPS := m.baseline.Height, m.baseline.FPS
+	if h := m.cfg.StreamHealth.MinHeight; h > 0 {
+		minHeight = h
+	}
+	if f := m.cfg.StreamHealth.MinFPS; f > 0 {
+		minFPS = float64(f)
+	}
+	degraded := q.Height < minHeight || q.FPS < minFPS-1
+
+	switch {
+	case degraded && !m.degraded:
+		slog.Warn("stream quality dropped", "from", m.baseline.String(), "to", q.String())
+		notifyAdmin(m.cfg, fmt.Sprintf("⚠️ <b>%s</b>: stream quality dropped to %s (was %s)", escapeHTML(ev.Channel), q, m.baseline))
+	case !degraded && m.degraded:
+		slog.Info("stream quality recovered", "quality", q.String())
+		notifyAdmin(m.cfg, fmt.Sprintf("✅ <b>%s</b>: stream quality recovered to %s", escapeHTML(ev.Channel), q))
+	}
+	m.degraded = degraded
+}
+
+func getPlaybackToken(ctx context.Context, channel string) (value, signature string, err error) {
+	query := map[string]any{
+		"operationName": "PlaybackAccessToken",
+		"query": `query PlaybackAccessToken($login: String!) {
+  streamPlaybackAccessToken(channelName: $login, params: {platform: "web", playerBackend: "mediaplayer", playerType: "site"}) { value signature }
+}`,
+		"variables": map[string]string{"login": channel},
+	}
+	body, _ := json.Marshal(query)
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://gql.twitch.tv/gql", strings.NewReader(string(body)))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Client-ID", twitchWebClientID)
+
+	resp, err := twitchHTTP.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return "", "", fmt.Errorf("playback token error (%d): %s", resp.StatusCode, data)
+	}
+	var result struct {
+		Data struct {
+			Token *struct {
+				Value     string `json:"value"`
+				Signature string `json:"signature"`
+			} `json:"streamPlaybackAccessToken"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", "", err
+	}
+	if result.Data.Token == nil {
+		return "", "", fmt.Errorf("no playback token for %s", channel)
+	}
+	return result.Data.Token.Value, result.Data.Token.Signature, nil
+}
+
+func getSourceQuality(ctx context.Context, channel string) (StreamQuality, error) {
+	token, sig, err := getPlaybackToken(ctx, channel)
+	if err != nil {
+		return StreamQuality{}, err
+	}
+
+	q := url.Values{"token": {token}, "sig": {sig}, "allow_source": {"true"}}
+	playlistURL := fmt.Sprintf("https://usher.ttvnw.net/api/channel/hls/%s.m3u8?%s", strings.ToLower(channel), q.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", playlistURL, nil)
+	if err != nil {
+		return StreamQuality{}, err
+	}
+	resp, err := twitchHTTP.Do(req)
+	if err != nil {
+		return StreamQuality{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return StreamQuality{}, fmt.Errorf("playlist error (%d)", resp.StatusCode)
+	}
+	return parseSourceQuality(resp.Body)
+}
+
+func parseSourceQuality(r io.Reader) (StreamQuality, error) {
+	var best StreamQuality
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		attrs, ok := strings.CutPrefix(line, "#EXT-X-STREAM-INF:")
+		if !ok {
+			continue
+		}
+		var q StreamQuality
+		source := false
+		for _, attr := range splitM3U8Attrs(attrs) {
+			key, value, _ := strings.Cut(attr, "=")
+			value = strings.Trim(value, `"`)
+			switch key {
+			case "BANDWIDTH":
+				q.Bandwidth, _ = strconv.Atoi(value)
+			case "RESOLUTION":
+				w, h, _ := strings.Cut(value, "x")
+				q.Width, _ = strconv.Atoi(w)
+				q.Height, _ = strconv.Atoi(h)
+			case "FRAME-RATE":
+				q.FPS, _ = strconv.ParseFloat(value, 64)
+			case "VIDEO":
+				source = value == "chunked"
+			}
+		}
+		if source {
+			return q, nil
+		}
+		if q.Height > best.Height || (q.Height == best.Height && q.FPS > best.FPS) {
+			best = q
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return StreamQuality{}, err
+	}
+	if best.Height == 0 {
+		return StreamQuality{}, fmt.Errorf("no video variants in playlist")
+	}
+	return best, nil
+}
+
+func splitM3U8Attrs(s string) []string {
+	var attrs []string
+	start, quoted := 0, false
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			attrs = append(attrs, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(attrs, s[start:])
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+type ChatHighlightsConfig struct {
+	Enabled  bool     `json:"enabled"`
+	Users    []string `json:"users"`
+	Badges   []string `json:"badges"`
+	Pattern  string   `json:"pattern"`
+	OnlyLive bool     `json:"only_live"`
+}
+
+type ChatHighlights struct {
+	cfg     *Config
+	pattern *regexp.Regexp
+
+	mu       sync.Mutex
+	live     bool
+	threadID *int
+}
+
+func newChatHighlights(cfg *Config) (*ChatHighlights, error) {
+	h := &ChatHighlights{cfg: cfg, threadID: cfg.Telegram.ThreadID}
+	if p := cfg.ChatHighlights.Pattern; p != "" {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chat_highlights.pattern: %w", err)
+		}
+		h.pattern = re
+	}
+	return h, nil
+}
+
+func (h *ChatHighlights) Handle(ctx context.Context, ev Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch ev.Type {
+	case EventStreamStarted, EventStreamUpdated:
+		h.live = true
+		if ev.Session.ThreadID != nil {
+			h.threadID = ev.Session.ThreadID
+		}
+	case EventStreamEnded:
+		h.live = false
+		h.threadID = h.cfg.Telegram.ThreadID
+	}
+}
+
+func (h *ChatHighlights) OnMessage(msg ChatMessage) {
+	if !h.matches(msg) {
+		return
+	}
+	h.mu.Lock()
+	live, threadID := h.live, h.threadID
+	h.mu.Unlock()
+	if h.cfg.ChatHighlights.OnlyLive && !live {
+		return
+	}
+
+	text := fmt.Sprintf("💬 <b>%s</b>: %s", escapeHTML(msg.Name()), escapeHTML(msg.Text))
+	if err := sendTextMessage(h.cfg.Telegram.BotToken, *h.cfg.Telegram.ChatID, threadID, text); err != nil {
+		slog.Error("failed to forward chat message", "error", err)
+	}
+}
+
+func (h *ChatHighlights) matches(msg ChatMessage) bool {
+	c := h.cfg.ChatHighlights
+	if slices.ContainsFunc(c.Users, func(u string) bool { return strings.EqualFold(u, msg.User) }) {
+		return true
+	}
+	for _, b := range c.Badges {
+		if _, ok := msg.Badges[b]; ok {
+			return true
+		}
+	}
+	return h.pattern != nil && h.pattern.MatchString(msg.Text)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	starjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+const hookMaxSteps = 10_000_000
+
+type HookConfig struct {
+	Script  string `json:"script"`
+	Timeout int    `json:"timeout_seconds"`
+}
+
+type HookResult struct {
+	Caption  *string
+	Suppress bool
+	Buttons  [][]InlineButton
+}
+
+func runHook(ctx context.Context, cfg HookConfig, ev EventPayload, caption string) (HookResult, error) {
+	var result HookResult
+	thread := &starlark.Thread{
+		Name:  "hook",
+		Print: func(_ *starlark.Thread, msg string) { slog.Info("hook: " + msg) },
+	}
+	thread.SetMaxExecutionSteps(hookMaxSteps)
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.Timeout)*time.Second)
+	defer cancel()
+	stop := context.AfterFunc(ctx, func() { thread.Cancel(ctx.Err().Error()) })
+	defer stop()
+
+	predeclared := starlark.StringDict{
+		"suppress": starlark.NewBuiltin("suppress", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+				return nil, err
+			}
+			result.Suppress = true
+			return starlark.None, nil
+		}),
+		"add_button": starlark.NewBuiltin("add_button", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var text, url string
+			if err := starlark.UnpackArgs(b.Name(), args, kwargs, "text", &text, "url", &url); err != nil {
+				return nil, err
+			}
+			result.Buttons = append(result.Buttons, []InlineButton{{Text: text, URL: url}})
+			return starlark.None, nil
+		}),
+	}
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, cfg.Script, nil, predeclared)
+	if err != nil {
+		return result, hookError(err)
+	}
+	fn, ok := globals["on_event"].(starlark.Callable)
+	if !ok {
+		return result, errors.New("script does not define on_event(event, caption)")
+	}
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return result, err
+	}
+	event, err := starlark.Call(thread, starjson.Module.Members["decode"], starlark.Tuple{starlark.String(data)}, nil)
+	if err != nil {
+		return result, err
+	}
+	ret, err := starlark.Call(thread, fn, starlark.Tuple{event, starlark.String(caption)}, nil)
+	if err != nil {
+		return result, hookError(err)
+	}
+	switch v := ret.(type) {
+	case starlark.NoneType:
+	case starlark.String:
+		s := string(v)
+		result.Caption = &s
+	default:
+		return result, fmt.Errorf("on_event must return a string or None, got %s", ret.Type())
+	}
+	return result, nil
+}
+
+func checkHookScript(path string) error {
+	_, err := (&syntax.FileOptions{}).Parse(path, nil, 0)
+	return err
+}
+
+func hookError(err error) error {
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		return errors.New(evalErr.Backtrace())
+	}
+	return err
+}
+
+func (n *TelegramNotifier) applyHook(ctx context.Context, ev Event, caption string, keyboard [][]InlineButton) (string, [][]InlineButton, bool) {
+	if n.cfg.Hook.Script == "" {
+		return caption, keyboard, true
+	}
+	result, err := runHook(ctx, n.cfg.Hook, newEventPayload(ev), caption)
+	if err != nil {
+		slog.Warn("event hook failed, sending unchanged message", "event", ev.Type, "error", err)
+		return caption, keyboard, true
+	}
+	if result.Suppress {
+		slog.Info("notification suppressed by hook", "event", ev.Type)
+		return caption, keyboard, false
+	}
+	if result.Caption != nil {
+		caption = *result.Caption
+	}
+	return caption, append(keyboard, result.Buttons...), true
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeHookScript(t *testing.T, src string) HookConfig {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hook.star")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return HookConfig{Script: path, Timeout: 5}
+}
+
+func TestRunHook(t *testing.T) {
+	cfg := writeHookScript(t, `
+def on_event(event, caption):
+    if event["game"] == "Just Chatting":
+        suppress()
+        return None
+    add_button("Shop", "https://example.com/shop")
+    return caption + " #" + event["game"].replace(" ", "")
+`)
+
+	result, err := runHook(context.Background(), cfg, EventPayload{Type: EventStreamStarted, Game: "Dark Souls"}, "live")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Suppress || result.Caption == nil || *result.Caption != "live #DarkSouls" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if len(result.Buttons) != 1 || result.Buttons[0][0].URL != "https://example.com/shop" {
+		t.Fatalf("unexpected buttons %v", result.Buttons)
+	}
+
+	result, err = runHook(context.Background(), cfg, EventPayload{Type: EventStreamStarted, Game: "Just Chatting"}, "live")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Suppress || result.Caption != nil {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
+
+func TestRunHookErrors(t *testing.T) {
+	for name, src := range map[string]string{
+		"missing on_event": "x = 1\n",
+		"runtime error":    "def on_event(event, caption):\n    return event[\"missing\"]\n",
+		"endless loop":     "def on_event(event, caption):\n    for i in range(1000000000):\n        pass\n",
+		"wrong result":     "def on_event(event, caption):\n    return 42\n",
+		"no file access":   "load(\"os.star\", \"os\")\ndef on_event(event, caption):\n    return None\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := runHook(context.Background(), writeHookScript(t, src), EventPayload{}, "live"); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestCheckHookScript(t *testing.T) {
+	cfg := writeHookScript(t, "def on_event(event, caption)\n    return None\n")
+	if err := checkHookScript(cfg.Script); err == nil || !strings.Contains(err.Error(), "hook.star") {
+		t.Fatalf("expected a syntax error, got %v", err)
+	}
+}
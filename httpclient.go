@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type HTTPClientConfig struct {
+	Timeout int         `json:"timeout_seconds"`
+	Proxy   string      `json:"proxy"`
+	Retry   RetryConfig `json:"retry"`
+}
+
+type APIEndpoints struct {
+	Telegram   string `json:"telegram"`
+	Helix      string `json:"helix"`
+	TwitchAuth string `json:"twitch_auth"`
+	Previews   string `json:"previews"`
+}
+
+var (
+	telegramAPI   = "https://api.telegram.org"
+	helixAPI      = "https://api.twitch.tv/helix"
+	twitchAuthAPI = "https://id.twitch.tv"
+	previewsCDN   = "https://static-cdn.jtvnw.net"
+)
+
+func configureEndpoints(e APIEndpoints) {
+	for _, o := range []struct {
+		dst   *string
+		value string
+	}{
+		{&telegramAPI, e.Telegram},
+		{&helixAPI, e.Helix},
+		{&twitchAuthAPI, e.TwitchAuth},
+		{&previewsCDN, e.Previews},
+	} {
+		if o.value != "" {
+			*o.dst = strings.TrimSuffix(o.value, "/")
+		}
+	}
+}
+
+var (
+	twitchHTTP   = &http.Client{Timeout: 15 * time.Second}
+	telegramHTTP = &http.Client{Timeout: 15 * time.Second}
+	externalHTTP = &http.Client{Timeout: 15 * time.Second}
+	twitchRetry  = RetryConfig{InitialDelay: 1, MaxDelay: 60, Multiplier: 2, MaxAttempts: 1}
+)
+
+func configureHTTPClient(client *http.Client, c HTTPClientConfig) error {
+	client.Timeout = time.Duration(c.Timeout) * time.Second
+
+	if c.Proxy == "" {
+		return nil
+	}
+	proxyURL, err := url.Parse(c.Proxy)
+	if err != nil {
+		return fmt.Errorf("invalid proxy %q: %w", c.Proxy, err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	client.Transport = transport
+	return nil
+}
+
+func applyRetryDefaults(r *RetryConfig, base RetryConfig) {
+	if r.InitialDelay <= 0 {
+		r.InitialDelay = base.InitialDelay
+	}
+	if r.MaxDelay <= 0 {
+		r.MaxDelay = base.MaxDelay
+	}
+	if r.Multiplier < 1 {
+		r.Multiplier = base.Multiplier
+	}
+	if r.Jitter == 0 {
+		r.Jitter = base.Jitter
+	}
+	if r.MaxAttempts == 0 {
+		r.MaxAttempts = base.MaxAttempts
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type CalendarFeed struct {
+	cfg           *Config
+	archive       *SessionArchive
+	mu            sync.Mutex
+	broadcasterID string
+}
+
+func newCalendarFeed(cfg *Config, archive *SessionArchive) *CalendarFeed {
+	return &CalendarFeed{cfg: cfg, archive: archive}
+}
+
+func (f *CalendarFeed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cfg := f.cfg
+	channel := cfg.Twitch.Channel
+	streamURL := fmt.Sprintf("https://twitch.tv/%s", channel)
+
+	var b strings.Builder
+	writeICalLine(&b, "BEGIN:VCALENDAR")
+	writeICalLine(&b, "VERSION:2.0")
+	writeICalLine(&b, "PRODID:-//twitch2tg-bot//calendar//EN")
+	writeICalLine(&b, "CALSCALE:GREGORIAN")
+	writeICalLine(&b, "X-WR-CALNAME:"+icalEscape(channel))
+	now := time.Now()
+
+	records, err := f.archive.Load(ctx)
+	if err != nil {
+		slog.Warn("failed to load sessions for calendar", "error", err)
+	}
+	for _, rec := range records {
+		desc := fmt.Sprintf("%s\n%d avg, %d peak", rec.Game, rec.AvgViewers, rec.PeakViewers)
+		writeICalEvent(&b, fmt.Sprintf("session-%d@%s", rec.StartTime.Unix(), channel), now, rec.StartTime, rec.EndTime, rec.Title, desc, streamURL)
+	}
+
+	f.mu.Lock()
+	if f.broadcasterID == "" {
+		f.broadcasterID, err = getBroadcasterID(ctx, channel, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+		if err != nil {
+			slog.Warn("failed to get broadcaster ID for calendar", "error", err)
+		}
+	}
+	broadcasterID := f.broadcasterID
+	f.mu.Unlock()
+	if broadcasterID != "" {
+		segments, err := getSchedule(ctx, broadcasterID, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+		if err != nil {
+			slog.Warn("failed to get schedule for calendar", "error", err)
+		}
+		for _, seg := range segments {
+			if seg.CanceledUntil != nil {
+				continue
+			}
+			end := seg.StartTime.Add(2 * time.Hour)
+			if seg.EndTime != nil {
+				end = *seg.EndTime
+			}
+			var game string
+			if seg.Category != nil {
+				game = seg.Category.Name
+			}
+			writeICalEvent(&b, "schedule-"+seg.ID+"@"+channel, now, seg.StartTime, end, seg.Title, game, streamURL)
+		}
+	}
+	writeICalLine(&b, "END:VCALENDAR")
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+func writeICalEvent(b *strings.Builder, uid string, stamp, start, end time.Time, summary, description, url string) {
+	const layout = "20060102T150405Z"
+	writeICalLine(b, "BEGIN:VEVENT")
+	writeICalLine(b, "UID:"+uid)
+	writeICalLine(b, "DTSTAMP:"+stamp.UTC().Format(layout))
+	writeICalLine(b, "DTSTART:"+start.UTC().Format(layout))
+	writeICalLine(b, "DTEND:"+end.UTC().Format(layout))
+	writeICalLine(b, "SUMMARY:"+icalEscape(summary))
+	if description != "" {
+		writeICalLine(b, "DESCRIPTION:"+icalEscape(description))
+	}
+	writeICalLine(b, "URL:"+url)
+	writeICalLine(b, "END:VEVENT")
+}
+
+func icalEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+func writeICalLine(b *strings.Builder, line string) {
+	for len(line) > 75 {
+		cut := 75
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+	}
+	b.WriteString(line + "\r\n")
+}
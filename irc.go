@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+type ChatMessage struct {
+	Time        time.Time
+	User        string
+	DisplayName string
+	Text        string
+	Badges      map[string]string
+}
+
+func (m ChatMessage) Name() string {
+	if m.DisplayName != "" {
+		return m.DisplayName
+	}
+	return m.User
+}
+
+type ChatMessageHandler func(msg ChatMessage)
+
+type TwitchChat struct {
+	channel string
+
+	mu       sync.Mutex
+	handlers []ChatMessageHandler
+}
+
+func newTwitchChat(channel string) *TwitchChat {
+	return &TwitchChat{channel: strings.ToLower(channel)}
+}
+
+func (c *TwitchChat) Subscribe(h ChatMessageHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers = append(c.handlers, h)
+}
+
+func (c *TwitchChat) Run(ctx context.Context) {
+	delay := time.Second
+	for ctx.Err() == nil {
+		start := time.Now()
+		err := c.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > time.Minute {
+			delay = time.Second
+		}
+		slog.Warn("twitch chat disconnected, reconnecting", "error", err, "in", delay)
+		sleep(ctx, delay)
+		delay = min(delay*2, time.Minute)
+	}
+}
+
+func (c *TwitchChat) session(ctx context.Context) error {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", "irc.chat.twitch.tv:6697", &tls.Config{ServerName: "irc.chat.twitch.tv"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	nick := fmt.Sprintf("justinfan%d", 10000+rand.IntN(89999))
+	fmt.Fprintf(conn, "CAP REQ :twitch.tv/tags twitch.tv/commands\r\nPASS SCHMOOZE\r\nNICK %s\r\nJOIN #%s\r\n", nick, c.channel)
+	slog.Info("connected to twitch chat", "channel", c.channel)
+
+	r := bufio.NewReader(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(6 * time.Minute))
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if strings.HasPrefix(line, "PING") {
+			fmt.Fprintf(conn, "PONG%s\r\n", strings.TrimPrefix(line, "PING"))
+			continue
+		}
+		if msg, ok := parsePrivmsg(line); ok {
+			c.mu.Lock()
+			handlers := c.handlers
+			c.mu.Unlock()
+			for _, h := range handlers {
+				h(msg)
+			}
+		} else if strings.Contains(line, " RECONNECT") {
+			return fmt.Errorf("server requested reconnect")
+		}
+	}
+}
+
+func parsePrivmsg(line string) (ChatMessage, bool) {
+	var tags string
+	if strings.HasPrefix(line, "@") {
+		var ok bool
+		tags, line, ok = strings.Cut(line[1:], " ")
+		if !ok {
+			return ChatMessage{}, false
+		}
+	}
+	prefix, rest, ok := strings.Cut(line, " ")
+	if !ok || !strings.HasPrefix(rest, "PRIVMSG ") {
+		return ChatMessage{}, false
+	}
+	_, text, ok := strings.Cut(rest, " :")
+	if !ok {
+		return ChatMessage{}, false
+	}
+	user, _, _ := strings.Cut(strings.TrimPrefix(prefix, ":"), "!")
+
+	msg := ChatMessage{Time: time.Now(), User: user, Text: text, Badges: map[string]string{}}
+	for _, tag := range strings.Split(tags, ";") {
+		key, value, _ := strings.Cut(tag, "=")
+		switch key {
+		case "display-name":
+			msg.DisplayName = unescapeTag(value)
+		case "badges":
+			for _, badge := range strings.Split(value, ",") {
+				if name, version, ok := strings.Cut(badge, "/"); ok {
+					msg.Badges[name] = version
+				}
+			}
+		}
+	}
+	if strings.HasPrefix(text, "\x01ACTION ") {
+		msg.Text = strings.TrimSuffix(strings.TrimPrefix(text, "\x01ACTION "), "\x01")
+	}
+	return msg, true
+}
+
+func unescapeTag(v string) string {
+	return strings.NewReplacer(`\s`, " ", `\:`, ";", `\\`, `\`, `\r`, "\r", `\n`, "\n").Replace(v)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	keyringService = "twitch2tg-bot"
+	keyringPrefix  = "keyring:"
+)
+
+func moveSecretsToKeyring(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	moved := 0
+	providers := secretProviders(&cfg)
+	for _, f := range secretFields(&cfg) {
+		scheme, _, isRef := strings.Cut(*f.value, ":")
+		if _, known := providers[scheme]; *f.value == "" || isRef && known {
+			continue
+		}
+		if err := keyringSet(f.path, *f.value); err != nil {
+			return fmt.Errorf("failed to store %s in the OS keyring: %w", f.path, err)
+		}
+		*f.value = keyringPrefix + f.path
+		fmt.Printf("%s moved to the OS keyring\n", f.path)
+		moved++
+	}
+	if moved == 0 {
+		fmt.Println("No plaintext secrets found in config")
+		return nil
+	}
+	return saveConfig(path, &cfg)
+}
+
+func keyringGet(name string) (string, error) {
+	secret, err := keyring.Get(keyringService, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", fmt.Errorf("secret %s not found", name)
+	}
+	return secret, err
+}
+
+func keyringSet(name, secret string) error {
+	return keyring.Set(keyringService, name, secret)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestMoveSecretsToKeyring(t *testing.T) {
+	keyring.MockInit()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"twitch": {"channel": "somechannel", "client_secret": "shh"}, "telegram": {"bot_token": "123:abc"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := moveSecretsToKeyring(path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "shh") || strings.Contains(string(data), "123:abc") {
+		t.Fatalf("secrets left in config: %s", data)
+	}
+	if secret, err := keyringGet("twitch.client_secret"); err != nil || secret != "shh" {
+		t.Fatalf("keyringGet() = %q, %v", secret, err)
+	}
+	if _, err := keyringGet("redis.password"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+}
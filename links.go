@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+type LinkTrackingConfig struct {
+	Params   map[string]string `json:"params"`
+	Redirect string            `json:"redirect"`
+}
+
+func trackedURL(cfg LinkTrackingConfig, raw string) string {
+	if len(cfg.Params) > 0 {
+		if u, err := url.Parse(raw); err == nil {
+			q := u.Query()
+			for k, v := range cfg.Params {
+				q.Set(k, v)
+			}
+			u.RawQuery = q.Encode()
+			raw = u.String()
+		}
+	}
+	if cfg.Redirect != "" {
+		raw = strings.ReplaceAll(cfg.Redirect, "{url}", url.QueryEscape(raw))
+	}
+	return raw
+}
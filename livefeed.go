@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	liveFeedState      EventType = "state"
+	telegramMembersTTL           = 5 * time.Minute
+)
+
+type LiveFeedMessage struct {
+	EventPayload
+	Channels        []string `json:"channels,omitempty"`
+	Live            bool     `json:"live"`
+	AvgViewers      int      `json:"avg_viewers,omitempty"`
+	PeakViewers     int      `json:"peak_viewers,omitempty"`
+	TelegramMembers int      `json:"telegram_members,omitempty"`
+}
+
+type LiveFeedServer struct {
+	cfg      *Config
+	channels []string
+	lives    map[string]*LiveState
+	feed     *EventFeed
+
+	mu         sync.Mutex
+	members    int
+	membersAt  time.Time
+	refreshing bool
+}
+
+func newLiveFeedServer(cfg *Config, lives map[string]*LiveState, feed *EventFeed) *LiveFeedServer {
+	return &LiveFeedServer{cfg: cfg, channels: monitoredChannels(cfg), lives: lives, feed: feed}
+}
+
+func (s *LiveFeedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !tokenAuthorized(r, s.cfg.Server.Token) {
+		writeAPIError(w, http.StatusUnauthorized, errors.New("invalid token"))
+		return
+	}
+	events, unsubscribe := s.feed.Subscribe()
+	defer unsubscribe()
+	conn, err := acceptWebSocket(w, r)
+	if err != nil {
+		slog.Warn("live feed connection rejected", "remote", r.RemoteAddr, "error", err)
+		return
+	}
+	defer conn.Close()
+	slog.Info("live feed client connected", "remote", r.RemoteAddr)
+
+	for _, ch := range s.channels {
+		state := EventPayload{Type: liveFeedState, Time: clock.Now(), Channel: ch}
+		if live := s.lives[ch]; live != nil {
+			if snap, ok := live.Snapshot(); ok {
+				state = snapshotPayload(ch, snap)
+				state.Type = liveFeedState
+			}
+		}
+		m := s.message(state)
+		m.Channels = s.channels
+		if err := writeBusMessage(conn, m); err != nil {
+			return
+		}
+	}
+	pumpEvents(conn, events, r.RemoteAddr, func(ev EventPayload) (any, bool) {
+		return s.message(ev), true
+	})
+}
+
+func (s *LiveFeedServer) message(p EventPayload) LiveFeedMessage {
+	m := LiveFeedMessage{EventPayload: p, TelegramMembers: s.telegramMembers()}
+	live := s.lives[strings.ToLower(p.Channel)]
+	if live == nil || p.Type == EventStreamEnded {
+		return m
+	}
+	if snap, ok := live.Snapshot(); ok {
+		m.Live = true
+		m.AvgViewers = snap.AvgViewers()
+		m.PeakViewers = snap.PeakViewers()
+	}
+	return m
+}
+
+func (s *LiveFeedServer) telegramMembers() int {
+	cfg := s.cfg
+	if cfg.Telegram.ChatID == nil {
+		return 0
+	}
+	s.mu.Lock()
+	if s.refreshing || clock.Now().Sub(s.membersAt) < telegramMembersTTL {
+		defer s.mu.Unlock()
+		return s.members
+	}
+	s.refreshing = true
+	s.mu.Unlock()
+
+	count, err := getChatMemberCount(cfg.Telegram.BotToken, *cfg.Telegram.ChatID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshing = false
+	s.membersAt = clock.Now()
+	if err != nil {
+		slog.Warn("failed to get telegram member count", "error", err)
+		return s.members
+	}
+	s.members = count
+	return count
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLiveFeedTokenAndChannels(t *testing.T) {
+	cfg := loadTestConfig(t, `{
+		"twitch": {"channel": "somechannel"},
+		"telegram": {"bot_token": "123:abc"},
+		"channels": [{"channel": "Other", "chat_id": -200}],
+		"server": {"token": "secret"}
+	}`)
+	primary, other := &LiveState{}, &LiveState{}
+	info := &StreamInfo{Channel: "other", Title: "Other title", Viewers: 12}
+	other.Handle(context.Background(), Event{Type: EventStreamStarted, Channel: "other", Info: info, Session: &StreamSession{}})
+	srv := httptest.NewServer(newLiveFeedServer(cfg, map[string]*LiveState{"somechannel": primary, "other": other}, &EventFeed{}))
+	defer srv.Close()
+	endpoint := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	for _, query := range []string{"", "?token=wrong"} {
+		if conn, err := dialWebSocket(endpoint+query, nil, time.Second); err == nil {
+			conn.Close()
+			t.Fatalf("connected with %q", query)
+		}
+	}
+
+	conn, err := dialWebSocket(endpoint+"?token=secret", nil, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var states []LiveFeedMessage
+	for range 2 {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var m LiveFeedMessage
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Fatal(err)
+		}
+		states = append(states, m)
+	}
+	if states[0].Channel != "other" || !states[0].Live || states[0].Title != "Other title" {
+		t.Fatalf("unexpected state for other: %+v", states[0])
+	}
+	if states[1].Channel != "somechannel" || states[1].Live || states[1].Type != liveFeedState {
+		t.Fatalf("unexpected state for somechannel: %+v", states[1])
+	}
+	if got := strings.Join(states[1].Channels, ","); got != "other,somechannel" {
+		t.Fatalf("channels = %q", got)
+	}
+}
+
+func TestLiveFeedMembersFetchedOutsideLock(t *testing.T) {
+	c := useManualClock(t, time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC))
+	var requests atomic.Int32
+	arrived, release := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			close(arrived)
+			<-release
+		}
+		fmt.Fprint(w, `{"ok":true,"result":42}`)
+	}))
+	defer srv.Close()
+	defer func(old string) { telegramAPI = old }(telegramAPI)
+	telegramAPI = srv.URL
+
+	chatID := int64(-100500)
+	cfg := &Config{}
+	cfg.Telegram.BotToken, cfg.Telegram.ChatID = "123:abc", &chatID
+	s := newLiveFeedServer(cfg, map[string]*LiveState{}, &EventFeed{})
+
+	first := make(chan int)
+	go func() { first <- s.telegramMembers() }()
+	<-arrived
+	done := make(chan int)
+	go func() { done <- s.telegramMembers() }()
+	select {
+	case n := <-done:
+		if n != 0 {
+			t.Fatalf("members during refresh = %d", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("telegramMembers blocked on an in-flight refresh")
+	}
+	close(release)
+	if n := <-first; n != 42 {
+		t.Fatalf("members = %d", n)
+	}
+
+	s.telegramMembers()
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("cached count refetched, %d requests", n)
+	}
+	c.Advance(telegramMembersTTL)
+	s.telegramMembers()
+	if n := requests.Load(); n != 2 {
+		t.Fatalf("stale count not refreshed, %d requests", n)
+	}
+}
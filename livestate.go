@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+type LiveSnapshot struct {
+	Info          StreamInfo
+	MessageID     int
+	StartTime     time.Time
+	BroadcasterID string
+	ViewerHistory []ViewerDataPoint
+	Marks         []StreamMark
+}
+
+func (s LiveSnapshot) AvgViewers() int {
+	return calculateAverage(s.ViewerHistory)
+}
+
+func (s LiveSnapshot) PeakViewers() int {
+	peak := 0
+	for _, p := range s.ViewerHistory {
+		peak = max(peak, p.Count)
+	}
+	return peak
+}
+
+type LiveState struct {
+	mu   sync.RWMutex
+	live *LiveSnapshot
+}
+
+func (s *LiveState) Handle(ctx context.Context, ev Event) {
+	switch ev.Type {
+	case EventStreamStarted, EventStreamUpdated:
+		snap := &LiveSnapshot{
+			Info:          *ev.Info,
+			MessageID:     ev.Session.MessageID,
+			StartTime:     ev.Session.StartTime,
+			BroadcasterID: ev.Session.BroadcasterID,
+			ViewerHistory: slices.Clone(ev.Session.ViewerHistory),
+			Marks:         ev.Session.Marks,
+		}
+		snap.Info.Tags = slices.Clone(ev.Info.Tags)
+		s.mu.Lock()
+		s.live = snap
+		s.mu.Unlock()
+	case EventStreamEnded:
+		s.mu.Lock()
+		s.live = nil
+		s.mu.Unlock()
+	}
+}
+
+func (s *LiveState) Snapshot() (LiveSnapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.live == nil {
+		return LiveSnapshot{}, false
+	}
+	return *s.live, true
+}
+
+type EventFeed struct {
+	mu   sync.Mutex
+	subs map[chan EventPayload]struct{}
+}
+
+func (f *EventFeed) Handle(ctx context.Context, ev Event) {
+	f.Publish(newEventPayload(ev))
+}
+
+func (f *EventFeed) Publish(payload EventPayload) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subs {
+		select {
+		case ch <- payload:
+		default:
+		}
+	}
+}
+
+func (f *EventFeed) Subscribe() (<-chan EventPayload, func()) {
+	ch := make(chan EventPayload, 16)
+	f.mu.Lock()
+	if f.subs == nil {
+		f.subs = make(map[chan EventPayload]struct{})
+	}
+	f.subs[ch] = struct{}{}
+	f.mu.Unlock()
+	return ch, func() {
+		f.mu.Lock()
+		delete(f.subs, ch)
+		f.mu.Unlock()
+	}
+}
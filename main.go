@@ -3,44 +3,195 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 )
 
 type Config struct {
+	secretRefs map[string]string
+
 	Twitch struct {
-		Channel      string `json:"channel"`
-		ClientID     string `json:"client_id"`
-		ClientSecret string `json:"client_secret"`
+		Channel      string             `json:"channel"`
+		ClientID     string             `json:"client_id"`
+		ClientSecret string             `json:"client_secret"`
+		SecretFile   string             `json:"client_secret_file"`
+		Credentials  []TwitchCredential `json:"extra_credentials"`
+		UserToken    string             `json:"user_token"`
+		RefreshToken string             `json:"refresh_token"`
 	} `json:"twitch"`
 	Telegram struct {
-		BotToken string `json:"bot_token"`
-		ChatID   *int64 `json:"chat_id"`
-		ThreadID *int   `json:"thread_id"`
+		BotToken       string              `json:"bot_token"`
+		BotTokenFile   string              `json:"bot_token_file"`
+		BackupBotToken string              `json:"backup_bot_token"`
+		ChatID         *int64              `json:"chat_id"`
+		ThreadID       *int                `json:"thread_id"`
+		AdminChatID    *int64              `json:"admin_chat_id"`
+		Admins         []int64             `json:"admins"`
+		Approval       bool                `json:"approval"`
+		Spoiler        bool                `json:"spoiler"`
+		ProtectContent bool                `json:"protect_content"`
+		CommentStats   bool                `json:"comment_stats"`
+		TrackReactions bool                `json:"track_reactions"`
+		ClipFilter     ClipFilterConfig    `json:"clip_filter"`
+		PaidClip       PaidClipConfig      `json:"paid_clip"`
+		Story          StoryConfig         `json:"story"`
+		Milestones     []MilestoneReaction `json:"milestones"`
+		Style          string              `json:"style"`
+		Footer         FooterConfig        `json:"footer"`
+		ForumTopics    struct {
+			Enabled      bool   `json:"enabled"`
+			CloseOnEnd   bool   `json:"close_on_end"`
+			StatusPrefix bool   `json:"status_prefix"`
+			LiveIcon     string `json:"live_icon"`
+			OfflineIcon  string `json:"offline_icon"`
+		} `json:"forum_topics"`
 	} `json:"telegram"`
-	Language       string `json:"language"`
-	CheckInterval  int    `json:"check_interval_seconds"`
-	UpdateInterval int    `json:"update_interval_minutes"`
-	SetupCompleted bool   `json:"setup_completed"`
+	Language                string      `json:"language"`
+	CommandLanguage         string      `json:"command_language"`
+	CheckInterval           int         `json:"check_interval_seconds"`
+	UpdateInterval          int         `json:"update_interval_minutes"`
+	SampleInterval          int         `json:"viewer_sample_interval_seconds"`
+	AnnounceDelay           int         `json:"announce_delay_seconds"`
+	ThumbnailUpdateInterval int         `json:"thumbnail_update_interval_minutes"`
+	Retry                   RetryConfig `json:"retry"`
+	HTTP                    struct {
+		Twitch    HTTPClientConfig `json:"twitch"`
+		Telegram  HTTPClientConfig `json:"telegram"`
+		Endpoints APIEndpoints     `json:"endpoints"`
+	} `json:"http"`
+	Teaser       TeaserConfig       `json:"teaser"`
+	Goals        []GoalConfig       `json:"goals"`
+	Steam        SteamConfig        `json:"steam"`
+	Multistream  MultistreamConfig  `json:"multistream"`
+	Squad        SquadConfig        `json:"squad"`
+	AutoClip     AutoClipConfig     `json:"auto_clip"`
+	LinkTracking LinkTrackingConfig `json:"link_tracking"`
+	Shortener    ShortenerConfig    `json:"shortener"`
+	Alerts       []AlertConfig      `json:"alerts"`
+	StreamHealth StreamHealthConfig `json:"stream_health"`
+	Screenshots  ScreenshotConfig   `json:"screenshots"`
+	Storage      StorageConfig      `json:"storage"`
+	ChatModes    ChatModesConfig    `json:"chat_modes"`
+	Moderation   struct {
+		Enabled bool `json:"enabled"`
+	} `json:"moderation"`
+	ChatBridge struct {
+		Say bool `json:"say"`
+	} `json:"chat_bridge"`
+	ChatHighlights ChatHighlightsConfig `json:"chat_highlights"`
+	AutoHighlights AutoHighlightsConfig `json:"auto_highlights"`
+	Baseline       BaselineConfig       `json:"baseline"`
+	FollowerGoal   FollowerGoalConfig   `json:"follower_goal"`
+	Quiet          QuietConfig          `json:"quiet"`
+	Server         ServerConfig         `json:"server"`
+	Serve          ServeConfig          `json:"serve"`
+	Upstream       UpstreamConfig       `json:"upstream"`
+	Redis          RedisConfig          `json:"redis"`
+	GRPC           GRPCConfig           `json:"grpc"`
+	Hook           HookConfig           `json:"hook"`
+	Plugins        PluginConfig         `json:"plugins"`
+	Vault          VaultConfig          `json:"vault"`
+	Channels       []ChannelTarget      `json:"channels"`
+	Commands       struct {
+		Enabled bool `json:"enabled"`
+	} `json:"commands"`
+	StateFile   string `json:"state_file"`
+	HistoryFile string `json:"history_file"`
+	Tracing     struct {
+		Enabled     bool              `json:"enabled"`
+		Endpoint    string            `json:"endpoint"`
+		ServiceName string            `json:"service_name"`
+		Headers     map[string]string `json:"headers"`
+	} `json:"tracing"`
+	CategoryEmoji struct {
+		Enabled bool              `json:"enabled"`
+		Map     map[string]string `json:"map"`
+	} `json:"category_emoji"`
+	ShowHourlyGrowth bool          `json:"show_hourly_growth"`
+	Layout           []string      `json:"layout"`
+	Hashtags         HashtagConfig `json:"hashtags"`
+	Mature           struct {
+		Enabled bool   `json:"enabled"`
+		Badge   string `json:"badge"`
+		Spoiler bool   `json:"spoiler"`
+	} `json:"mature"`
+	SetupCompleted bool `json:"setup_completed"`
+}
+
+type TeaserConfig struct {
+	Enabled       bool   `json:"enabled"`
+	MinutesBefore int    `json:"minutes_before"`
+	Source        string `json:"source"`
+	Cron          string `json:"cron"`
+	OnStart       string `json:"on_start"`
+}
+
+type RetryConfig struct {
+	InitialDelay int     `json:"initial_delay_seconds"`
+	MaxDelay     int     `json:"max_delay_seconds"`
+	Multiplier   float64 `json:"multiplier"`
+	Jitter       float64 `json:"jitter"`
+	MaxAttempts  int     `json:"max_attempts"`
 }
 
 type Localization struct {
 	StartedStreaming string
 	IsLive           string
+	OnBreak          string
 	StreamEnded      string
+	StartingSoon     string
+	StartsAt         string
+	StartsIn         string
 	ButtonText       string
 	Peak             string
+	PeakAt           string
 	Viewers          string
 	Avg              string
 	Clips            string
 	Growing          string
 	Steady           string
 	Dropping         string
+	LastHour         string
+	Retention        string
+	CoStreamWith     string
+	Squad            string
+	ClipThanks       string
+	ViewerSpike      string
+	Titles           string
+	Marks            string
+	Highlights       string
+	HoursWatched     string
+	Quiet            string
+	NoStreamsFor     string
+	Upcoming         string
+	BestClip         string
+	FollowersTo      string
+	UsualAudience    string
+	Weekdays         [7]string
+	PerMinute        string
+	ShieldMode       string
+	EmoteOnly        string
+	SubOnly          string
+	FollowersOnly    string
+	SlowMode         string
+	UniqueChat       string
+	Giveaway         string
+	GiveawayJoin     string
+	Participants     string
+	Winner           string
+	GiveawayEnded    string
+	GiveawayJoined   string
+	AlreadyJoined    string
+	GiveawayCanceled string
 }
 
 type ViewerDataPoint struct {
@@ -48,6 +199,17 @@ type ViewerDataPoint struct {
 	Count     int       `json:"count"`
 }
 
+type TitleChange struct {
+	Timestamp time.Time `json:"timestamp"`
+	Title     string    `json:"title"`
+	Viewers   int       `json:"viewers"`
+}
+
+type TagChange struct {
+	Timestamp time.Time `json:"timestamp"`
+	Tags      []string  `json:"tags"`
+}
+
 type StreamSession struct {
 	MessageID     int
 	StartTime     time.Time
@@ -56,45 +218,292 @@ type StreamSession struct {
 	Tags          []string
 	BroadcasterID string
 	ViewerHistory []ViewerDataPoint
-	UpdateCounter int
+	TitleHistory  []TitleChange
+	TagHistory    []TagChange
+	ThreadID      *int
+	Bot           int
+	Marks         []StreamMark
+	Highlights    []Highlight
+	Baseline      int
 }
 
 func loadConfig(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("config file not found: %s", path)
+		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
 
+	checker := checkConfigJSON(data)
+	if len(checker.issues) > 0 {
+		return nil, &ConfigErrors{File: path, Issues: checker.issues}
+	}
 	var cfg Config
 	if err := json.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
-	if e := os.Getenv("TWITCH_CLIENT_ID"); e != "" {
-		cfg.Twitch.ClientID = e
+	if err := resolveSecretRefs(&cfg); err != nil {
+		return nil, err
 	}
-	if e := os.Getenv("TWITCH_CLIENT_SECRET"); e != "" {
-		cfg.Twitch.ClientSecret = e
+	secrets := []struct {
+		env  string
+		file string
+		dst  *string
+	}{
+		{"TWITCH_CLIENT_ID", "", &cfg.Twitch.ClientID},
+		{"TWITCH_CLIENT_SECRET", cfg.Twitch.SecretFile, &cfg.Twitch.ClientSecret},
+		{"TELEGRAM_BOT_TOKEN", cfg.Telegram.BotTokenFile, &cfg.Telegram.BotToken},
+		{"TELEGRAM_BACKUP_BOT_TOKEN", "", &cfg.Telegram.BackupBotToken},
 	}
-	if e := os.Getenv("TELEGRAM_BOT_TOKEN"); e != "" {
-		cfg.Telegram.BotToken = e
+	for _, s := range secrets {
+		file := s.file
+		if e := os.Getenv(s.env + "_FILE"); e != "" {
+			file = e
+		}
+		if file != "" {
+			secret, err := readSecretFile(file)
+			if err != nil {
+				return nil, err
+			}
+			*s.dst = secret
+		}
+		if e := os.Getenv(s.env); e != "" {
+			*s.dst = e
+		}
 	}
 
 	if cfg.UpdateInterval == 0 {
 		cfg.UpdateInterval = 5
 	}
+	if cfg.ThumbnailUpdateInterval == 0 {
+		cfg.ThumbnailUpdateInterval = 15
+	}
 	if cfg.CheckInterval == 0 {
 		cfg.CheckInterval = 60
 	}
+	if cfg.SampleInterval == 0 {
+		cfg.SampleInterval = cfg.CheckInterval
+	}
 	if cfg.Language == "" {
 		cfg.Language = "ru"
 	}
+	if cfg.CommandLanguage == "" {
+		cfg.CommandLanguage = cfg.Language
+	}
+	if cfg.StateFile == "" {
+		cfg.StateFile = "state.json"
+	}
+	if cfg.HistoryFile == "" {
+		cfg.HistoryFile = "sessions.jsonl"
+	}
+	if cfg.Tracing.Endpoint == "" {
+		cfg.Tracing.Endpoint = "http://localhost:4318"
+	}
+	if cfg.Tracing.ServiceName == "" {
+		cfg.Tracing.ServiceName = "twitch2tg-bot"
+	}
+	if cfg.Hook.Timeout == 0 {
+		cfg.Hook.Timeout = 5
+	}
+	if cfg.Hook.Script != "" {
+		if err := checkHookScript(cfg.Hook.Script); err != nil {
+			return nil, fmt.Errorf("invalid hook.script: %w", err)
+		}
+	}
+	if cfg.Plugins.Timeout == 0 {
+		cfg.Plugins.Timeout = 10
+	}
+	if cfg.Redis.Addr == "" {
+		cfg.Redis.Addr = "localhost:6379"
+	}
+	if cfg.Redis.Channel == "" {
+		cfg.Redis.Channel = "twitch2tg:events"
+	}
+	if cfg.Multistream.URL == "" {
+		cfg.Multistream.URL = "https://www.multitwitch.tv/{channels}"
+	}
+	if cfg.Screenshots.Dir == "" {
+		cfg.Screenshots.Dir = "screenshots"
+	}
+	if cfg.Screenshots.Interval == 0 {
+		cfg.Screenshots.Interval = cfg.UpdateInterval
+	}
+	if cfg.Screenshots.Timelapse.FPS == 0 {
+		cfg.Screenshots.Timelapse.FPS = 5
+	}
+	if cfg.Screenshots.Timelapse.Width == 0 {
+		cfg.Screenshots.Timelapse.Width = 480
+	}
+	if cfg.Screenshots.Timelapse.MaxFrames == 0 {
+		cfg.Screenshots.Timelapse.MaxFrames = 150
+	}
+	if cfg.Telegram.PaidClip.Stars == 0 {
+		cfg.Telegram.PaidClip.Stars = 10
+	}
+	if cfg.GRPC.Listen != "" && cfg.GRPC.Token == "" && !loopbackAddr(cfg.GRPC.Listen) {
+		return nil, fmt.Errorf("grpc.token is required when grpc.listen is not a loopback address")
+	}
+	if err := validateFooter(cfg.Telegram.Footer); err != nil {
+		return nil, err
+	}
+	if cfg.Hashtags.Style == "" {
+		cfg.Hashtags.Style = "camel"
+	}
+	if !slices.Contains(hashtagStyles, cfg.Hashtags.Style) {
+		return nil, fmt.Errorf("unknown hashtags.style %q (available: %s)", cfg.Hashtags.Style, strings.Join(hashtagStyles, ", "))
+	}
+	if cfg.Telegram.Story.ActiveHours == 0 {
+		cfg.Telegram.Story.ActiveHours = 24
+	}
+	if !slices.Contains([]int{6, 12, 24, 48}, cfg.Telegram.Story.ActiveHours) {
+		return nil, fmt.Errorf("telegram.story.active_hours must be 6, 12, 24 or 48")
+	}
+	for i, m := range cfg.Telegram.Milestones {
+		if m.Viewers <= 0 || m.Emoji == "" {
+			return nil, fmt.Errorf("telegram.milestones[%d]: viewers and emoji are required", i)
+		}
+	}
+	if cfg.Telegram.Story.Enabled && cfg.Telegram.Story.BusinessConnectionID == "" {
+		return nil, fmt.Errorf("telegram.story requires business_connection_id")
+	}
+	if cfg.AutoHighlights.ChatFactor == 0 {
+		cfg.AutoHighlights.ChatFactor = 3
+	}
+	if cfg.AutoHighlights.MinMessages == 0 {
+		cfg.AutoHighlights.MinMessages = 20
+	}
+	if cfg.AutoHighlights.ViewerPercent == 0 {
+		cfg.AutoHighlights.ViewerPercent = 10
+	}
+	if cfg.AutoHighlights.Max == 0 {
+		cfg.AutoHighlights.Max = 5
+	}
+	for i, t := range cfg.FollowerGoal.Targets {
+		if t <= 0 {
+			return nil, fmt.Errorf("follower_goal.targets[%d] must be positive", i)
+		}
+	}
+	cfg.FollowerGoal.Targets = sortFollowerTargets(cfg.FollowerGoal.Targets)
+	if cfg.Quiet.Days <= 0 {
+		cfg.Quiet.Days = 7
+	}
+	if cfg.Quiet.RepeatDays <= 0 {
+		cfg.Quiet.RepeatDays = 7
+	}
+	if cfg.Baseline.Weeks == 0 {
+		cfg.Baseline.Weeks = 12
+	}
+	if cfg.Baseline.MinSessions == 0 {
+		cfg.Baseline.MinSessions = 3
+	}
+	if cfg.Baseline.Factor == 0 {
+		cfg.Baseline.Factor = 1.5
+	}
+	if cfg.Baseline.Factor <= 1 {
+		return nil, fmt.Errorf("baseline.factor must be greater than 1")
+	}
+	if cfg.AutoClip.SpikePercent == 0 {
+		cfg.AutoClip.SpikePercent = 50
+	}
+	if cfg.AutoClip.WindowMinutes == 0 {
+		cfg.AutoClip.WindowMinutes = 5
+	}
+	if cfg.AutoClip.MinViewers == 0 {
+		cfg.AutoClip.MinViewers = 10
+	}
+	if cfg.AutoClip.CooldownMinutes == 0 {
+		cfg.AutoClip.CooldownMinutes = 30
+	}
+	if cfg.Mature.Badge == "" {
+		cfg.Mature.Badge = "🔞"
+	}
+	for _, alert := range cfg.Alerts {
+		if !slices.Contains(alertConditions, alert.Condition) {
+			return nil, fmt.Errorf("unknown alert condition %q (available: %s)", alert.Condition, strings.Join(alertConditions, ", "))
+		}
+	}
+	if err := validateChannelTargets(cfg.Channels); err != nil {
+		return nil, err
+	}
+	if cfg.Telegram.Style == "" {
+		cfg.Telegram.Style = "default"
+	}
+	if !validStyle(cfg.Telegram.Style) {
+		return nil, fmt.Errorf("unknown message style %q (available: %s)", cfg.Telegram.Style, strings.Join(styleNames(), ", "))
+	}
+	if cfg.Layout == nil {
+		cfg.Layout = layoutSections
+	}
+	for _, section := range cfg.Layout {
+		if !slices.Contains(layoutSections, section) {
+			return nil, fmt.Errorf("unknown layout section %q (available: %s)", section, strings.Join(layoutSections, ", "))
+		}
+	}
+	if cfg.Retry.InitialDelay <= 0 {
+		cfg.Retry.InitialDelay = 1
+	}
+	if cfg.Retry.MaxDelay <= 0 {
+		cfg.Retry.MaxDelay = 60
+	}
+	if cfg.Retry.Multiplier < 1 {
+		cfg.Retry.Multiplier = 2
+	}
+	if cfg.Retry.Jitter == 0 {
+		cfg.Retry.Jitter = 0.2
+	}
+	if cfg.HTTP.Twitch.Timeout <= 0 {
+		cfg.HTTP.Twitch.Timeout = 15
+	}
+	if cfg.HTTP.Telegram.Timeout <= 0 {
+		cfg.HTTP.Telegram.Timeout = 15
+	}
+	if cfg.HTTP.Twitch.Retry.MaxAttempts == 0 {
+		cfg.HTTP.Twitch.Retry.MaxAttempts = 1
+	}
+	applyRetryDefaults(&cfg.HTTP.Twitch.Retry, cfg.Retry)
+	applyRetryDefaults(&cfg.HTTP.Telegram.Retry, cfg.Retry)
+	if cfg.Teaser.MinutesBefore <= 0 {
+		cfg.Teaser.MinutesBefore = 15
+	}
+	if cfg.Teaser.Source == "" {
+		cfg.Teaser.Source = "twitch"
+		if cfg.Teaser.Cron != "" {
+			cfg.Teaser.Source = "cron"
+		}
+	}
+	if cfg.Teaser.OnStart == "" {
+		cfg.Teaser.OnStart = "replace"
+	}
 
+	checker.checkRanges(&cfg)
+	if len(checker.issues) > 0 {
+		return nil, &ConfigErrors{File: path, Issues: checker.issues}
+	}
 	return &cfg, nil
 }
 
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return secret, nil
+}
+
 func saveConfig(path string, cfg *Config) error {
-	data, err := json.MarshalIndent(cfg, "", "  ")
+	out := *cfg
+	restoreSecretRefs(&out)
+	if out.Twitch.SecretFile != "" {
+		out.Twitch.ClientSecret = ""
+	}
+	if out.Telegram.BotTokenFile != "" {
+		out.Telegram.BotToken = ""
+	}
+	data, err := json.MarshalIndent(&out, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -107,29 +516,103 @@ func getLocalization(lang string) Localization {
 		return Localization{
 			StartedStreaming: "LIVE",
 			IsLive:           "LIVE",
+			OnBreak:          "☕ BRB",
 			StreamEnded:      "OFFLINE",
+			StartingSoon:     "SOON",
+			StartsAt:         "starts at",
+			StartsIn:         "in",
 			ButtonText:       "Watch",
 			Peak:             "peak",
+			PeakAt:           "at",
 			Viewers:          "viewers",
 			Avg:              "avg",
 			Clips:            "clips",
 			Growing:          "growing",
 			Steady:           "steady",
 			Dropping:         "dropping",
+			LastHour:         "in the last hour",
+			Retention:        "retention",
+			CoStreamWith:     "together with",
+			Squad:            "Squad",
+			ClipThanks:       "Thanks for the clips",
+			ViewerSpike:      "viewer spike",
+			Titles:           "titles",
+			Marks:            "moments",
+			Highlights:       "highlights",
+			HoursWatched:     "hours watched",
+			Quiet:            "💤 ON A BREAK",
+			NoStreamsFor:     "No streams for %d days — but we'll be back soon!",
+			Upcoming:         "Upcoming streams",
+			BestClip:         "Best recent clip",
+			FollowersTo:      "to %s followers",
+			UsualAudience:    "your usual %s audience",
+			Weekdays:         [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+			PerMinute:        "msg/min",
+			ShieldMode:       "shield mode",
+			EmoteOnly:        "emote-only",
+			SubOnly:          "sub-only",
+			FollowersOnly:    "followers-only",
+			SlowMode:         "slow mode",
+			UniqueChat:       "unique chat",
+			Giveaway:         "Giveaway",
+			GiveawayJoin:     "🎉 Join",
+			Participants:     "Participants",
+			Winner:           "Winner",
+			GiveawayEnded:    "This giveaway is over",
+			GiveawayJoined:   "You're in!",
+			AlreadyJoined:    "You're already in",
+			GiveawayCanceled: "Giveaway canceled",
 		}
 	case "ru":
 		return Localization{
 			StartedStreaming: "LIVE",
 			IsLive:           "LIVE",
+			OnBreak:          "☕ ПЕРЕРЫВ",
 			StreamEnded:      "OFFLINE",
+			StartingSoon:     "SOON",
+			StartsAt:         "начало в",
+			StartsIn:         "через",
 			ButtonText:       "Смотреть",
 			Peak:             "пик",
+			PeakAt:           "на",
 			Viewers:          "зрителей",
 			Avg:              "среднее",
 			Clips:            "клипов",
 			Growing:          "растёт",
 			Steady:           "стабильно",
 			Dropping:         "падает",
+			LastHour:         "за последний час",
+			Retention:        "удержание",
+			CoStreamWith:     "вместе с",
+			Squad:            "В скводе",
+			ClipThanks:       "Спасибо за клипы",
+			ViewerSpike:      "всплеск зрителей",
+			Titles:           "названия",
+			Marks:            "моменты",
+			Highlights:       "яркие моменты",
+			HoursWatched:     "ч просмотра",
+			Quiet:            "💤 ПЕРЕРЫВ",
+			NoStreamsFor:     "Стримов не было уже %d дн., но мы скоро вернёмся!",
+			Upcoming:         "Ближайшие стримы",
+			BestClip:         "Лучший клип",
+			FollowersTo:      "до %s фолловеров",
+			UsualAudience:    "от обычной аудитории %s",
+			Weekdays:         [7]string{"в воскресенье", "в понедельник", "во вторник", "в среду", "в четверг", "в пятницу", "в субботу"},
+			PerMinute:        "сообщ./мин",
+			ShieldMode:       "режим защиты",
+			EmoteOnly:        "только смайлики",
+			SubOnly:          "только подписчики",
+			FollowersOnly:    "только фолловеры",
+			SlowMode:         "медленный режим",
+			UniqueChat:       "уникальные сообщения",
+			Giveaway:         "Розыгрыш",
+			GiveawayJoin:     "🎉 Участвовать",
+			Participants:     "Участников",
+			Winner:           "Победитель",
+			GiveawayEnded:    "Розыгрыш завершён",
+			GiveawayJoined:   "Вы участвуете!",
+			AlreadyJoined:    "Вы уже участвуете",
+			GiveawayCanceled: "Розыгрыш отменён",
 		}
 	default:
 		return getLocalization("en")
@@ -147,24 +630,123 @@ func calculateAverage(history []ViewerDataPoint) int {
 	return sum / len(history)
 }
 
-func getMaxViewers(history []ViewerDataPoint) int {
+func hoursWatched(history []ViewerDataPoint) float64 {
+	var total float64
+	for i := 1; i < len(history); i++ {
+		a, b := history[i-1], history[i]
+		total += float64(a.Count+b.Count) / 2 * b.Timestamp.Sub(a.Timestamp).Hours()
+	}
+	return total
+}
+
+func viewerChange(history []ViewerDataPoint, window time.Duration) (int, bool) {
+	if len(history) < 2 {
+		return 0, false
+	}
+	last := history[len(history)-1]
+	cutoff := last.Timestamp.Add(-window)
+	if history[0].Timestamp.After(cutoff) {
+		return 0, false
+	}
+	base := history[0]
+	for _, p := range history {
+		if p.Timestamp.After(cutoff) {
+			break
+		}
+		base = p
+	}
+	return last.Count - base.Count, true
+}
+
+func calculateRetention(history []ViewerDataPoint, start time.Time) (float64, []RetentionPoint) {
+	if len(history) == 0 || history[len(history)-1].Timestamp.Sub(start) < time.Hour {
+		return 0, nil
+	}
+
+	baselineEnd := start.Add(retentionWindow)
+	var baseline []ViewerDataPoint
+	for _, p := range history {
+		if p.Timestamp.Before(baselineEnd) {
+			baseline = append(baseline, p)
+		}
+	}
+	base := calculateAverage(baseline)
+	if base == 0 {
+		return 0, nil
+	}
+
+	var series []RetentionPoint
+	var tail []ViewerDataPoint
+	endWindow := history[len(history)-1].Timestamp.Add(-retentionWindow)
+	for _, p := range history {
+		if p.Timestamp.Before(baselineEnd) {
+			continue
+		}
+		series = append(series, RetentionPoint{
+			Minute: int(p.Timestamp.Sub(start).Minutes()),
+			Ratio:  float64(p.Count) / float64(base),
+		})
+		if !p.Timestamp.Before(endWindow) {
+			tail = append(tail, p)
+		}
+	}
+	return float64(calculateAverage(tail)) / float64(base), series
+}
+
+func getMaxViewers(history []ViewerDataPoint) ViewerDataPoint {
 	if len(history) == 0 {
-		return 0
+		return ViewerDataPoint{}
 	}
-	max := history[0].Count
+	peak := history[0]
 	for _, p := range history {
-		if p.Count > max {
-			max = p.Count
+		if p.Count > peak.Count {
+			peak = p
 		}
 	}
-	return max
+	return peak
 }
 
 func main() {
 	configPath := "config.json"
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		if err := serveCommand(configPath, os.Args[2:]); err != nil {
+			slog.Error("serve failed", "error", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		if err := simulateCommand(configPath, os.Args[2:]); err != nil {
+			slog.Error("simulation failed", "error", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	setupFlag := flag.Bool("setup", false, "Run interactive setup and exit")
+	previewFlag := flag.Bool("preview-formats", false, "Render all message styles with sample data and exit")
+	previewLive := flag.Bool("preview-live", false, "Use live stream data for --preview-formats when the channel is online")
+	previewChat := flag.Int64("preview-chat", 0, "Also send --preview-formats output to this Telegram chat ID")
+	keyringFlag := flag.Bool("keyring", false, "Move the bot token and Twitch client secret from config.json to the OS keyring and exit")
+	fixturesDir := flag.String("render-fixtures", "", "Render every message style and language with fixed sample data into this directory and exit")
 	flag.Parse()
 
+	if *fixturesDir != "" {
+		if err := renderFixtures(*fixturesDir); err != nil {
+			slog.Error("failed to render fixtures", "error", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if *keyringFlag {
+		if err := moveSecretsToKeyring(configPath); err != nil {
+			slog.Error("failed to move secrets to keyring", "error", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	if *setupFlag {
 		if err := setupInteractive(configPath, true); err != nil {
 			slog.Error("setup failed", "error", err)
@@ -175,6 +757,11 @@ func main() {
 	}
 
 	cfg, err := loadConfig(configPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, "Run with --setup to fix the config interactively")
+		os.Exit(1)
+	}
 	if err != nil {
 		fmt.Println("No config file found. Starting interactive setup...")
 		fmt.Println()
@@ -189,6 +776,14 @@ func main() {
 		}
 	}
 
+	if *previewFlag {
+		if err := previewFormats(context.Background(), cfg, *previewLive, *previewChat); err != nil {
+			slog.Error("preview failed", "error", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	if !cfg.SetupCompleted {
 		fmt.Println("Setup incomplete. Running interactive setup...")
 		fmt.Println()
@@ -203,9 +798,259 @@ func main() {
 		}
 	}
 
+	stateStore, err = openStateStore(cfg.StateFile)
+	if err != nil {
+		slog.Error("failed to open state file", "error", err)
+		os.Exit(1)
+	}
+
+	store, err := newStorage(cfg.Storage)
+	if err != nil {
+		slog.Error("failed to configure storage", "error", err)
+		os.Exit(1)
+	}
+
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
+	configureEndpoints(cfg.HTTP.Endpoints)
+	if err := configureHTTPClient(twitchHTTP, cfg.HTTP.Twitch); err != nil {
+		slog.Error("failed to configure twitch client", "error", err)
+		os.Exit(1)
+	}
+	if err := configureHTTPClient(telegramHTTP, cfg.HTTP.Telegram); err != nil {
+		slog.Error("failed to configure telegram client", "error", err)
+		os.Exit(1)
+	}
+	twitchRetry = cfg.HTTP.Twitch.Retry
+
+	if cfg.Tracing.Enabled {
+		tracer = newTracer(cfg.Tracing.Endpoint, cfg.Tracing.ServiceName, cfg.Tracing.Headers)
+		for _, client := range []*http.Client{twitchHTTP, telegramHTTP} {
+			base := client.Transport
+			if base == nil {
+				base = http.DefaultTransport
+			}
+			client.Transport = tracingTransport{base: base}
+		}
+		go tracer.Run(ctx)
+	}
+
+	if cfg.Upstream.URL != "" {
+		upstream = newUpstreamClient(cfg, monitoredChannels(cfg))
+		disableHelixFeatures(cfg)
+	} else {
+		creds := []TwitchCredential{{ClientID: cfg.Twitch.ClientID, ClientSecret: cfg.Twitch.ClientSecret}}
+		creds = append(creds, cfg.Twitch.Credentials...)
+		if len(creds) > 1 {
+			setTwitchCredentials(creds)
+		}
+		streamHub = newStreamHub(cfg, monitoredChannels(cfg))
+		go runTokenRefresher(ctx, creds)
+		preloadTwitchUsers(ctx, cfg)
+	}
+
+	bus := &EventBus{}
+	if cfg.Teaser.Enabled {
+		teaser, err := newTeaser(cfg)
+		if err != nil {
+			slog.Error("failed to start teaser", "error", err)
+			os.Exit(1)
+		}
+		bus.Subscribe(teaser.Handle)
+		go teaser.Run(ctx)
+	}
+	poller := newUpdatePoller(cfg.Telegram.BotToken)
+
+	var discussions *DiscussionTracker
+	if cfg.Telegram.CommentStats {
+		chat, err := getChat(cfg.Telegram.BotToken, *cfg.Telegram.ChatID)
+		if err != nil {
+			slog.Warn("failed to get chat info", "error", err)
+		} else if chat.LinkedChatID == 0 {
+			slog.Warn("comment_stats is enabled but the chat has no linked discussion group")
+		}
+		discussions = newDiscussionTracker(*cfg.Telegram.ChatID)
+		poller.Subscribe(discussions.HandleUpdate, "message")
+	}
+
+	var reactions *ReactionTracker
+	if cfg.Telegram.TrackReactions {
+		reactions = newReactionTracker(*cfg.Telegram.ChatID)
+		poller.Subscribe(reactions.HandleUpdate, "message_reaction", "message_reaction_count")
+	}
+
+	if cfg.ChatModes.Enabled {
+		bus.Subscribe(newChatModeWatcher(cfg).Handle)
+	}
+	if cfg.FollowerGoal.Enabled {
+		bus.Subscribe(newFollowerGoalWatcher(cfg).Handle)
+	}
+	control := newMonitorControl()
+	access := newChatAccess(cfg)
+	go access.Run(ctx)
+	poller.Subscribe(access.HandleUpdate, "my_chat_member")
+	var approval *ApprovalGate
+	if cfg.Telegram.Approval {
+		if cfg.Telegram.AdminChatID == nil {
+			slog.Warn("telegram.approval requires telegram.admin_chat_id")
+		} else {
+			approval = newApprovalGate(cfg, control)
+			poller.Subscribe(approval.HandleUpdate, "callback_query")
+		}
+	}
+	caption := &CaptionState{}
+	marks := &MarkLog{}
+	bus.Subscribe(marks.Handle)
+	var detector *HighlightDetector
+	if cfg.AutoHighlights.Enabled {
+		detector = newHighlightDetector(cfg)
+		bus.Subscribe(detector.Handle)
+	}
+	archive := newSessionArchive(store, cfg.HistoryFile, reactions)
+	if cfg.Baseline.Enabled {
+		bus.Subscribe(newBaselineTracker(cfg, archive).Handle)
+	}
+	bus.Subscribe(newTelegramNotifier(cfg, discussions, access, approval, caption).Handle)
+	if len(cfg.Telegram.Milestones) > 0 {
+		bus.Subscribe(newMilestoneReactor(cfg).Handle)
+	}
+	bus.Subscribe(archive.Handle)
+	if cfg.Quiet.Enabled {
+		quiet := newQuietPoster(cfg, archive)
+		bus.Subscribe(quiet.Handle)
+		go quiet.Run(ctx)
+	}
+	live := &LiveState{}
+	bus.Subscribe(live.Handle)
+	feed := &EventFeed{}
+	bus.Subscribe(feed.Handle)
+	bus.Subscribe(logStreamStats)
+	if len(cfg.Alerts) > 0 {
+		if cfg.Telegram.AdminChatID == nil {
+			slog.Warn("alerts are configured but telegram.admin_chat_id is not set")
+		}
+		bus.Subscribe(newAlertMonitor(cfg).Handle)
+	}
+	if cfg.Screenshots.Enabled {
+		bus.Subscribe(newScreenshotArchive(cfg, store).Handle)
+	}
+	if cfg.Moderation.Enabled {
+		broadcasterID, err := getBroadcasterID(ctx, cfg.Twitch.Channel, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+		switch {
+		case cfg.Twitch.UserToken == "":
+			slog.Warn("moderation is enabled but twitch.user_token is not set")
+		case err != nil:
+			slog.Error("failed to get broadcaster ID for moderation events", "error", err)
+		default:
+			digest := newModerationDigest(cfg)
+			bus.Subscribe(digest.Handle)
+			go newEventSubClient(cfg, moderationSubscriptions(broadcasterID), digest.HandleEventSub).Run(ctx)
+		}
+	}
+	if cfg.StreamHealth.Enabled {
+		bus.Subscribe(newHealthMonitor(cfg).Handle)
+	}
+	if cfg.AutoClip.Enabled {
+		if cfg.Twitch.UserToken == "" {
+			slog.Warn("auto_clip is enabled but twitch.user_token is not set")
+		} else {
+			bus.Subscribe(newAutoClipper(cfg).Handle)
+		}
+	}
+
+	if cfg.Redis.Enabled {
+		redis := newRedisPublisher(cfg.Redis, cfg.Retry)
+		bus.Subscribe(redis.Handle)
+		go redis.Run(ctx)
+	}
+
+	if cfg.Plugins.Dir != "" {
+		pn, err := loadPlugins(ctx, cfg.Plugins)
+		if err != nil {
+			slog.Error("failed to load plugins", "error", err)
+			os.Exit(1)
+		}
+		for _, p := range pn.plugins {
+			slog.Info("plugin loaded", "plugin", p.Name)
+		}
+		if len(pn.plugins) > 0 {
+			bus.Subscribe(pn.Handle)
+			go pn.Run(ctx)
+		}
+	}
+
+	var chat *TwitchChat
+	if cfg.ChatHighlights.Enabled {
+		highlights, err := newChatHighlights(cfg)
+		if err != nil {
+			slog.Error("failed to start chat highlights", "error", err)
+			os.Exit(1)
+		}
+		bus.Subscribe(highlights.Handle)
+		chat = newTwitchChat(cfg.Twitch.Channel)
+		chat.Subscribe(highlights.OnMessage)
+	}
+	if detector != nil {
+		if chat == nil {
+			chat = newTwitchChat(cfg.Twitch.Channel)
+		}
+		chat.Subscribe(detector.OnMessage)
+	}
+	if chat != nil {
+		go chat.Run(ctx)
+	}
+
+	commands := newCommandRouter(cfg)
+	if cfg.Commands.Enabled {
+		newStatusCommands(cfg, archive, live).Register(commands)
+		if cfg.Telegram.AdminChatID != nil || len(cfg.Telegram.Admins) > 0 {
+			newControlCommands(cfg, control, live, caption, marks).Register(commands)
+			giveaway := newGiveaway(cfg, live)
+			giveaway.Register(commands)
+			poller.Subscribe(giveaway.HandleUpdate, "callback_query")
+		}
+	}
+	if cfg.ChatBridge.Say {
+		if cfg.Twitch.UserToken == "" || (cfg.Telegram.AdminChatID == nil && len(cfg.Telegram.Admins) == 0) {
+			slog.Warn("chat_bridge.say requires twitch.user_token and telegram.admin_chat_id or telegram.admins")
+		} else {
+			commands.Handle(Command{Name: "say", Admin: true, Handler: newChatBridge(cfg).Say})
+		}
+	}
+	if commands.Active() {
+		commands.Handle(Command{Name: "help", Handler: commands.Help})
+		commands.Register()
+		poller.Subscribe(commands.HandleUpdate, "message")
+	}
+
+	if upstream != nil {
+		go upstream.Run(ctx)
+	}
+	archives, lives := startChannelTargets(ctx, cfg, store, poller, feed)
+	archives[strings.ToLower(cfg.Twitch.Channel)] = archive
+	lives[strings.ToLower(cfg.Twitch.Channel)] = live
+
+	if poller.Active() {
+		go poller.Run(ctx)
+	}
+
+	if cfg.Server.Listen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /calendar.ics", newCalendarFeed(cfg, archive))
+		graph := newStatusGraph(cfg, archive, live)
+		mux.Handle("GET /graphql", graph)
+		mux.Handle("POST /graphql", graph)
+		mux.Handle("GET /api/channels/{name}/sessions", newSessionsAPI(archives))
+		newGrafanaDatasource(cfg, archives, live).Register(mux)
+		mux.Handle("GET /ws/live", newLiveFeedServer(cfg, lives, feed))
+		go runServer(ctx, cfg.Server.Listen, mux)
+	}
+
+	if cfg.GRPC.Listen != "" {
+		go newGRPCServer(cfg, live, feed, control).Run(ctx)
+	}
+
 	slog.Info("starting monitor")
-	monitorLoop(ctx, cfg)
+	monitorLoop(ctx, cfg, streamSourceFor(cfg), bus, control)
 }
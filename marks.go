@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type StreamMark struct {
+	Time time.Time `json:"time"`
+	Text string    `json:"text"`
+}
+
+type MarkLog struct {
+	mu        sync.Mutex
+	startTime time.Time
+	marks     []StreamMark
+}
+
+func (l *MarkLog) Add(start time.Time, mark StreamMark) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.startTime.Equal(start) {
+		l.startTime = start
+		l.marks = nil
+	}
+	l.marks = append(l.marks, mark)
+}
+
+func (l *MarkLog) Get(start time.Time) []StreamMark {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.startTime.Equal(start) {
+		return nil
+	}
+	return slices.Clone(l.marks)
+}
+
+func (l *MarkLog) Handle(ctx context.Context, ev Event) {
+	if ev.Session != nil {
+		ev.Session.Marks = l.Get(ev.Session.StartTime)
+	}
+}
+
+type MarkEntry struct {
+	At   string
+	Text string
+}
+
+func markEntries(marks []StreamMark, start time.Time, lang string) []MarkEntry {
+	entries := make([]MarkEntry, 0, len(marks))
+	for _, m := range marks {
+		entries = append(entries, MarkEntry{At: formatDuration(m.Time.Sub(start), lang), Text: m.Text})
+	}
+	return entries
+}
+
+func formatMarks(marks []MarkEntry, mf MessageFormat) string {
+	if len(marks) == 0 {
+		return ""
+	}
+	lines := []string{"📍 " + mf.Marks + ":"}
+	for _, m := range marks {
+		lines = append(lines, fmt.Sprintf("%s — %s", m.At, escapeHTML(m.Text)))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+type MilestoneReaction struct {
+	Viewers int    `json:"viewers"`
+	Emoji   string `json:"emoji"`
+}
+
+type MilestoneReactor struct {
+	cfg        *Config
+	milestones []MilestoneReaction
+	reached    int
+	messageID  int
+}
+
+func newMilestoneReactor(cfg *Config) *MilestoneReactor {
+	milestones := slices.Clone(cfg.Telegram.Milestones)
+	slices.SortFunc(milestones, func(a, b MilestoneReaction) int { return a.Viewers - b.Viewers })
+	return &MilestoneReactor{cfg: cfg, milestones: milestones, reached: -1}
+}
+
+func (m *MilestoneReactor) Handle(ctx context.Context, ev Event) {
+	switch ev.Type {
+	case EventStreamStarted:
+		m.reached = -1
+		m.messageID = 0
+	case EventStreamUpdated:
+		if ev.Session.MessageID == 0 {
+			return
+		}
+		reached := m.reached
+		for i, ms := range m.milestones {
+			if i > reached && ev.Info.Viewers >= ms.Viewers {
+				reached = i
+			}
+		}
+		if reached < 0 || (reached == m.reached && ev.Session.MessageID == m.messageID) {
+			return
+		}
+		ms := m.milestones[reached]
+		if err := setMessageReaction(m.cfg.Telegram.BotToken, *m.cfg.Telegram.ChatID, ev.Session.MessageID, ms.Emoji); err != nil {
+			slog.Warn("failed to set milestone reaction", "viewers", ms.Viewers, "error", err)
+			return
+		}
+		slog.Info("milestone reaction set", "viewers", ms.Viewers, "emoji", ms.Emoji)
+		m.reached = reached
+		m.messageID = ev.Session.MessageID
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+type ModerationAction struct {
+	Time      time.Time
+	Type      string
+	User      string
+	Moderator string
+	Reason    string
+	Duration  time.Duration
+}
+
+type ModerationDigest struct {
+	cfg *Config
+
+	mu      sync.Mutex
+	live    bool
+	actions []ModerationAction
+}
+
+func newModerationDigest(cfg *Config) *ModerationDigest {
+	return &ModerationDigest{cfg: cfg}
+}
+
+func moderationSubscriptions(broadcasterID string) []EventSubSubscription {
+	cond := map[string]string{"broadcaster_user_id": broadcasterID}
+	return []EventSubSubscription{
+		{Type: "channel.ban", Version: "1", Condition: cond},
+		{Type: "channel.unban", Version: "1", Condition: cond},
+	}
+}
+
+func (d *ModerationDigest) HandleEventSub(subType string, raw json.RawMessage) {
+	var ev struct {
+		UserLogin      string    `json:"user_login"`
+		ModeratorLogin string    `json:"moderator_user_login"`
+		Reason         string    `json:"reason"`
+		IsPermanent    bool      `json:"is_permanent"`
+		BannedAt       time.Time `json:"banned_at"`
+		EndsAt         time.Time `json:"ends_at"`
+	}
+	if err := json.Unmarshal(raw, &ev); err != nil {
+		slog.Warn("invalid moderation event", "error", err)
+		return
+	}
+
+	action := ModerationAction{Time: time.Now(), User: ev.UserLogin, Moderator: ev.ModeratorLogin, Reason: ev.Reason}
+	switch {
+	case subType == "channel.unban":
+		action.Type = "unban"
+	case ev.IsPermanent:
+		action.Type = "ban"
+	default:
+		action.Type = "timeout"
+		action.Duration = ev.EndsAt.Sub(ev.BannedAt).Round(time.Second)
+	}
+	slog.Info("moderation action", "type", action.Type, "user", action.User, "moderator", action.Moderator)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.live {
+		d.actions = append(d.actions, action)
+	}
+}
+
+func (d *ModerationDigest) Handle(ctx context.Context, ev Event) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch ev.Type {
+	case EventStreamStarted:
+		d.live = true
+		d.actions = nil
+	case EventStreamEnded:
+		d.live = false
+		if len(d.actions) > 0 {
+			notifyAdmin(d.cfg, formatModerationDigest(ev.Channel, d.actions))
+		}
+		d.actions = nil
+	}
+}
+
+func formatModerationDigest(channel string, actions []ModerationAction) string {
+	counts := map[string]int{}
+	mods := map[string]int{}
+	for _, a := range actions {
+		counts[a.Type]++
+		if a.Moderator != "" {
+			mods[a.Moderator]++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🛡 <b>%s</b> moderation: %d bans, %d timeouts, %d unbans\n", escapeHTML(channel), counts["ban"], counts["timeout"], counts["unban"])
+	for mod, n := range mods {
+		fmt.Fprintf(&b, "\n%s: %d", escapeHTML(mod), n)
+	}
+	b.WriteString("\n")
+
+	const maxLines = 30
+	for i, a := range actions {
+		if i == maxLines {
+			fmt.Fprintf(&b, "\n… %d more", len(actions)-maxLines)
+			break
+		}
+		line := fmt.Sprintf("\n%s %s <b>%s</b>", a.Time.Local().Format("15:04"), a.Type, escapeHTML(a.User))
+		if a.Duration > 0 {
+			line += fmt.Sprintf(" (%s)", a.Duration)
+		}
+		if a.Moderator != "" {
+			line += " — " + escapeHTML(a.Moderator)
+		}
+		if a.Reason != "" {
+			line += ": <i>" + escapeHTML(a.Reason) + "</i>"
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
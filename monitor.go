@@ -2,53 +2,103 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"log/slog"
-	"os"
+	"math/rand/v2"
+	"slices"
 	"time"
 )
 
-func retryWithBackoff(ctx context.Context, operation func() error, operationName string) error {
-	delays := []int{1, 3, 5, 10, 15, 30, 45, 60}
+type permanentError interface {
+	Permanent() bool
+}
+
+func isPermanentError(err error) bool {
+	var pe permanentError
+	return errors.As(err, &pe) && pe.Permanent()
+}
+
+func retryWithBackoff(ctx context.Context, policy RetryConfig, operation func() error, operationName string) error {
+	delay := time.Duration(policy.InitialDelay) * time.Second
+	maxDelay := time.Duration(policy.MaxDelay) * time.Second
 
-	for _, delay := range delays {
-		if err := operation(); err == nil {
+	for attempt := 1; ; attempt++ {
+		err := operation()
+		if err == nil {
+			if attempt > 1 {
+				slog.Info("operation recovered", "name", operationName, "attempts", attempt)
+			}
 			return nil
 		}
-
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(time.Duration(delay) * time.Second):
-			slog.Warn("retrying operation", "name", operationName, "next_in", delay)
+		if isPermanentError(err) {
+			slog.Error("operation failed permanently", "name", operationName, "error", err)
+			return err
+		}
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			slog.Error("operation failed", "name", operationName, "attempts", attempt, "error", err)
+			return err
 		}
-	}
 
-	for {
-		if err := operation(); err == nil {
-			slog.Info("operation recovered", "name", operationName)
-			return nil
+		wait := jitter(delay, policy.Jitter)
+		var tgErr *TelegramError
+		if errors.As(err, &tgErr) && time.Duration(tgErr.RetryAfter)*time.Second > wait {
+			wait = time.Duration(tgErr.RetryAfter) * time.Second
 		}
-		slog.Warn("operation still failing", "name", operationName)
+		slog.Warn("retrying operation", "name", operationName, "attempt", attempt, "next_in", wait, "error", err)
+
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-time.After(60 * time.Second):
+		case <-clock.After(wait):
+		}
+
+		delay = time.Duration(float64(delay) * policy.Multiplier)
+		if delay > maxDelay {
+			delay = maxDelay
 		}
 	}
 }
 
-func monitorLoop(ctx context.Context, cfg *Config) {
+func jitter(d time.Duration, factor float64) time.Duration {
+	if factor <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + factor*(2*rand.Float64()-1)))
+}
+
+type StreamSource interface {
+	Stream(ctx context.Context) (*StreamInfo, error)
+	BroadcasterID(ctx context.Context) (string, error)
+}
+
+type twitchSource struct {
+	cfg *Config
+}
+
+func (s twitchSource) Stream(ctx context.Context) (*StreamInfo, error) {
+	cfg := s.cfg
+	ctx, span := startSpan(ctx, "check stream", spanKindInternal)
+	span.SetAttr("twitch.channel", cfg.Twitch.Channel)
+	info, err := getStreamInfo(ctx, cfg.Twitch.Channel, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret, cfg.Language)
+	span.End(err)
+	return info, err
+}
+
+func (s twitchSource) BroadcasterID(ctx context.Context) (string, error) {
+	cfg := s.cfg
+	return getBroadcasterID(ctx, cfg.Twitch.Channel, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+}
+
+func monitorLoop(ctx context.Context, cfg *Config, source StreamSource, bus *EventBus, control *MonitorControl) {
 	slog.Info("monitor started",
 		"channel", cfg.Twitch.Channel,
 		"check_interval", cfg.CheckInterval,
 		"update_interval", cfg.UpdateInterval,
 	)
 
-	loc := getLocalization(cfg.Language)
 	var session *StreamSession
-	checksPerUpdate := (cfg.UpdateInterval * 60) / cfg.CheckInterval
 	lastWasLive := false
+	guard := newChannelGuard(cfg)
 
 	for {
 		select {
@@ -58,27 +108,22 @@ func monitorLoop(ctx context.Context, cfg *Config) {
 		default:
 		}
 
-		var info *StreamInfo
-		var err error
-
-		if fileExists("simulate_end") {
-			slog.Info("simulate_end trigger detected")
-			os.Remove("simulate_end")
-			if session != nil {
-				info = nil
-				err = fmt.Errorf("simulated end")
-			}
-		} else {
-			info, err = getStreamInfo(ctx, cfg.Twitch.Channel, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret, cfg.Language)
+		if control.Paused() {
+			control.Sleep(ctx, time.Duration(cfg.CheckInterval)*time.Second)
+			continue
 		}
+		forced := control.takeForced()
+
+		info, err := source.Stream(ctx)
 
 		if err != nil {
 			slog.Error("stream status check failed", "error", err)
-			sleep(ctx, time.Duration(cfg.CheckInterval)*time.Second)
+			control.Sleep(ctx, time.Duration(cfg.CheckInterval)*time.Second)
 			continue
 		}
 
 		isLive := info != nil
+		now := clock.Now()
 
 		if isLive != lastWasLive {
 			if isLive {
@@ -89,117 +134,91 @@ func monitorLoop(ctx context.Context, cfg *Config) {
 			lastWasLive = isLive
 		}
 
+		if !isLive {
+			guard.Check(ctx, now)
+		}
+
 		if isLive && session == nil {
 			slog.Info("stream started", "channel", cfg.Twitch.Channel)
 
-			broadcasterID, err := getBroadcasterID(ctx, cfg.Twitch.Channel, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+			broadcasterID, err := source.BroadcasterID(ctx)
 			if err != nil {
 				slog.Error("failed to get broadcaster ID", "error", err)
-				sleep(ctx, time.Duration(cfg.CheckInterval)*time.Second)
+				control.Sleep(ctx, time.Duration(cfg.CheckInterval)*time.Second)
 				continue
 			}
 
-			thumbnailURL := getThumbnailURL(cfg.Twitch.Channel)
-			message := formatStartMessage(info, loc)
-			dataPoint := ViewerDataPoint{Timestamp: time.Now(), Count: info.Viewers}
-
-			var messageID int
-			retryWithBackoff(ctx, func() error {
-				var sendErr error
-				messageID, sendErr = sendPhotoMessage(
-					cfg.Telegram.BotToken, *cfg.Telegram.ChatID, cfg.Telegram.ThreadID,
-					thumbnailURL, message, info.URL, loc.ButtonText,
-				)
-				return sendErr
-			}, "send start notification")
-
-			if messageID != 0 {
-				slog.Info("start notification sent")
-				session = &StreamSession{
-					MessageID:     messageID,
-					StartTime:     time.Now(),
-					Game:          info.Game,
-					Title:         info.Title,
-					Tags:          info.Tags,
-					BroadcasterID: broadcasterID,
-					ViewerHistory: []ViewerDataPoint{dataPoint},
-				}
+			startTime := now
+			if !info.StartedAt.IsZero() && info.StartedAt.Before(now) {
+				startTime = info.StartedAt
+			}
+			session = &StreamSession{
+				StartTime:     startTime,
+				Game:          info.Game,
+				Title:         info.Title,
+				Tags:          info.Tags,
+				BroadcasterID: broadcasterID,
+				ViewerHistory: []ViewerDataPoint{{Timestamp: now, Count: info.Viewers}},
+				TitleHistory:  []TitleChange{{Timestamp: startTime, Title: info.Title, Viewers: info.Viewers}},
+				TagHistory:    []TagChange{{Timestamp: startTime, Tags: info.Tags}},
 			}
+			bus.Publish(ctx, Event{Type: EventStreamStarted, Time: now, Channel: cfg.Twitch.Channel, Info: info, Session: session})
 
 		} else if isLive && session != nil {
-			session.ViewerHistory = append(session.ViewerHistory, ViewerDataPoint{
-				Timestamp: time.Now(), Count: info.Viewers,
-			})
-			session.UpdateCounter++
-			gameChanged := info.Game != session.Game && session.Game != ""
-
-			if session.UpdateCounter >= checksPerUpdate || gameChanged {
-				if gameChanged {
-					slog.Info("game changed", "from", session.Game, "to", info.Game)
-				}
-				slog.Info("updating stream info", "viewers", info.Viewers, "uptime", info.Uptime)
-
-				avgViewers := calculateAverage(session.ViewerHistory)
-				thumbnailURL := getThumbnailURL(cfg.Twitch.Channel)
-
-				clips, _ := getRecentClips(ctx, session.BroadcasterID, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret, session.StartTime)
-				message := formatUpdateMessageWithClips(info, avgViewers, session.ViewerHistory, clips, loc)
-
-				retryWithBackoff(ctx, func() error {
-					return editPhotoMessage(
-						cfg.Telegram.BotToken, *cfg.Telegram.ChatID, session.MessageID,
-						thumbnailURL, message, info.URL, loc.ButtonText,
-					)
-				}, "update stream info")
-
-				slog.Info("stream info updated")
-				session.UpdateCounter = 0
-				session.Game = info.Game
-				session.Title = info.Title
-				session.Tags = info.Tags
+			last := session.ViewerHistory[len(session.ViewerHistory)-1]
+			if now.Sub(last.Timestamp) >= time.Duration(cfg.SampleInterval)*time.Second {
+				session.ViewerHistory = append(session.ViewerHistory, ViewerDataPoint{
+					Timestamp: now, Count: info.Viewers,
+				})
+			}
+
+			if info.Title != session.Title {
+				slog.Info("title changed", "from", session.Title, "to", info.Title)
+				session.TitleHistory = append(session.TitleHistory, TitleChange{Timestamp: now, Title: info.Title, Viewers: info.Viewers})
+			}
+
+			previousTags := session.Tags
+			tagsChanged := !slices.Equal(info.Tags, previousTags)
+			if tagsChanged {
+				slog.Info("tags changed", "from", previousTags, "to", info.Tags)
+				session.TagHistory = append(session.TagHistory, TagChange{Timestamp: now, Tags: info.Tags})
+			}
+
+			previousGame := session.Game
+			session.Game = info.Game
+			session.Title = info.Title
+			session.Tags = info.Tags
+
+			if info.Game != previousGame && previousGame != "" {
+				bus.Publish(ctx, Event{Type: EventGameChanged, Time: now, Channel: cfg.Twitch.Channel, Info: info, Session: session, PreviousGame: previousGame})
+			}
+			if tagsChanged {
+				bus.Publish(ctx, Event{Type: EventTagsChanged, Time: now, Channel: cfg.Twitch.Channel, Info: info, Session: session, PreviousTags: previousTags})
 			}
+			bus.Publish(ctx, Event{Type: EventStreamUpdated, Time: now, Channel: cfg.Twitch.Channel, Info: info, Session: session, Forced: forced})
 
 		} else if !isLive && session != nil {
 			slog.Info("stream ended", "channel", cfg.Twitch.Channel)
-
-			duration := time.Since(session.StartTime)
-			durationStr := formatDuration(duration, cfg.Language)
-			avgViewers := calculateAverage(session.ViewerHistory)
-			maxViewers := getMaxViewers(session.ViewerHistory)
-
-			slog.Info("stream stats",
-				"duration", durationStr,
-				"avg_viewers", avgViewers,
-				"max_viewers", maxViewers,
-			)
-
-			clips, _ := getRecentClips(ctx, session.BroadcasterID, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret, session.StartTime)
-			message := formatEndMessage(cfg.Twitch.Channel, durationStr, avgViewers, maxViewers, session.Game, session.Title, session.Tags, clips, loc)
-			streamURL := fmt.Sprintf("https://twitch.tv/%s", cfg.Twitch.Channel)
-
-			retryWithBackoff(ctx, func() error {
-				return editMessageCaption(
-					cfg.Telegram.BotToken, *cfg.Telegram.ChatID, session.MessageID,
-					message, streamURL, loc.ButtonText,
-				)
-			}, "send end notification")
-
-			slog.Info("end notification sent")
+			bus.Publish(ctx, Event{Type: EventStreamEnded, Time: now, Channel: cfg.Twitch.Channel, Session: session})
 			session = nil
 		}
 
-		sleep(ctx, time.Duration(cfg.CheckInterval)*time.Second)
+		control.Sleep(ctx, time.Duration(cfg.CheckInterval)*time.Second)
+	}
+}
+
+func notifyAdmin(cfg *Config, text string) {
+	if cfg.Telegram.AdminChatID == nil {
+		return
+	}
+	if err := sendTextMessage(cfg.Telegram.BotToken, *cfg.Telegram.AdminChatID, nil, text); err != nil {
+		slog.Error("failed to notify admin", "error", err)
 	}
 }
 
 func sleep(ctx context.Context, d time.Duration) {
 	select {
 	case <-ctx.Done():
-	case <-time.After(d):
+	case <-clock.After(d):
 	}
 }
-
-func fileExists(filename string) bool {
-	_, err := os.Stat(filename)
-	return err == nil
-}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func loadTestConfig(t *testing.T, data string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+const testConfigJSON = `{
+	"twitch": {"channel": "somechannel", "client_id": "client", "client_secret": "secret"},
+	"telegram": {"bot_token": "123:abc", "chat_id": -100500},
+	"language": "en",
+	"check_interval_seconds": 60,
+	"update_interval_minutes": 1
+}`
+
+func TestMonitorLoopAnnounceUpdateEnd(t *testing.T) {
+	helix := newFakeHelix(t)
+	bot := newFakeBotAPI(t)
+	start := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	c := useManualClock(t, start)
+
+	cfg := loadTestConfig(t, testConfigJSON)
+	helix.AddUser("42", "somechannel")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	bus := &EventBus{}
+	bus.Subscribe(newTelegramNotifier(cfg, nil, nil, nil, nil).Handle)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		monitorLoop(ctx, cfg, newStreamHub(cfg, monitoredChannels(cfg)).Source(cfg.Twitch.Channel), bus, newMonitorControl())
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	tick := func() {
+		c.BlockUntil(1)
+		c.Advance(time.Duration(cfg.CheckInterval) * time.Second)
+	}
+
+	c.BlockUntil(1)
+	if calls := bot.Calls(); len(calls) != 0 {
+		t.Fatalf("offline channel produced bot calls: %v", calls)
+	}
+
+	helix.SetLive("somechannel", TwitchStream{Title: "First title", GameName: "Just Chatting", ViewerCount: 10, StartedAt: start})
+	tick()
+	sent := bot.WaitFor(t, 1, "sendPhoto")[0]
+	if sent.Params["chat_id"] != "-100500" || !strings.Contains(sent.Params["caption"], "First title") {
+		t.Fatalf("unexpected announcement %v", sent.Params)
+	}
+
+	helix.SetLive("somechannel", TwitchStream{Title: "Second title", GameName: "Just Chatting", ViewerCount: 25, StartedAt: start})
+	tick()
+	edit := bot.WaitFor(t, 1, "editMessageCaption", "editMessageMedia")[0]
+	if edit.Params["message_id"] != "101" || !strings.Contains(edit.Params["caption"]+edit.Params["media"], "Second title") {
+		t.Fatalf("unexpected edit %v", edit.Params)
+	}
+
+	helix.SetOffline("somechannel")
+	tick()
+	edits := bot.WaitFor(t, 2, "editMessageCaption", "editMessageMedia")
+	end := edits[len(edits)-1]
+	if end.Params["message_id"] != "101" || !strings.Contains(end.Params["caption"], "OFFLINE") {
+		t.Fatalf("unexpected end message %v", end.Params)
+	}
+	if n := len(bot.Calls("sendPhoto")); n != 1 {
+		t.Fatalf("announcement was sent %d times", n)
+	}
+}
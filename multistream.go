@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+type MultistreamConfig struct {
+	Partners       []string `json:"partners"`
+	CombinedButton bool     `json:"combined_button"`
+	URL            string   `json:"url"`
+}
+
+func resolvePartners(ctx context.Context, cfg *Config, info *StreamInfo) {
+	partners := cfg.Multistream.Partners
+	if len(partners) == 0 || info == nil {
+		return
+	}
+	live, err := getLiveChannels(ctx, partners, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+	if err != nil {
+		slog.Warn("failed to check multistream partners", "error", err)
+		return
+	}
+	info.Partners = live
+}
+
+func watchURL(cfg *Config, info *StreamInfo) string {
+	if !cfg.Multistream.CombinedButton || len(info.Partners) == 0 {
+		return info.URL
+	}
+	channels := append([]string{info.Channel}, info.Partners...)
+	return strings.ReplaceAll(cfg.Multistream.URL, "{channels}", strings.Join(channels, "/"))
+}
@@ -0,0 +1,487 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type TelegramNotifier struct {
+	cfg             *Config
+	format          MessageFormat
+	style           MessageStyle
+	checksPerUpdate int
+	updateCounter   int
+	announceAt      time.Time
+	discussions     *DiscussionTracker
+	access          *ChatAccess
+	approval        *ApprovalGate
+	caption         *CaptionState
+	bots            *BotPool
+	goals           []GoalFetcher
+	steam           *SteamResolver
+	shortener       *Shortener
+	edits           *EditQueue
+
+	mu            sync.Mutex
+	lastThumbnail time.Time
+	squad         squadCache
+}
+
+func newTelegramNotifier(cfg *Config, discussions *DiscussionTracker, access *ChatAccess, approval *ApprovalGate, caption *CaptionState) *TelegramNotifier {
+	n := &TelegramNotifier{
+		cfg:             cfg,
+		format:          newMessageFormat(cfg),
+		style:           messageStyles[cfg.Telegram.Style],
+		checksPerUpdate: (cfg.UpdateInterval * 60) / cfg.CheckInterval,
+		discussions:     discussions,
+		access:          access,
+		approval:        approval,
+		caption:         caption,
+		bots:            newBotPool(cfg),
+		goals:           newGoalFetchers(cfg.Goals),
+		steam:           newSteamResolver(cfg.Steam),
+		shortener:       newShortener(cfg.Shortener),
+	}
+	n.edits = newEditQueue(n.sendUpdate)
+	return n
+}
+
+func (n *TelegramNotifier) Handle(ctx context.Context, ev Event) {
+	if ev.Session != nil {
+		n.edits.Apply(ev.Session)
+	}
+	switch ev.Type {
+	case EventStreamStarted:
+		n.announceAt = ev.Time.Add(time.Duration(n.cfg.AnnounceDelay) * time.Second)
+		if ev.Session.MessageID == 0 && !ev.Time.Before(n.announceAt) {
+			n.sendStart(ctx, ev)
+		} else if n.cfg.AnnounceDelay > 0 {
+			slog.Info("start notification delayed", "until", n.announceAt)
+		}
+	case EventGameChanged:
+		slog.Info("game changed", "from", ev.PreviousGame, "to", ev.Info.Game)
+		if ev.Session.MessageID != 0 {
+			n.queueUpdate(ctx, ev)
+		}
+	case EventTagsChanged:
+		if ev.Session.MessageID != 0 {
+			n.updateCounter = n.checksPerUpdate - 1
+		}
+	case EventStreamUpdated:
+		n.updateCounter++
+		if ev.Forced {
+			n.updateCounter = n.checksPerUpdate
+		}
+		if n.access.takeRestored() && ev.Session.MessageID != 0 {
+			n.edits.Flush(ev.Session)
+			slog.Info("access to the chat restored, posting a fresh announcement", "old_message_id", ev.Session.MessageID)
+			n.discussions.Forget(ev.Session.MessageID)
+			forgetAnnouncement(n.cfg, ev)
+			ev.Session.MessageID = 0
+		}
+		if ev.Session.MessageID == 0 {
+			if !ev.Time.Before(n.announceAt) {
+				n.sendStart(ctx, ev)
+			}
+		} else if n.updateCounter >= n.checksPerUpdate {
+			n.queueUpdate(ctx, ev)
+		}
+	case EventStreamEnded:
+		n.edits.Flush(ev.Session)
+		if ev.Session.MessageID != 0 {
+			n.sendEnd(ctx, ev)
+			forgetAnnouncement(n.cfg, ev)
+		}
+	}
+}
+
+func (n *TelegramNotifier) queueUpdate(ctx context.Context, ev Event) {
+	n.updateCounter = 0
+	n.edits.Push(ctx, ev)
+}
+
+func (n *TelegramNotifier) sendStart(ctx context.Context, ev Event) {
+	cfg := n.cfg
+	if !n.access.Allowed() {
+		slog.Info("no access to the chat, start notification postponed")
+		return
+	}
+	if n.resumeAnnouncement(ctx, ev) {
+		return
+	}
+	n.resolveMature(ctx, ev)
+	resolvePartners(ctx, cfg, ev.Info)
+	n.resolveSquad(ctx, ev)
+	thumbnailURL := getThumbnailURL(ev.Channel)
+	message, keyboard, ok := n.applyHook(ctx, ev, n.style.Start(ev.Info, n.format), n.keyboard(watchURL(cfg, ev.Info), ev.Info.Game, ev.Info.Squad))
+	if !ok || !n.approval.Approved(ev, thumbnailURL, message) {
+		return
+	}
+
+	threadID := n.threadFor(ctx, ev)
+
+	var messageID int
+	bot, err := n.bots.Send(ctx, cfg.HTTP.Telegram.Retry, "send start notification", func(token string) error {
+		var sendErr error
+		messageID, sendErr = sendPhotoMessage(
+			token, *cfg.Telegram.ChatID, threadID,
+			thumbnailURL, message, keyboard, sendOptionsFor(cfg, ev.Info),
+		)
+		return sendErr
+	})
+	n.access.Report(err)
+	if err != nil && ctx.Err() == nil && n.access.Allowed() {
+		notifyAdmin(cfg, fmt.Sprintf("Failed to send start notification for <b>%s</b>: %s", escapeHTML(ev.Channel), escapeHTML(err.Error())))
+	}
+
+	if messageID != 0 {
+		slog.Info("start notification sent")
+		ev.Session.MessageID = messageID
+		ev.Session.Bot = bot
+		n.updateCounter = 0
+		n.setLastThumbnail(ev.Time)
+		rememberAnnouncement(n.cfg, ev)
+		n.setTopicStatus(ev, true)
+		if cfg.Telegram.Story.Enabled {
+			n.postStory(ctx, ev)
+		}
+	}
+}
+
+func (n *TelegramNotifier) sendUpdate(ctx context.Context, ev Event) {
+	cfg := n.cfg
+	session := ev.Session
+	if !n.access.Allowed() {
+		slog.Info("no access to the chat, stream info update postponed")
+		return
+	}
+	slog.Info("updating stream info", "viewers", ev.Info.Viewers, "uptime", ev.Info.Uptime)
+
+	n.resolveMature(ctx, ev)
+	resolvePartners(ctx, cfg, ev.Info)
+	n.resolveSquad(ctx, ev)
+	avgViewers := calculateAverage(session.ViewerHistory)
+	thumbnailURL := getThumbnailURL(ev.Channel)
+	note, onBreak := n.caption.Get(session.StartTime)
+
+	clips := recentClips(ctx, cfg, ev)
+	clips = n.shortener.ShortenClips(ctx, cfg.Telegram.ClipFilter.Apply(clips))
+	message, keyboard, ok := n.applyHook(ctx, ev, n.style.Live(LiveSummary{
+		Info:       ev.Info,
+		AvgViewers: avgViewers,
+		History:    session.ViewerHistory,
+		Clips:      clips,
+		Goals:      fetchGoals(ctx, n.goals),
+		Note:       note,
+		OnBreak:    onBreak,
+	}, n.format), n.keyboard(watchURL(cfg, ev.Info), ev.Info.Game, ev.Info.Squad))
+	if !ok {
+		return
+	}
+
+	n.mu.Lock()
+	refreshThumbnail := ev.Time.Sub(n.lastThumbnail) >= time.Duration(cfg.ThumbnailUpdateInterval)*time.Minute
+	n.mu.Unlock()
+	token := n.bots.Token(session.Bot)
+	err := retryWithBackoff(ctx, cfg.HTTP.Telegram.Retry, func() error {
+		if !refreshThumbnail {
+			return n.bots.guard(editMessageCaption(
+				token, *cfg.Telegram.ChatID, session.MessageID,
+				message, keyboard,
+			))
+		}
+		return n.bots.guard(editPhotoMessage(
+			token, *cfg.Telegram.ChatID, session.MessageID,
+			thumbnailURL, message, keyboard, sendOptionsFor(cfg, ev.Info),
+		))
+	}, "update stream info")
+	if err == nil && refreshThumbnail {
+		n.setLastThumbnail(ev.Time)
+	}
+	if reason := n.repostReason(session, err); reason != "" {
+		slog.Warn(reason+", posting a new one", "message_id", session.MessageID)
+		err = n.repost(ctx, ev, message, keyboard)
+	}
+	n.access.Report(err)
+	if err == nil {
+		slog.Info("stream info updated")
+	} else if ctx.Err() == nil && n.access.Allowed() {
+		notifyAdmin(cfg, fmt.Sprintf("Failed to update stream info for <b>%s</b>: %s", escapeHTML(ev.Channel), escapeHTML(err.Error())))
+	}
+}
+
+func (n *TelegramNotifier) sendEnd(ctx context.Context, ev Event) {
+	cfg := n.cfg
+	session := ev.Session
+	n.mu.Lock()
+	n.squad.logins = nil
+	n.mu.Unlock()
+
+	peak := getMaxViewers(session.ViewerHistory)
+	retention, _ := calculateRetention(session.ViewerHistory, session.StartTime)
+	clips := recentClips(ctx, cfg, ev)
+	clips = n.shortener.ShortenClips(ctx, cfg.Telegram.ClipFilter.Apply(clips))
+
+	sum := EndSummary{
+		Channel:    ev.Channel,
+		Duration:   formatDuration(ev.Time.Sub(session.StartTime), cfg.Language),
+		AvgViewers: calculateAverage(session.ViewerHistory),
+		MaxViewers: peak.Count,
+		PeakAt:     formatDuration(peak.Timestamp.Sub(session.StartTime), cfg.Language),
+		Retention:  retention,
+		Watched:    hoursWatched(session.ViewerHistory),
+		Baseline:   baselineEntry(session, calculateAverage(session.ViewerHistory), cfg.Baseline.Factor),
+		Game:       session.Game,
+		Title:      session.Title,
+		Tags:       session.Tags,
+		Titles:     titleEntries(session, cfg.Language),
+		Clips:      clips,
+		History:    session.ViewerHistory,
+		Marks:      markEntries(session.Marks, session.StartTime, cfg.Language),
+		Highlights: highlightEntries(session.Highlights, session.StartTime, cfg.Language),
+	}
+
+	post, comment := n.discussions.Lookup(session.MessageID)
+	var message string
+	if comment {
+		compact := sum
+		compact.Retention = 0
+		compact.Titles = nil
+		compact.Marks = nil
+		compact.Highlights = nil
+		compact.Clips = nil
+		message = n.style.End(compact, n.format)
+	} else {
+		message = n.style.End(sum, n.format)
+	}
+	streamURL := fmt.Sprintf("https://twitch.tv/%s", ev.Channel)
+	message, keyboard, ok := n.applyHook(ctx, ev, message, n.keyboard(streamURL, session.Game, nil))
+
+	if ok && !n.access.Allowed() {
+		slog.Warn("no access to the chat, end notification skipped")
+	} else if ok {
+		token := n.bots.Token(session.Bot)
+		err := retryWithBackoff(ctx, cfg.HTTP.Telegram.Retry, func() error {
+			return n.bots.guard(editMessageCaption(
+				token, *cfg.Telegram.ChatID, session.MessageID,
+				message, keyboard,
+			))
+		}, "send end notification")
+		if reason := n.repostReason(session, err); reason != "" {
+			slog.Warn(reason+", posting a new one", "message_id", session.MessageID)
+			err = n.repost(ctx, ev, message, keyboard)
+		}
+		n.access.Report(err)
+		if err == nil {
+			slog.Info("end notification sent")
+		} else if ctx.Err() == nil && n.access.Allowed() {
+			notifyAdmin(cfg, fmt.Sprintf("Failed to send end notification for <b>%s</b>: %s", escapeHTML(ev.Channel), escapeHTML(err.Error())))
+		}
+	}
+
+	if comment {
+		if err := sendReply(cfg.Telegram.BotToken, post.chatID, post.messageID, formatEndDetails(sum, n.format)); err != nil {
+			slog.Warn("failed to post stats comment", "error", err)
+		} else {
+			slog.Info("stats comment posted", "chat_id", post.chatID)
+		}
+		n.discussions.Forget(session.MessageID)
+	}
+
+	if cfg.Telegram.PaidClip.Enabled {
+		n.sendPaidClip(ctx, ev, clips)
+	}
+
+	n.setTopicStatus(ev, false)
+	if session.ThreadID != nil && cfg.Telegram.ForumTopics.CloseOnEnd {
+		if err := closeForumTopic(cfg.Telegram.BotToken, *cfg.Telegram.ChatID, *session.ThreadID); err != nil {
+			slog.Warn("failed to close forum topic", "error", err)
+		}
+	}
+}
+
+func titleEntries(session *StreamSession, lang string) []TitleEntry {
+	entries := make([]TitleEntry, 0, len(session.TitleHistory))
+	for _, t := range session.TitleHistory {
+		entries = append(entries, TitleEntry{
+			At:      formatDuration(t.Timestamp.Sub(session.StartTime), lang),
+			Title:   t.Title,
+			Viewers: t.Viewers,
+		})
+	}
+	return entries
+}
+
+func (n *TelegramNotifier) keyboard(streamURL, game string, squad []string) [][]InlineButton {
+	kb := watchKeyboard(n.format.ButtonText, trackedURL(n.cfg.LinkTracking, streamURL))
+	if n.cfg.Squad.Buttons {
+		kb = append(kb, squadKeyboard(squad)...)
+	}
+	if n.steam != nil {
+		if storeURL := n.steam.StoreURL(game); storeURL != "" {
+			kb = append(kb, []InlineButton{{Text: "Steam: " + game, URL: storeURL}})
+		}
+	}
+	return kb
+}
+
+func (n *TelegramNotifier) threadFor(ctx context.Context, ev Event) *int {
+	if n.cfg.Telegram.ForumTopics.Enabled {
+		return n.ensureTopic(ctx, ev)
+	}
+	return n.cfg.Telegram.ThreadID
+}
+
+func (n *TelegramNotifier) repost(ctx context.Context, ev Event, caption string, keyboard [][]InlineButton) error {
+	cfg := n.cfg
+	threadID := n.threadFor(ctx, ev)
+	thumbnailURL := getThumbnailURL(ev.Channel)
+
+	var messageID int
+	bot, err := n.bots.Send(ctx, cfg.HTTP.Telegram.Retry, "repost stream message", func(token string) error {
+		var sendErr error
+		messageID, sendErr = sendPhotoMessage(
+			token, *cfg.Telegram.ChatID, threadID,
+			thumbnailURL, caption, keyboard, sendOptionsFor(cfg, ev.Info),
+		)
+		return sendErr
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("stream message reposted", "old_message_id", ev.Session.MessageID, "message_id", messageID, "bot", bot)
+	ev.Session.MessageID = messageID
+	ev.Session.Bot = bot
+	n.setLastThumbnail(ev.Time)
+	rememberAnnouncement(n.cfg, ev)
+	return nil
+}
+
+func (n *TelegramNotifier) setLastThumbnail(t time.Time) {
+	n.mu.Lock()
+	n.lastThumbnail = t
+	n.mu.Unlock()
+}
+
+func (n *TelegramNotifier) repostReason(session *StreamSession, err error) string {
+	if n.bots.Failover(session.Bot, err) {
+		return "bot that posted the live message is unavailable"
+	}
+	var tgErr *TelegramError
+	if !errors.As(err, &tgErr) {
+		return ""
+	}
+	switch {
+	case tgErr.EditExpired():
+		return "live message can no longer be edited"
+	case tgErr.MessageNotFound():
+		return "live message was deleted"
+	}
+	return ""
+}
+
+func (n *TelegramNotifier) ensureTopic(ctx context.Context, ev Event) *int {
+	cfg := n.cfg
+	if ev.Session.ThreadID != nil {
+		return ev.Session.ThreadID
+	}
+
+	name := topicName(cfg, ev.Session.StartTime, ev.Info.Title, true)
+	var threadID int
+	err := retryWithBackoff(ctx, cfg.HTTP.Telegram.Retry, func() error {
+		var createErr error
+		threadID, createErr = createForumTopic(cfg.Telegram.BotToken, *cfg.Telegram.ChatID, name)
+		return createErr
+	}, "create forum topic")
+	if err != nil {
+		slog.Error("failed to create forum topic, using default thread", "error", err)
+		return cfg.Telegram.ThreadID
+	}
+
+	slog.Info("forum topic created", "thread_id", threadID, "name", name)
+	ev.Session.ThreadID = &threadID
+	return ev.Session.ThreadID
+}
+
+func topicName(cfg *Config, start time.Time, title string, live bool) string {
+	name := formatTopicName(start, title)
+	if !cfg.Telegram.ForumTopics.StatusPrefix {
+		return name
+	}
+	if live {
+		return "🔴 " + name
+	}
+	return "⚫ " + name
+}
+
+func (n *TelegramNotifier) setTopicStatus(ev Event, live bool) {
+	cfg := n.cfg
+	ft := cfg.Telegram.ForumTopics
+	icon := ft.OfflineIcon
+	if live {
+		icon = ft.LiveIcon
+	}
+
+	threadID := ev.Session.ThreadID
+	var name string
+	if threadID != nil {
+		if !live && ft.StatusPrefix {
+			name = topicName(cfg, ev.Session.StartTime, ev.Session.Title, false)
+		}
+	} else {
+		threadID = cfg.Telegram.ThreadID
+	}
+	if threadID == nil || (name == "" && icon == "") {
+		return
+	}
+	if err := editForumTopic(cfg.Telegram.BotToken, *cfg.Telegram.ChatID, *threadID, name, icon); err != nil {
+		slog.Warn("failed to update forum topic status", "error", err)
+	}
+}
+
+func (n *TelegramNotifier) resolveMature(ctx context.Context, ev Event) {
+	cfg := n.cfg
+	if !cfg.Mature.Enabled || ev.Info.Mature || upstream != nil {
+		return
+	}
+	labels, err := getContentLabels(ctx, ev.Session.BroadcasterID, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+	if err != nil {
+		slog.Warn("failed to get content classification labels", "error", err)
+		return
+	}
+	ev.Info.Mature = len(labels) > 0
+}
+
+func recentClips(ctx context.Context, cfg *Config, ev Event) []ClipInfo {
+	if upstream != nil {
+		return upstream.Clips(ev.Channel)
+	}
+	clips, _ := getRecentClips(ctx, ev.Session.BroadcasterID, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret, ev.Session.StartTime)
+	return clips
+}
+
+func sendOptionsFor(cfg *Config, info *StreamInfo) SendOptions {
+	mature := info != nil && info.Mature && cfg.Mature.Enabled
+	return SendOptions{
+		Spoiler:        cfg.Telegram.Spoiler || (mature && cfg.Mature.Spoiler),
+		ProtectContent: cfg.Telegram.ProtectContent,
+	}
+}
+
+func logStreamStats(ctx context.Context, ev Event) {
+	if ev.Type != EventStreamEnded {
+		return
+	}
+	peak := getMaxViewers(ev.Session.ViewerHistory)
+	slog.Info("stream stats",
+		"duration", ev.Time.Sub(ev.Session.StartTime).Round(time.Second),
+		"avg_viewers", calculateAverage(ev.Session.ViewerHistory),
+		"max_viewers", peak.Count,
+		"peak_at", peak.Timestamp.Sub(ev.Session.StartTime).Round(time.Second),
+	)
+}
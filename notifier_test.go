@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotifierQueueStartUpdateEnd(t *testing.T) {
+	helix := newFakeHelix(t)
+	bot := newFakeBotAPI(t)
+	start := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	useManualClock(t, start)
+
+	cfg := loadTestConfig(t, strings.Replace(testConfigJSON, `"language": "en",`, `"language": "en", "squad": {"enabled": true},`, 1))
+	helix.AddUser("42", "somechannel")
+	helix.AddUser("43", "friend")
+	helix.SetSharedChat("42", "42", "43")
+
+	n := newTelegramNotifier(cfg, nil, nil, nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for stream := range 3 {
+		bot.SetEditDelay(0)
+		startTime := start.Add(time.Duration(stream) * time.Hour)
+		info := &StreamInfo{Channel: "somechannel", Title: "Title", Game: "Just Chatting", Viewers: 10, StartedAt: startTime}
+		session := &StreamSession{StartTime: startTime, BroadcasterID: "42", ViewerHistory: []ViewerDataPoint{{Timestamp: startTime, Count: 10}}}
+
+		n.Handle(ctx, Event{Type: EventStreamStarted, Time: startTime, Channel: "somechannel", Info: info, Session: session})
+		sent := bot.WaitFor(t, stream+1, "sendPhoto")[stream]
+		if !strings.Contains(sent.Params["caption"], "friend") {
+			t.Fatalf("squad is missing from the announcement: %q", sent.Params["caption"])
+		}
+
+		bot.SetEditDelay(time.Second)
+		edits := len(bot.Calls("editMessageCaption", "editMessageMedia"))
+		for i := range n.checksPerUpdate {
+			at := startTime.Add(time.Duration(i+1) * 20 * time.Minute)
+			n.Handle(ctx, Event{Type: EventStreamUpdated, Time: at, Channel: "somechannel", Info: info, Session: session})
+		}
+		if stream == 1 {
+			continue
+		}
+		bot.WaitFor(t, edits+1, "editMessageCaption", "editMessageMedia")
+		bot.SetEditDelay(0)
+
+		n.Handle(ctx, Event{Type: EventStreamEnded, Time: startTime.Add(50 * time.Minute), Channel: "somechannel", Session: session})
+		calls := bot.Calls("editMessageCaption", "editMessageMedia")
+		if end := calls[len(calls)-1]; !strings.Contains(end.Params["caption"], "OFFLINE") {
+			t.Fatalf("unexpected end message %v", end.Params)
+		}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.squad.logins != nil {
+		t.Fatalf("squad was not reset after the stream: %v", n.squad.logins)
+	}
+}
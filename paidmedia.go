@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+type PaidClipConfig struct {
+	Enabled bool `json:"enabled"`
+	Stars   int  `json:"stars"`
+}
+
+func topClip(clips []ClipInfo) (ClipInfo, bool) {
+	if len(clips) == 0 {
+		return ClipInfo{}, false
+	}
+	top := clips[0]
+	for _, c := range clips[1:] {
+		if c.Views > top.Views {
+			top = c
+		}
+	}
+	return top, true
+}
+
+func clipVideoURL(c ClipInfo) string {
+	i := strings.Index(c.ThumbnailURL, "-preview-")
+	if i < 0 {
+		return ""
+	}
+	return c.ThumbnailURL[:i] + ".mp4"
+}
+
+func (n *TelegramNotifier) sendPaidClip(ctx context.Context, ev Event, clips []ClipInfo) {
+	cfg := n.cfg
+	clip, ok := topClip(clips)
+	if !ok {
+		return
+	}
+	videoURL := clipVideoURL(clip)
+	if videoURL == "" {
+		slog.Warn("cannot derive video URL for paid clip", "clip", clip.URL)
+		return
+	}
+
+	caption := fmt.Sprintf("🎬 <b>%s</b> • %s", escapeHTML(ev.Channel), escapeHTML(clip.Title))
+	err := retryWithBackoff(ctx, cfg.HTTP.Telegram.Retry, func() error {
+		_, sendErr := sendPaidMedia(cfg.Telegram.BotToken, *cfg.Telegram.ChatID, n.threadFor(ctx, ev), cfg.Telegram.PaidClip.Stars, "video", videoURL, caption)
+		return sendErr
+	}, "send paid clip")
+	if err != nil {
+		slog.Error("failed to send paid clip", "clip", clip.URL, "error", err)
+		return
+	}
+	slog.Info("paid clip sent", "clip", clip.URL, "stars", cfg.Telegram.PaidClip.Stars)
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+const (
+	pluginHostModule  = "twitch2tg"
+	pluginMemoryPages = 1024
+)
+
+type PluginConfig struct {
+	Dir      string                       `json:"dir"`
+	Timeout  int                          `json:"timeout_seconds"`
+	Settings map[string]map[string]string `json:"settings"`
+}
+
+type Plugin struct {
+	Name    string
+	Command []string
+	wasm    wazero.CompiledModule
+}
+
+type PluginInput struct {
+	Version int          `json:"version"`
+	Event   EventPayload `json:"event"`
+}
+
+type PluginNotifier struct {
+	cfg     PluginConfig
+	plugins []Plugin
+	runtime wazero.Runtime
+	events  chan EventPayload
+}
+
+type pluginCall struct {
+	plugin   string
+	input    []byte
+	settings map[string]string
+}
+
+type pluginCallKey struct{}
+
+func loadPlugins(ctx context.Context, cfg PluginConfig) (*PluginNotifier, error) {
+	entries, err := os.ReadDir(cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugins directory: %w", err)
+	}
+
+	n := &PluginNotifier{cfg: cfg, events: make(chan EventPayload, 64)}
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path, err := filepath.Abs(filepath.Join(cfg.Dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if filepath.Ext(path) == ".wasm" {
+			if n.runtime == nil {
+				if n.runtime, err = newPluginRuntime(ctx); err != nil {
+					return nil, err
+				}
+			}
+			code, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			compiled, err := n.runtime.CompileModule(ctx, code)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compile plugin %s: %w", e.Name(), err)
+			}
+			n.plugins = append(n.plugins, Plugin{Name: e.Name(), wasm: compiled})
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode()&0111 == 0 {
+			continue
+		}
+		n.plugins = append(n.plugins, Plugin{Name: e.Name(), Command: []string{path}})
+	}
+	return n, nil
+}
+
+func newPluginRuntime(ctx context.Context) (wazero.Runtime, error) {
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(pluginMemoryPages))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+	_, err := r.NewHostModuleBuilder(pluginHostModule).
+		NewFunctionBuilder().WithFunc(pluginEventLen).Export("event_len").
+		NewFunctionBuilder().WithFunc(pluginEventRead).Export("event_read").
+		NewFunctionBuilder().WithFunc(pluginLog).Export("log").
+		NewFunctionBuilder().WithFunc(pluginSetting).Export("setting").
+		NewFunctionBuilder().WithFunc(pluginHTTPPost).Export("http_post").
+		Instantiate(ctx)
+	if err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+	return r, nil
+}
+
+func (n *PluginNotifier) Handle(ctx context.Context, ev Event) {
+	select {
+	case n.events <- newEventPayload(ev):
+	default:
+		slog.Warn("plugin queue is full, dropping event", "event", ev.Type)
+	}
+}
+
+func (n *PluginNotifier) Run(ctx context.Context) {
+	if n.runtime != nil {
+		defer n.runtime.Close(context.Background())
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-n.events:
+			for _, p := range n.plugins {
+				if err := n.run(ctx, p, ev); err != nil && ctx.Err() == nil {
+					slog.Error("plugin failed", "plugin", p.Name, "event", ev.Type, "error", err)
+				}
+			}
+		}
+	}
+}
+
+func (n *PluginNotifier) run(ctx context.Context, p Plugin, ev EventPayload) error {
+	timeout := time.Duration(n.cfg.Timeout) * time.Second
+	input := PluginInput{Version: 1, Event: ev}
+	if p.wasm == nil {
+		return runJSONCommand(ctx, p.Command, timeout, input, nil)
+	}
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, pluginCallKey{}, &pluginCall{plugin: p.Name, input: data, settings: n.cfg.Settings[p.Name]})
+
+	var stderr bytes.Buffer
+	config := wazero.NewModuleConfig().WithName("").WithStderr(&stderr)
+	_, reactor := p.wasm.ExportedFunctions()["on_event"]
+	if reactor {
+		config = config.WithStartFunctions("_initialize")
+	} else {
+		config = config.WithStdin(bytes.NewReader(data))
+	}
+	mod, err := n.runtime.InstantiateModule(ctx, p.wasm, config)
+	if err != nil {
+		return wasmError(err, &stderr)
+	}
+	defer mod.Close(context.Background())
+	if !reactor {
+		return nil
+	}
+
+	results, err := mod.ExportedFunction("on_event").Call(ctx)
+	if err != nil {
+		return wasmError(err, &stderr)
+	}
+	if len(results) > 0 && api.DecodeI32(results[0]) != 0 {
+		return wasmError(fmt.Errorf("on_event returned %d", api.DecodeI32(results[0])), &stderr)
+	}
+	return nil
+}
+
+func wasmError(err error, stderr *bytes.Buffer) error {
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return fmt.Errorf("%w: %s", err, msg)
+	}
+	return err
+}
+
+func currentPluginCall(ctx context.Context) *pluginCall {
+	call, _ := ctx.Value(pluginCallKey{}).(*pluginCall)
+	if call == nil {
+		return &pluginCall{}
+	}
+	return call
+}
+
+func readGuestString(m api.Module, ptr, size uint32) (string, bool) {
+	data, ok := m.Memory().Read(ptr, size)
+	return string(data), ok
+}
+
+func pluginEventLen(ctx context.Context) uint32 {
+	return uint32(len(currentPluginCall(ctx).input))
+}
+
+func pluginEventRead(ctx context.Context, m api.Module, ptr uint32) {
+	if !m.Memory().Write(ptr, currentPluginCall(ctx).input) {
+		panic("event_read: buffer is out of memory bounds")
+	}
+}
+
+func pluginLog(ctx context.Context, m api.Module, level, ptr, size uint32) {
+	msg, ok := readGuestString(m, ptr, size)
+	if !ok {
+		panic("log: message is out of memory bounds")
+	}
+	plugin := currentPluginCall(ctx).plugin
+	switch level {
+	case 0:
+		slog.Info(msg, "plugin", plugin)
+	case 1:
+		slog.Warn(msg, "plugin", plugin)
+	default:
+		slog.Error(msg, "plugin", plugin)
+	}
+}
+
+func pluginSetting(ctx context.Context, m api.Module, keyPtr, keySize, bufPtr, bufSize uint32) int32 {
+	key, ok := readGuestString(m, keyPtr, keySize)
+	if !ok {
+		panic("setting: key is out of memory bounds")
+	}
+	value, found := currentPluginCall(ctx).settings[key]
+	if !found {
+		return -1
+	}
+	if int(bufSize) >= len(value) && !m.Memory().WriteString(bufPtr, value) {
+		panic("setting: buffer is out of memory bounds")
+	}
+	return int32(len(value))
+}
+
+func pluginHTTPPost(ctx context.Context, m api.Module, urlPtr, urlSize, typePtr, typeSize, bodyPtr, bodySize uint32) int32 {
+	url, ok1 := readGuestString(m, urlPtr, urlSize)
+	contentType, ok2 := readGuestString(m, typePtr, typeSize)
+	body, ok3 := m.Memory().Read(bodyPtr, bodySize)
+	if !ok1 || !ok2 || !ok3 {
+		panic("http_post: arguments are out of memory bounds")
+	}
+	plugin := currentPluginCall(ctx).plugin
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bytes.Clone(body)))
+	if err != nil {
+		slog.Warn("plugin request failed", "plugin", plugin, "error", err)
+		return -1
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := externalHTTP.Do(req)
+	if err != nil {
+		slog.Warn("plugin request failed", "plugin", plugin, "error", err)
+		return -1
+	}
+	resp.Body.Close()
+	return int32(resp.StatusCode)
+}
+
+func runJSONCommand(ctx context.Context, command []string, timeout time.Duration, input, output any) error {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("%s failed: %w: %s", command[0], err, strings.TrimSpace(stderr.String()))
+	}
+	if output == nil || len(bytes.TrimSpace(out)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(out, output); err != nil {
+		return fmt.Errorf("%s returned invalid JSON: %w", command[0], err)
+	}
+	return nil
+}
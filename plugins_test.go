@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func buildWasmPlugin(t *testing.T, src, dir string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("building a wasm plugin is slow")
+	}
+	cmd := exec.Command("go", "build", "-buildmode=c-shared", "-o", filepath.Join(dir, filepath.Base(src)+".wasm"), "./"+src)
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to build %s: %v\n%s", src, err, out)
+	}
+}
+
+func TestWasmPlugin(t *testing.T) {
+	dir := t.TempDir()
+	buildWasmPlugin(t, "testdata/plugins/webhook", dir)
+
+	received := make(chan PluginInput, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in PluginInput
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &in); err != nil || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- in
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	cfg := PluginConfig{Dir: dir, Timeout: 10, Settings: map[string]map[string]string{"webhook.wasm": {"url": srv.URL}}}
+	n, err := loadPlugins(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.runtime.Close(ctx)
+	if len(n.plugins) != 1 || n.plugins[0].Name != "webhook.wasm" {
+		t.Fatalf("unexpected plugins %v", n.plugins)
+	}
+
+	ev := EventPayload{Type: EventStreamStarted, Time: time.Unix(1700000000, 0).UTC(), Channel: "somechannel", Game: "Celeste"}
+	if err := n.run(ctx, n.plugins[0], ev); err != nil {
+		t.Fatal(err)
+	}
+	in := <-received
+	if in.Version != 1 || in.Event.Type != EventStreamStarted || in.Event.Game != "Celeste" {
+		t.Fatalf("unexpected payload %+v", in)
+	}
+
+	n.cfg.Settings = nil
+	if err := n.run(ctx, n.plugins[0], ev); err == nil {
+		t.Fatal("expected an error without the url setting")
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type RenderedMessage struct {
+	Style string
+	Stage string
+	Text  string
+}
+
+func sampleStream(cfg *Config, now time.Time) (*StreamInfo, []ViewerDataPoint, []ClipInfo) {
+	info := &StreamInfo{
+		Channel:   cfg.Twitch.Channel,
+		URL:       fmt.Sprintf("https://twitch.tv/%s", cfg.Twitch.Channel),
+		Title:     "Sample stream title",
+		Game:      "Just Chatting",
+		Viewers:   142,
+		Uptime:    formatDuration(2*time.Hour+15*time.Minute, cfg.Language),
+		Tags:      []string{"English", "Chill"},
+		StartedAt: now.Add(-2*time.Hour - 15*time.Minute),
+	}
+	var history []ViewerDataPoint
+	for i := 0; i <= 27; i++ {
+		history = append(history, ViewerDataPoint{
+			Timestamp: info.StartedAt.Add(time.Duration(i) * 5 * time.Minute),
+			Count:     60 + i*3 + (i%4)*5,
+		})
+	}
+	clips := []ClipInfo{
+		{URL: "https://clips.twitch.tv/sample1", Title: "Best moment", Creator: "viewer1", Views: 40},
+		{URL: "https://clips.twitch.tv/sample2", Title: "So close", Creator: "viewer2", Views: 12},
+	}
+	return info, history, clips
+}
+
+func previewFormats(ctx context.Context, cfg *Config, live bool, chatID int64) error {
+	info, history, clips := sampleStream(cfg, time.Now())
+	if live {
+		liveInfo, err := getStreamInfo(ctx, cfg.Twitch.Channel, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret, cfg.Language)
+		if err != nil {
+			return err
+		}
+		if liveInfo == nil {
+			fmt.Printf("%s is offline, using sample data\n\n", cfg.Twitch.Channel)
+		} else {
+			info = liveInfo
+			history = []ViewerDataPoint{{Timestamp: time.Now(), Count: info.Viewers}}
+			if id, err := getBroadcasterID(ctx, cfg.Twitch.Channel, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret); err == nil {
+				clips, _ = getRecentClips(ctx, id, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret, info.StartedAt)
+			}
+		}
+	}
+
+	for _, msg := range renderStyles(cfg, info, history, clips) {
+		fmt.Printf("=== %s / %s (%d chars) ===\n%s\n\n", msg.Style, msg.Stage, len([]rune(msg.Text)), msg.Text)
+		if chatID != 0 {
+			header := fmt.Sprintf("<b>[%s / %s]</b>\n\n", msg.Style, msg.Stage)
+			if err := sendTextMessage(cfg.Telegram.BotToken, chatID, nil, header+msg.Text); err != nil {
+				return fmt.Errorf("failed to send %s/%s preview: %w", msg.Style, msg.Stage, err)
+			}
+		}
+	}
+	return nil
+}
+
+func renderStyles(cfg *Config, info *StreamInfo, history []ViewerDataPoint, clips []ClipInfo) []RenderedMessage {
+	mf := newMessageFormat(cfg)
+	peak := getMaxViewers(history)
+	retention, _ := calculateRetention(history, info.StartedAt)
+	liveSum := LiveSummary{Info: info, AvgViewers: calculateAverage(history), History: history, Clips: clips}
+	end := EndSummary{
+		Channel:    info.Channel,
+		Duration:   info.Uptime,
+		AvgViewers: calculateAverage(history),
+		MaxViewers: peak.Count,
+		PeakAt:     formatDuration(peak.Timestamp.Sub(info.StartedAt), cfg.Language),
+		Retention:  retention,
+		Watched:    hoursWatched(history),
+		Game:       info.Game,
+		Title:      info.Title,
+		Tags:       info.Tags,
+		Clips:      clips,
+		History:    history,
+	}
+
+	var out []RenderedMessage
+	for _, name := range styleNames() {
+		style := messageStyles[name]
+		out = append(out,
+			RenderedMessage{Style: name, Stage: "start", Text: style.Start(info, mf)},
+			RenderedMessage{Style: name, Stage: "live", Text: style.Live(liveSum, mf)},
+			RenderedMessage{Style: name, Stage: "end", Text: style.End(end, mf)},
+		)
+	}
+	return out
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	quietCheckInterval = time.Hour
+	quietClipDays      = 30
+	quietMaxSegments   = 3
+)
+
+type QuietConfig struct {
+	Enabled    bool `json:"enabled"`
+	Days       int  `json:"days"`
+	RepeatDays int  `json:"repeat_days"`
+}
+
+type QuietPoster struct {
+	cfg     *Config
+	format  MessageFormat
+	archive *SessionArchive
+
+	mu   sync.Mutex
+	live bool
+}
+
+func newQuietPoster(cfg *Config, archive *SessionArchive) *QuietPoster {
+	return &QuietPoster{cfg: cfg, format: newMessageFormat(cfg), archive: archive}
+}
+
+func (q *QuietPoster) Run(ctx context.Context) {
+	for {
+		q.tick(ctx, clock.Now())
+		sleep(ctx, quietCheckInterval)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (q *QuietPoster) Handle(ctx context.Context, ev Event) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	switch ev.Type {
+	case EventStreamStarted, EventStreamUpdated:
+		q.live = true
+	case EventStreamEnded:
+		q.live = false
+	}
+}
+
+func (q *QuietPoster) tick(ctx context.Context, now time.Time) {
+	q.mu.Lock()
+	live := q.live
+	q.mu.Unlock()
+	if live || stateStore == nil {
+		return
+	}
+
+	records, err := q.archive.Load(ctx)
+	if err != nil {
+		slog.Warn("failed to load sessions for quiet channel post", "error", err)
+		return
+	}
+	if len(records) == 0 {
+		return
+	}
+	lastStream := records[len(records)-1].EndTime
+	var lastPost time.Time
+	stateStore.View(func(st *State) { lastPost = st.QuietPostedAt })
+	if now.Sub(lastStream) < time.Duration(q.cfg.Quiet.Days)*24*time.Hour {
+		return
+	}
+	if lastPost.After(lastStream) && now.Sub(lastPost) < time.Duration(q.cfg.Quiet.RepeatDays)*24*time.Hour {
+		return
+	}
+
+	if err := q.post(ctx, now, lastStream); err != nil {
+		slog.Error("failed to send quiet channel post", "error", err)
+		return
+	}
+	if err := stateStore.Update(func(st *State) { st.QuietPostedAt = now }); err != nil {
+		slog.Warn("failed to save quiet channel post time", "error", err)
+	}
+}
+
+func (q *QuietPoster) post(ctx context.Context, now, lastStream time.Time) error {
+	cfg := q.cfg
+	user, err := getTwitchUser(ctx, cfg.Twitch.Channel, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+	if err != nil {
+		return fmt.Errorf("failed to get channel info: %w", err)
+	}
+
+	segments, err := getSchedule(ctx, user.ID, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+	if err != nil {
+		slog.Warn("failed to get schedule for quiet channel post", "error", err)
+	}
+	clips, err := getClipsBetween(ctx, user.ID, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret, now.AddDate(0, 0, -quietClipDays), now)
+	if err != nil {
+		slog.Warn("failed to get clips for quiet channel post", "error", err)
+	}
+	var best *ClipInfo
+	for i := range clips {
+		if best == nil || clips[i].Views > best.Views {
+			best = &clips[i]
+		}
+	}
+
+	imageURL := user.OfflineImageURL
+	if best != nil && best.ThumbnailURL != "" {
+		imageURL = best.ThumbnailURL
+	}
+	if imageURL == "" {
+		imageURL = user.ProfileImageURL
+	}
+
+	days := int(now.Sub(lastStream).Hours() / 24)
+	message := formatQuietMessage(cfg.Twitch.Channel, days, formatScheduleLines(segments, now, quietMaxSegments, q.format), best, q.format)
+	streamURL := trackedURL(cfg.LinkTracking, fmt.Sprintf("https://twitch.tv/%s", cfg.Twitch.Channel))
+	if _, err := sendPhotoMessage(
+		cfg.Telegram.BotToken, *cfg.Telegram.ChatID, cfg.Telegram.ThreadID,
+		imageURL, message, watchKeyboard(q.format.ButtonText, streamURL), sendOptionsFor(cfg, nil),
+	); err != nil {
+		return err
+	}
+	slog.Info("quiet channel post sent", "days_since_stream", days)
+	return nil
+}
+
+func formatScheduleLines(segments []TwitchScheduleSegment, now time.Time, limit int, mf MessageFormat) []string {
+	var lines []string
+	for _, seg := range segments {
+		if limit > 0 && len(lines) >= limit {
+			break
+		}
+		if seg.CanceledUntil != nil || seg.StartTime.Before(now) {
+			continue
+		}
+		line := fmt.Sprintf("<b>%s</b>", seg.StartTime.Local().Format("02.01 15:04"))
+		if seg.Title != "" {
+			line += " — " + escapeHTML(seg.Title)
+		}
+		if seg.Category != nil && seg.Category.Name != "" {
+			line += " • " + formatGame(seg.Category.Name, mf)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func formatQuietMessage(channel string, days int, schedule []string, clip *ClipInfo, mf MessageFormat) string {
+	parts := []string{
+		fmt.Sprintf("<b>%s</b> • %s", escapeHTML(channel), mf.Quiet),
+		fmt.Sprintf(mf.NoStreamsFor, days),
+	}
+	if len(schedule) > 0 {
+		parts = append(parts, "📅 "+mf.Upcoming+":\n"+strings.Join(schedule, "\n"))
+	}
+	if clip != nil {
+		parts = append(parts, "🎬 "+mf.BestClip+": "+formatLink(clip.URL, clip.Title))
+	}
+	if footer := formatFooter(channel, mf); footer != "" {
+		parts = append(parts, footer)
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+var (
+	positiveReactions = []string{"👍", "❤", "❤️", "🔥", "🥰", "👏", "😁", "🤩", "🎉", "💯", "⚡", "🏆", "😍", "🤯", "🙏", "👌", "🤝", "😎"}
+	negativeReactions = []string{"👎", "💩", "🤮", "😢", "😡", "🤬", "🥱", "😴", "🤡", "😭"}
+)
+
+type ReactionTracker struct {
+	chatID int64
+
+	mu     sync.Mutex
+	counts map[int]map[string]int
+}
+
+func newReactionTracker(chatID int64) *ReactionTracker {
+	return &ReactionTracker{chatID: chatID, counts: map[int]map[string]int{}}
+}
+
+func (r *ReactionTracker) HandleUpdate(ctx context.Context, u TelegramUpdate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c := u.MessageReactionCount; c != nil && c.Chat.ID == r.chatID {
+		counts := map[string]int{}
+		for _, rc := range c.Reactions {
+			counts[rc.Type.Key()] = rc.TotalCount
+		}
+		r.counts[c.MessageID] = counts
+	}
+
+	if m := u.MessageReaction; m != nil && m.Chat.ID == r.chatID {
+		counts := r.counts[m.MessageID]
+		if counts == nil {
+			counts = map[string]int{}
+			r.counts[m.MessageID] = counts
+		}
+		for _, rt := range m.OldReaction {
+			if counts[rt.Key()] > 0 {
+				counts[rt.Key()]--
+			}
+		}
+		for _, rt := range m.NewReaction {
+			counts[rt.Key()]++
+		}
+	}
+}
+
+func (r *ReactionTracker) Take(messageID int) map[string]int {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := r.counts[messageID]
+	delete(r.counts, messageID)
+	for k, v := range counts {
+		if v == 0 {
+			delete(counts, k)
+		}
+	}
+	return counts
+}
+
+func communityRating(reactions map[string]int) float64 {
+	var positive, negative int
+	for emoji, n := range reactions {
+		switch {
+		case slices.Contains(positiveReactions, emoji):
+			positive += n
+		case slices.Contains(negativeReactions, emoji):
+			negative += n
+		}
+	}
+	if positive+negative == 0 {
+		return 0
+	}
+	return 5 * float64(positive) / float64(positive+negative)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+)
+
+type RedisConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Addr     string `json:"addr"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	DB       int    `json:"db"`
+	Channel  string `json:"channel"`
+}
+
+type EventPayload struct {
+	Type         EventType  `json:"type"`
+	Time         time.Time  `json:"time"`
+	Channel      string     `json:"channel"`
+	Broadcaster  string     `json:"broadcaster_id,omitempty"`
+	Title        string     `json:"title,omitempty"`
+	Game         string     `json:"game,omitempty"`
+	Viewers      int        `json:"viewers"`
+	Tags         []string   `json:"tags,omitempty"`
+	URL          string     `json:"url,omitempty"`
+	StartedAt    time.Time  `json:"started_at"`
+	Mature       bool       `json:"mature,omitempty"`
+	PreviousGame string     `json:"previous_game,omitempty"`
+	PreviousTags []string   `json:"previous_tags,omitempty"`
+	Clips        []ClipInfo `json:"clips,omitempty"`
+}
+
+func newEventPayload(ev Event) EventPayload {
+	p := EventPayload{
+		Type:         ev.Type,
+		Time:         ev.Time,
+		Channel:      ev.Channel,
+		PreviousGame: ev.PreviousGame,
+		PreviousTags: ev.PreviousTags,
+	}
+	if ev.Session != nil {
+		p.Broadcaster = ev.Session.BroadcasterID
+	}
+	if ev.Info != nil {
+		p.Title = ev.Info.Title
+		p.Game = ev.Info.Game
+		p.Viewers = ev.Info.Viewers
+		p.Tags = ev.Info.Tags
+		p.URL = ev.Info.URL
+		p.StartedAt = ev.Info.StartedAt
+		p.Mature = ev.Info.Mature
+	} else if ev.Session != nil {
+		p.Title = ev.Session.Title
+		p.Game = ev.Session.Game
+		p.Tags = ev.Session.Tags
+		p.StartedAt = ev.Session.StartTime
+	}
+	return p
+}
+
+type RedisPublisher struct {
+	cfg    RedisConfig
+	retry  RetryConfig
+	events chan []byte
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newRedisPublisher(cfg RedisConfig, retry RetryConfig) *RedisPublisher {
+	return &RedisPublisher{cfg: cfg, retry: retry, events: make(chan []byte, 64)}
+}
+
+func (p *RedisPublisher) Handle(ctx context.Context, ev Event) {
+	data, err := json.Marshal(newEventPayload(ev))
+	if err != nil {
+		slog.Error("failed to encode event for redis", "error", err)
+		return
+	}
+	select {
+	case p.events <- data:
+	default:
+		slog.Warn("redis publish queue is full, dropping event", "event", ev.Type)
+	}
+}
+
+func (p *RedisPublisher) Run(ctx context.Context) {
+	defer p.close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data := <-p.events:
+			err := retryWithBackoff(ctx, p.retry, func() error {
+				err := p.publish(ctx, data)
+				if err != nil {
+					p.close()
+				}
+				return err
+			}, "redis publish")
+			if err != nil && ctx.Err() == nil {
+				slog.Error("failed to publish event to redis", "channel", p.cfg.Channel, "error", err)
+			}
+		}
+	}
+}
+
+func (p *RedisPublisher) publish(ctx context.Context, data []byte) error {
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return err
+		}
+	}
+	_, err := p.command("PUBLISH", p.cfg.Channel, string(data))
+	return err
+}
+
+func (p *RedisPublisher) connect(ctx context.Context) error {
+	var d net.Dialer
+	dialCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	conn, err := d.DialContext(dialCtx, "tcp", p.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	p.conn = conn
+	p.reader = bufio.NewReader(conn)
+
+	if p.cfg.Password != "" {
+		args := []string{"AUTH", p.cfg.Password}
+		if p.cfg.Username != "" {
+			args = []string{"AUTH", p.cfg.Username, p.cfg.Password}
+		}
+		if _, err := p.command(args...); err != nil {
+			p.close()
+			return err
+		}
+	}
+	if p.cfg.DB != 0 {
+		if _, err := p.command("SELECT", strconv.Itoa(p.cfg.DB)); err != nil {
+			p.close()
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *RedisPublisher) command(args ...string) (string, error) {
+	p.conn.SetDeadline(time.Now().Add(10 * time.Second))
+	buf := fmt.Appendf(nil, "*%d\r\n", len(args))
+	for _, a := range args {
+		buf = fmt.Appendf(buf, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := p.conn.Write(buf); err != nil {
+		return "", err
+	}
+
+	line, err := p.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 3 {
+		return "", fmt.Errorf("malformed redis reply %q", line)
+	}
+	reply := line[1 : len(line)-2]
+	switch line[0] {
+	case '+', ':':
+		return reply, nil
+	case '-':
+		return "", fmt.Errorf("redis %s: %s", args[0], reply)
+	default:
+		return "", fmt.Errorf("unexpected redis reply %q", line)
+	}
+}
+
+func (p *RedisPublisher) close() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+}
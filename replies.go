@@ -0,0 +1,151 @@
+package main
+
+import "strings"
+
+type ReplyLocalization struct {
+	Lang             string
+	Offline          string
+	NoSessions       string
+	NoSchedule       string
+	CommandFailed    string
+	Paused           string
+	Resumed          string
+	UpdateQueued     string
+	CaptionUsage     string
+	CaptionSet       string
+	CaptionCleared   string
+	BreakStarted     string
+	BreakEnded       string
+	AlreadyOnBreak   string
+	NotOnBreak       string
+	GiveawayUsage    string
+	GiveawayPosted   string
+	GiveawayActive   string
+	NoGiveaway       string
+	NoEntrants       string
+	GiveawayWinner   string
+	GiveawayCanceled string
+	NoChartData      string
+	MarkUsage        string
+	MarkAdded        string
+	WatchedWeek      string
+	WatchedMonth     string
+	Descriptions     map[string]string
+}
+
+var replyLanguages = []string{"en", "ru"}
+
+func getReplyLocalization(lang string) ReplyLocalization {
+	switch lang {
+	case "en":
+		return ReplyLocalization{
+			Lang:             "en",
+			Offline:          "not streaming right now",
+			NoSessions:       "No finished streams yet",
+			NoSchedule:       "No upcoming streams",
+			CommandFailed:    "Command failed",
+			Paused:           "Monitoring paused",
+			Resumed:          "Monitoring resumed",
+			UpdateQueued:     "The announcement will be refreshed on the next check",
+			CaptionUsage:     "Reply to the live announcement with /caption text to add a note, or with /caption alone to remove it",
+			CaptionSet:       "Note added to the announcement",
+			CaptionCleared:   "Note removed from the announcement",
+			BreakStarted:     "The announcement now shows a break, send /back when the stream resumes",
+			BreakEnded:       "Break label removed",
+			AlreadyOnBreak:   "The stream is already marked as on a break",
+			NotOnBreak:       "The stream is not marked as on a break",
+			GiveawayUsage:    "/giveaway start prize — start a giveaway\n/giveaway draw — pick a winner\n/giveaway cancel — cancel it",
+			GiveawayPosted:   "Giveaway posted, send /giveaway draw to pick a winner",
+			GiveawayActive:   "A giveaway is already running",
+			NoGiveaway:       "No giveaway is running",
+			NoEntrants:       "Nobody has joined yet",
+			GiveawayWinner:   "Winner",
+			GiveawayCanceled: "Giveaway canceled",
+			NoChartData:      "Not enough data for a chart yet, try again in a few minutes",
+			MarkUsage:        "/mark text — mark the current moment of the stream, e.g. /mark boss kill",
+			MarkAdded:        "Moment marked at",
+			WatchedWeek:      "Hours watched this week",
+			WatchedMonth:     "this month",
+			Descriptions: map[string]string{
+				"status":   "Stream status",
+				"stats":    "Last stream stats",
+				"schedule": "Upcoming streams",
+				"chart":    "Viewer chart of the current stream",
+				"help":     "List commands",
+				"say":      "Send a message to Twitch chat",
+				"pause":    "Pause monitoring",
+				"resume":   "Resume monitoring",
+				"update":   "Refresh the announcement now",
+				"caption":  "Add a note to the live announcement",
+				"brb":      "Mark the stream as on a break",
+				"back":     "Remove the break label",
+				"giveaway": "Run a giveaway in the chat",
+				"mark":     "Mark a moment of the stream",
+			},
+		}
+	case "ru":
+		return ReplyLocalization{
+			Lang:             "ru",
+			Offline:          "сейчас не в эфире",
+			NoSessions:       "Завершённых трансляций пока нет",
+			NoSchedule:       "Запланированных трансляций нет",
+			CommandFailed:    "Ошибка выполнения команды",
+			Paused:           "Мониторинг приостановлен",
+			Resumed:          "Мониторинг возобновлён",
+			UpdateQueued:     "Анонс обновится при следующей проверке",
+			CaptionUsage:     "Ответьте на анонс трансляции командой /caption текст, чтобы добавить заметку, или просто /caption, чтобы убрать её",
+			CaptionSet:       "Заметка добавлена в анонс",
+			CaptionCleared:   "Заметка убрана из анонса",
+			BreakStarted:     "В анонсе отмечен перерыв, отправьте /back, когда стрим продолжится",
+			BreakEnded:       "Отметка о перерыве снята",
+			AlreadyOnBreak:   "Перерыв уже отмечен",
+			NotOnBreak:       "Перерыв не отмечен",
+			GiveawayUsage:    "/giveaway start приз — начать розыгрыш\n/giveaway draw — выбрать победителя\n/giveaway cancel — отменить",
+			GiveawayPosted:   "Розыгрыш опубликован, отправьте /giveaway draw, чтобы выбрать победителя",
+			GiveawayActive:   "Розыгрыш уже идёт",
+			NoGiveaway:       "Сейчас нет розыгрыша",
+			NoEntrants:       "Пока никто не участвует",
+			GiveawayWinner:   "Победитель",
+			GiveawayCanceled: "Розыгрыш отменён",
+			NoChartData:      "Для графика пока мало данных, попробуйте через несколько минут",
+			MarkUsage:        "/mark текст — отметить текущий момент трансляции, например /mark убили босса",
+			MarkAdded:        "Момент отмечен на",
+			WatchedWeek:      "Часов просмотра за неделю",
+			WatchedMonth:     "за месяц",
+			Descriptions: map[string]string{
+				"status":   "Статус трансляции",
+				"stats":    "Статистика последней трансляции",
+				"schedule": "Ближайшие трансляции",
+				"chart":    "График зрителей текущей трансляции",
+				"help":     "Список команд",
+				"say":      "Отправить сообщение в чат Twitch",
+				"pause":    "Приостановить мониторинг",
+				"resume":   "Возобновить мониторинг",
+				"update":   "Обновить анонс сейчас",
+				"caption":  "Добавить заметку в анонс трансляции",
+				"brb":      "Отметить перерыв в трансляции",
+				"back":     "Снять отметку о перерыве",
+				"giveaway": "Провести розыгрыш в чате",
+				"mark":     "Отметить момент трансляции",
+			},
+		}
+	default:
+		return getReplyLocalization("en")
+	}
+}
+
+func replyLanguage(cfg *Config, msg *TelegramMessage) string {
+	if msg != nil && msg.From != nil {
+		code, _, _ := strings.Cut(strings.ToLower(msg.From.LanguageCode), "-")
+		for _, l := range replyLanguages {
+			if l == code {
+				return l
+			}
+		}
+	}
+	return cfg.CommandLanguage
+}
+
+func replyLocalization(cfg *Config, msg *TelegramMessage) ReplyLocalization {
+	return getReplyLocalization(replyLanguage(cfg, msg))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+type ScreenshotConfig struct {
+	Enabled     bool            `json:"enabled"`
+	Dir         string          `json:"dir"`
+	Interval    int             `json:"interval_minutes"`
+	KeepDays    int             `json:"keep_days"`
+	MaxSessions int             `json:"max_sessions"`
+	Timelapse   TimelapseConfig `json:"timelapse"`
+}
+
+type ScreenshotArchive struct {
+	cfg      *Config
+	store    Storage
+	lastShot time.Time
+}
+
+func newScreenshotArchive(cfg *Config, store Storage) *ScreenshotArchive {
+	return &ScreenshotArchive{cfg: cfg, store: store}
+}
+
+func sessionDir(root, channel string, start time.Time) string {
+	return path.Join(root, channel, start.UTC().Format("2006-01-02_15-04-05"))
+}
+
+func (a *ScreenshotArchive) Handle(ctx context.Context, ev Event) {
+	switch ev.Type {
+	case EventStreamStarted:
+		a.lastShot = time.Time{}
+		a.cleanup(ctx, ev.Channel, ev.Time)
+	case EventStreamUpdated:
+	case EventStreamEnded:
+		if a.cfg.Screenshots.Timelapse.Enabled && ev.Session.MessageID != 0 {
+			a.sendTimelapse(ctx, ev)
+		}
+		return
+	default:
+		return
+	}
+	if ev.Time.Sub(a.lastShot) < time.Duration(a.cfg.Screenshots.Interval)*time.Minute {
+		return
+	}
+
+	data, err := downloadImage(ctx, getThumbnailURL(ev.Channel))
+	if err != nil {
+		slog.Warn("failed to download screenshot", "error", err)
+		return
+	}
+	key := path.Join(sessionDir(a.cfg.Screenshots.Dir, ev.Channel, ev.Session.StartTime), ev.Time.UTC().Format("15-04-05")+".jpg")
+	if err := a.store.Put(ctx, key, data); err != nil {
+		slog.Error("failed to save screenshot", "error", err)
+		return
+	}
+	a.lastShot = ev.Time
+}
+
+func (a *ScreenshotArchive) cleanup(ctx context.Context, channel string, now time.Time) {
+	root := path.Join(a.cfg.Screenshots.Dir, channel)
+	keys, err := a.store.List(ctx, root)
+	if err != nil {
+		slog.Warn("failed to list screenshots", "error", err)
+		return
+	}
+	var sessions []string
+	for _, key := range keys {
+		name, _, ok := strings.Cut(strings.TrimPrefix(key, root+"/"), "/")
+		if ok && !slices.Contains(sessions, name) {
+			sessions = append(sessions, name)
+		}
+	}
+	slices.Sort(sessions)
+
+	var remove []string
+	if a.cfg.Screenshots.KeepDays > 0 {
+		cutoff := now.AddDate(0, 0, -a.cfg.Screenshots.KeepDays)
+		for len(sessions) > 0 {
+			start, err := time.Parse("2006-01-02_15-04-05", sessions[0])
+			if err != nil || !start.Before(cutoff) {
+				break
+			}
+			remove = append(remove, sessions[0])
+			sessions = sessions[1:]
+		}
+	}
+	if a.cfg.Screenshots.MaxSessions > 0 && len(sessions) >= a.cfg.Screenshots.MaxSessions {
+		n := len(sessions) - a.cfg.Screenshots.MaxSessions + 1
+		remove = append(remove, sessions[:n]...)
+	}
+
+	for _, name := range remove {
+		if err := a.store.Delete(ctx, path.Join(root, name)); err != nil {
+			slog.Warn("failed to remove old screenshots", "session", name, "error", err)
+		} else {
+			slog.Info("old screenshots removed", "session", name)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+type SecretProvider interface {
+	Get(ctx context.Context, ref string) (string, error)
+}
+
+type VaultConfig struct {
+	Address   string `json:"address"`
+	Token     string `json:"token"`
+	Namespace string `json:"namespace"`
+}
+
+type secretField struct {
+	path  string
+	value *string
+}
+
+func secretFields(cfg *Config) []secretField {
+	return []secretField{
+		{"twitch.client_secret", &cfg.Twitch.ClientSecret},
+		{"telegram.bot_token", &cfg.Telegram.BotToken},
+		{"telegram.backup_bot_token", &cfg.Telegram.BackupBotToken},
+		{"storage.s3.secret_key", &cfg.Storage.S3.SecretKey},
+		{"redis.password", &cfg.Redis.Password},
+		{"grpc.token", &cfg.GRPC.Token},
+	}
+}
+
+func secretProviders(cfg *Config) map[string]SecretProvider {
+	return map[string]SecretProvider{
+		"keyring": keyringProvider{},
+		"vault":   newVaultProvider(cfg.Vault),
+		"sops":    sopsProvider{},
+	}
+}
+
+func resolveSecretRefs(cfg *Config) error {
+	providers := secretProviders(cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, f := range secretFields(cfg) {
+		scheme, ref, ok := strings.Cut(*f.value, ":")
+		provider, known := providers[scheme]
+		if !ok || !known {
+			continue
+		}
+		secret, err := provider.Get(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s from %s: %w", f.path, scheme, err)
+		}
+		if cfg.secretRefs == nil {
+			cfg.secretRefs = make(map[string]string)
+		}
+		cfg.secretRefs[f.path] = *f.value
+		*f.value = secret
+	}
+	return nil
+}
+
+func restoreSecretRefs(cfg *Config) {
+	for _, f := range secretFields(cfg) {
+		if ref, ok := cfg.secretRefs[f.path]; ok {
+			*f.value = ref
+		}
+	}
+}
+
+type keyringProvider struct{}
+
+func (keyringProvider) Get(ctx context.Context, ref string) (string, error) {
+	return keyringGet(ref)
+}
+
+type vaultProvider struct {
+	cfg VaultConfig
+}
+
+func newVaultProvider(cfg VaultConfig) *vaultProvider {
+	if cfg.Address == "" {
+		cfg.Address = os.Getenv("VAULT_ADDR")
+	}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if cfg.Namespace == "" {
+		cfg.Namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	return &vaultProvider{cfg: cfg}
+}
+
+func (p *vaultProvider) Get(ctx context.Context, ref string) (string, error) {
+	path, key, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || key == "" {
+		return "", fmt.Errorf("vault reference must look like vault:<path>#<key>, got %q", ref)
+	}
+	if p.cfg.Address == "" || p.cfg.Token == "" {
+		return "", fmt.Errorf("vault.address and vault.token (or VAULT_ADDR and VAULT_TOKEN) are required")
+	}
+
+	url := strings.TrimRight(p.cfg.Address, "/") + "/v1/" + strings.TrimLeft(path, "/")
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.cfg.Token)
+	if p.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.cfg.Namespace)
+	}
+	resp, err := externalHTTP.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s for %s", resp.Status, path)
+	}
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	data := body.Data
+	if inner, ok := data["data"].(map[string]any); ok {
+		data = inner
+	}
+	secret, ok := data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("key %q not found in vault secret %s", key, path)
+	}
+	return secret, nil
+}
+
+type sopsProvider struct{}
+
+func (sopsProvider) Get(ctx context.Context, ref string) (string, error) {
+	file, key, ok := strings.Cut(ref, "#")
+	if !ok || file == "" || key == "" {
+		return "", fmt.Errorf("sops reference must look like sops:<file>#<key.path>, got %q", ref)
+	}
+	var extract strings.Builder
+	for _, part := range strings.Split(key, ".") {
+		quoted, _ := json.Marshal(part)
+		extract.WriteString("[" + string(quoted) + "]")
+	}
+
+	cmd := exec.CommandContext(ctx, "sops", "--decrypt", "--extract", extract.String(), file)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("sops --decrypt %s: %w: %s", file, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
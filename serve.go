@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	busKeepalive         EventType = "keepalive"
+	busKeepaliveInterval           = 30 * time.Second
+)
+
+type ServeConfig struct {
+	Channels []string `json:"channels"`
+	Token    string   `json:"token"`
+}
+
+type BusServer struct {
+	token string
+	feed  *EventFeed
+	live  map[string]*LiveState
+}
+
+func newBusServer(token string, feed *EventFeed, live map[string]*LiveState) *BusServer {
+	return &BusServer{token: token, feed: feed, live: live}
+}
+
+func (b *BusServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !tokenAuthorized(r, b.token) {
+		writeAPIError(w, http.StatusUnauthorized, errors.New("invalid token"))
+		return
+	}
+	channels := map[string]bool{}
+	for _, ch := range strings.Split(r.URL.Query().Get("channels"), ",") {
+		if ch = strings.ToLower(strings.TrimSpace(ch)); ch != "" {
+			channels[ch] = true
+		}
+	}
+	wanted := func(channel string) bool {
+		return len(channels) == 0 || channels[strings.ToLower(channel)]
+	}
+
+	events, unsubscribe := b.feed.Subscribe()
+	defer unsubscribe()
+	conn, err := acceptWebSocket(w, r)
+	if err != nil {
+		slog.Warn("event bus connection rejected", "remote", r.RemoteAddr, "error", err)
+		return
+	}
+	defer conn.Close()
+	slog.Info("event bus client connected", "remote", r.RemoteAddr, "channels", len(channels))
+
+	snapshot := []EventPayload{}
+	for ch, live := range b.live {
+		if snap, ok := live.Snapshot(); ok && wanted(ch) {
+			snapshot = append(snapshot, snapshotPayload(ch, snap))
+		}
+	}
+	if err := writeBusMessage(conn, snapshot); err != nil {
+		return
+	}
+	pumpEvents(conn, events, r.RemoteAddr, func(ev EventPayload) (any, bool) {
+		return ev, wanted(ev.Channel)
+	})
+}
+
+func pumpEvents(conn *wsConn, events <-chan EventPayload, remote string, message func(EventPayload) (any, bool)) {
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	keepalive := time.NewTicker(busKeepaliveInterval)
+	defer keepalive.Stop()
+	for {
+		var err error
+		select {
+		case <-closed:
+			slog.Info("websocket client disconnected", "remote", remote)
+			return
+		case t := <-keepalive.C:
+			err = writeBusMessage(conn, EventPayload{Type: busKeepalive, Time: t})
+		case ev := <-events:
+			msg, ok := message(ev)
+			if !ok {
+				continue
+			}
+			err = writeBusMessage(conn, msg)
+		}
+		if err != nil {
+			slog.Info("websocket client dropped", "remote", remote, "error", err)
+			return
+		}
+	}
+}
+
+func snapshotPayload(channel string, snap LiveSnapshot) EventPayload {
+	p := newEventPayload(Event{Type: EventStreamUpdated, Time: clock.Now(), Channel: channel, Info: &snap.Info})
+	p.Broadcaster = snap.BroadcasterID
+	return p
+}
+
+func writeBusMessage(conn *wsConn, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return conn.WriteFrame(wsText, data)
+}
+
+type ServeEnricher struct {
+	cfg  *Config
+	feed *EventFeed
+	jobs chan serveJob
+
+	mu           sync.Mutex
+	mature       bool
+	labelsQueued bool
+	clips        []ClipInfo
+	clipsAt      time.Time
+}
+
+type serveJob struct {
+	payload       EventPayload
+	broadcasterID string
+	startTime     time.Time
+	labels        bool
+	clips         bool
+}
+
+func newServeEnricher(cfg *Config, feed *EventFeed) *ServeEnricher {
+	return &ServeEnricher{cfg: cfg, feed: feed, jobs: make(chan serveJob, 16)}
+}
+
+func (e *ServeEnricher) Handle(ctx context.Context, ev Event) {
+	job := serveJob{broadcasterID: ev.Session.BroadcasterID, startTime: ev.Session.StartTime}
+	e.mu.Lock()
+	switch ev.Type {
+	case EventStreamStarted:
+		e.mature, e.labelsQueued, e.clips, e.clipsAt = false, false, nil, ev.Time
+	case EventStreamUpdated:
+		if job.clips = ev.Time.Sub(e.clipsAt) >= time.Duration(e.cfg.UpdateInterval)*time.Minute; job.clips {
+			e.clipsAt = ev.Time
+		}
+	case EventStreamEnded:
+		job.clips = true
+	}
+	if ev.Info != nil {
+		ev.Info.Mature = ev.Info.Mature || e.mature
+		job.labels = !ev.Info.Mature && !e.labelsQueued
+		e.labelsQueued = e.labelsQueued || job.labels
+	}
+	job.payload = newEventPayload(ev)
+	job.payload.Clips = e.clips
+	e.mu.Unlock()
+
+	select {
+	case e.jobs <- job:
+	default:
+		slog.Warn("event bus publisher is falling behind, dropping event", "channel", ev.Channel, "type", ev.Type)
+	}
+}
+
+func (e *ServeEnricher) Run(ctx context.Context) {
+	cfg := e.cfg
+	for {
+		var job serveJob
+		select {
+		case <-ctx.Done():
+			return
+		case job = <-e.jobs:
+		}
+		if job.labels {
+			labels, err := getContentLabels(ctx, job.broadcasterID, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+			e.mu.Lock()
+			if err != nil {
+				slog.Warn("failed to get content classification labels", "channel", job.payload.Channel, "error", err)
+				e.labelsQueued = false
+			} else if len(labels) > 0 {
+				e.mature = true
+				job.payload.Mature = true
+			}
+			e.mu.Unlock()
+		}
+		if job.clips {
+			clips, err := getRecentClips(ctx, job.broadcasterID, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret, job.startTime)
+			if err != nil {
+				slog.Warn("failed to get clips", "channel", job.payload.Channel, "error", err)
+			} else {
+				e.mu.Lock()
+				e.clips = clips
+				e.mu.Unlock()
+				job.payload.Clips = clips
+			}
+		}
+		e.feed.Publish(job.payload)
+	}
+}
+
+func serveChannels(cfg *Config) []string {
+	channels := cfg.Serve.Channels
+	if len(channels) == 0 {
+		channels = []string{cfg.Twitch.Channel}
+		for _, t := range cfg.Channels {
+			channels = append(channels, t.Channel)
+		}
+	}
+	var out []string
+	for _, ch := range channels {
+		if ch = strings.ToLower(strings.TrimSpace(ch)); ch != "" {
+			out = append(out, ch)
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+func serveCommand(configPath string, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", "", "Address for the event bus endpoint (defaults to server.listen)")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	addr := *listen
+	if addr == "" {
+		addr = cfg.Server.Listen
+	}
+	if addr == "" {
+		return errors.New("--listen or server.listen is required")
+	}
+	if cfg.Serve.Token == "" {
+		return errors.New("serve.token is required")
+	}
+	channels := serveChannels(cfg)
+	if len(channels) == 0 {
+		return errors.New("serve.channels is empty")
+	}
+
+	configureEndpoints(cfg.HTTP.Endpoints)
+	if err := configureHTTPClient(twitchHTTP, cfg.HTTP.Twitch); err != nil {
+		return err
+	}
+	twitchRetry = cfg.HTTP.Twitch.Retry
+	creds := []TwitchCredential{{ClientID: cfg.Twitch.ClientID, ClientSecret: cfg.Twitch.ClientSecret}}
+	creds = append(creds, cfg.Twitch.Credentials...)
+	if len(creds) > 1 {
+		setTwitchCredentials(creds)
+	}
+	stateStore, err = openStateStore(cfg.StateFile)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	go runTokenRefresher(ctx, creds)
+	users, err := getTwitchUsers(ctx, "login", channels, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+	if err != nil {
+		slog.Warn("failed to preload twitch users", "error", err)
+	}
+	for _, u := range users {
+		cacheTwitchUser(u)
+	}
+
+	hub := newStreamHub(cfg, channels)
+
+	feed := &EventFeed{}
+	lives := make(map[string]*LiveState, len(channels))
+	for _, ch := range channels {
+		c := *cfg
+		c.Twitch.Channel = ch
+		live := &LiveState{}
+		lives[ch] = live
+		bus := &EventBus{}
+		enricher := newServeEnricher(&c, feed)
+		go enricher.Run(ctx)
+		bus.Subscribe(enricher.Handle)
+		bus.Subscribe(live.Handle)
+		go monitorLoop(ctx, &c, hub.Source(ch), bus, newMonitorControl())
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /bus", newBusServer(cfg.Serve.Token, feed, lives))
+	slog.Info("serving event bus", "channels", len(channels), "addr", addr)
+	runServer(ctx, addr, mux)
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+const retentionWindow = 30 * time.Minute
+
+type RetentionPoint struct {
+	Minute int     `json:"minute"`
+	Ratio  float64 `json:"ratio"`
+}
+
+type SessionRecord struct {
+	Channel         string            `json:"channel"`
+	StartTime       time.Time         `json:"start_time"`
+	EndTime         time.Time         `json:"end_time"`
+	Game            string            `json:"game"`
+	Title           string            `json:"title"`
+	TitleHistory    []TitleChange     `json:"title_history,omitempty"`
+	TagHistory      []TagChange       `json:"tag_history,omitempty"`
+	AvgViewers      int               `json:"avg_viewers"`
+	PeakViewers     int               `json:"peak_viewers"`
+	PeakAt          time.Time         `json:"peak_at"`
+	Retention       float64           `json:"retention,omitempty"`
+	RetentionSeries []RetentionPoint  `json:"retention_series,omitempty"`
+	HoursWatched    float64           `json:"hours_watched,omitempty"`
+	Baseline        int               `json:"baseline,omitempty"`
+	Reactions       map[string]int    `json:"reactions,omitempty"`
+	Rating          float64           `json:"rating,omitempty"`
+	Marks           []StreamMark      `json:"marks,omitempty"`
+	Highlights      []Highlight       `json:"highlights,omitempty"`
+	ViewerHistory   []ViewerDataPoint `json:"viewer_history"`
+}
+
+type SessionArchive struct {
+	mu        sync.Mutex
+	store     Storage
+	path      string
+	reactions *ReactionTracker
+}
+
+func newSessionArchive(store Storage, path string, reactions *ReactionTracker) *SessionArchive {
+	return &SessionArchive{store: store, path: path, reactions: reactions}
+}
+
+func (a *SessionArchive) Handle(ctx context.Context, ev Event) {
+	if ev.Type != EventStreamEnded {
+		return
+	}
+
+	session := ev.Session
+	peak := getMaxViewers(session.ViewerHistory)
+	retention, series := calculateRetention(session.ViewerHistory, session.StartTime)
+	rec := SessionRecord{
+		Channel:         ev.Channel,
+		StartTime:       session.StartTime,
+		EndTime:         ev.Time,
+		Game:            session.Game,
+		Title:           session.Title,
+		TitleHistory:    session.TitleHistory,
+		TagHistory:      session.TagHistory,
+		AvgViewers:      calculateAverage(session.ViewerHistory),
+		PeakViewers:     peak.Count,
+		PeakAt:          peak.Timestamp,
+		Retention:       retention,
+		RetentionSeries: series,
+		HoursWatched:    hoursWatched(session.ViewerHistory),
+		Baseline:        session.Baseline,
+		Marks:           session.Marks,
+		Highlights:      session.Highlights,
+		ViewerHistory:   session.ViewerHistory,
+	}
+	if reactions := a.reactions.Take(session.MessageID); len(reactions) > 0 {
+		rec.Reactions = reactions
+		rec.Rating = communityRating(reactions)
+		slog.Info("community rating", "rating", rec.Rating, "reactions", reactions)
+	}
+	if err := a.Append(ctx, rec); err != nil {
+		slog.Error("failed to archive session", "error", err)
+	}
+}
+
+func (r SessionRecord) Watched() float64 {
+	if r.HoursWatched > 0 {
+		return r.HoursWatched
+	}
+	return hoursWatched(r.ViewerHistory)
+}
+
+func watchedSince(records []SessionRecord, since time.Time) float64 {
+	var total float64
+	for _, rec := range records {
+		if !rec.StartTime.Before(since) {
+			total += rec.Watched()
+		}
+	}
+	return total
+}
+
+func (a *SessionArchive) Append(ctx context.Context, rec SessionRecord) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return a.store.Append(ctx, a.path, append(data, '\n'))
+}
+
+func (a *SessionArchive) Load(ctx context.Context) ([]SessionRecord, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	data, err := a.store.Get(ctx, a.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []SessionRecord
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var rec SessionRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			slog.Warn("skipping corrupt session record", "error", err)
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sessionsPageDefault = 20
+	sessionsPageMax     = 100
+)
+
+type SessionStats struct {
+	StartTime       time.Time         `json:"start_time"`
+	EndTime         time.Time         `json:"end_time"`
+	DurationSeconds int64             `json:"duration_seconds"`
+	Game            string            `json:"game"`
+	Title           string            `json:"title"`
+	AvgViewers      int               `json:"avg_viewers"`
+	PeakViewers     int               `json:"peak_viewers"`
+	PeakAt          time.Time         `json:"peak_at"`
+	Retention       float64           `json:"retention,omitempty"`
+	HoursWatched    float64           `json:"hours_watched"`
+	Baseline        int               `json:"baseline,omitempty"`
+	Rating          float64           `json:"rating,omitempty"`
+	Reactions       map[string]int    `json:"reactions,omitempty"`
+	TitleHistory    []TitleChange     `json:"title_history,omitempty"`
+	Marks           []StreamMark      `json:"marks,omitempty"`
+	Highlights      []Highlight       `json:"highlights,omitempty"`
+	ViewerHistory   []ViewerDataPoint `json:"viewer_history,omitempty"`
+	RetentionSeries []RetentionPoint  `json:"retention_series,omitempty"`
+}
+
+type SessionsPage struct {
+	Channel    string         `json:"channel"`
+	Total      int            `json:"total"`
+	Offset     int            `json:"offset"`
+	Limit      int            `json:"limit"`
+	NextOffset *int           `json:"next_offset"`
+	Sessions   []SessionStats `json:"sessions"`
+}
+
+type SessionsAPI struct {
+	archives map[string]*SessionArchive
+}
+
+func newSessionsAPI(archives map[string]*SessionArchive) *SessionsAPI {
+	return &SessionsAPI{archives: archives}
+}
+
+func (api *SessionsAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	channel := strings.ToLower(r.PathValue("name"))
+	archive, ok := api.archives[channel]
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("channel %q is not monitored", channel))
+		return
+	}
+
+	q := r.URL.Query()
+	offset, err := queryInt(q.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid offset %q", q.Get("offset")))
+		return
+	}
+	limit, err := queryInt(q.Get("limit"), sessionsPageDefault)
+	if err != nil || limit < 1 || limit > sessionsPageMax {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("limit must be between 1 and %d", sessionsPageMax))
+		return
+	}
+	history := q.Get("history") == "true" || q.Get("history") == "1"
+
+	records, err := archive.Load(r.Context())
+	if err != nil {
+		slog.Error("failed to load sessions for api", "channel", channel, "error", err)
+		writeAPIError(w, http.StatusInternalServerError, fmt.Errorf("failed to load sessions"))
+		return
+	}
+
+	page := SessionsPage{Channel: channel, Total: len(records), Offset: offset, Limit: limit, Sessions: []SessionStats{}}
+	for i := len(records) - 1 - offset; i >= 0 && len(page.Sessions) < limit; i-- {
+		page.Sessions = append(page.Sessions, sessionStats(records[i], history))
+	}
+	if next := offset + len(page.Sessions); next < len(records) {
+		page.NextOffset = &next
+	}
+
+	writeJSON(w, page)
+}
+
+func sessionStats(rec SessionRecord, history bool) SessionStats {
+	s := SessionStats{
+		StartTime:       rec.StartTime,
+		EndTime:         rec.EndTime,
+		DurationSeconds: int64(rec.EndTime.Sub(rec.StartTime).Seconds()),
+		Game:            rec.Game,
+		Title:           rec.Title,
+		AvgViewers:      rec.AvgViewers,
+		PeakViewers:     rec.PeakViewers,
+		PeakAt:          rec.PeakAt,
+		Retention:       rec.Retention,
+		HoursWatched:    rec.Watched(),
+		Baseline:        rec.Baseline,
+		Rating:          rec.Rating,
+		Reactions:       rec.Reactions,
+		TitleHistory:    rec.TitleHistory,
+		Marks:           rec.Marks,
+		Highlights:      rec.Highlights,
+	}
+	if history {
+		s.ViewerHistory = rec.ViewerHistory
+		s.RetentionSeries = rec.RetentionSeries
+	}
+	return s
+}
+
+func queryInt(value string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
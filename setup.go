@@ -1,289 +1,998 @@
 package main
 
 import (
-	"bufio"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
-)
 
-type TelegramUpdate struct {
-	UpdateID int `json:"update_id"`
-	Message  *struct {
-		MessageID       int  `json:"message_id"`
-		MessageThreadID *int `json:"message_thread_id"`
-		Chat            struct {
-			ID       int64  `json:"id"`
-			Type     string `json:"type"`
-			Title    string `json:"title"`
-			Username string `json:"username"`
-		} `json:"chat"`
-		Text string `json:"text"`
-	} `json:"message"`
-}
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
 
 type TelegramBotInfo struct {
 	Username string `json:"username"`
 }
 
-func setupInteractive(configPath string, isReconfigure bool) error {
-	reader := bufio.NewReader(os.Stdin)
-	ctx := context.Background()
+var errSetupCancelled = errors.New("setup cancelled")
 
-	var cfg *Config
-	if isReconfigure {
-		var err error
-		cfg, err = loadConfig(configPath)
-		if err != nil {
-			cfg = &Config{}
-		}
-	} else {
-		cfg = &Config{}
-	}
+const (
+	setupStepTwitch    = "twitch"
+	setupStepChannel   = "channel"
+	setupStepBot       = "bot"
+	setupStepChat      = "chat"
+	setupStepChannels  = "channels"
+	setupStepLanguage  = "language"
+	setupStepIntervals = "intervals"
+)
 
-	fmt.Println()
-	fmt.Println("Twitch Stream Monitor - Setup")
-	fmt.Println()
+type setupStep struct {
+	name    string
+	title   string
+	done    func(cfg *Config) bool
+	summary func(cfg *Config) string
+	open    func(m *setupModel) setupScreen
+}
 
-	stepNum := 0
-	totalSteps := 6
+type setupStatus struct {
+	checked bool
+	err     error
+}
 
-	if cfg.Twitch.ClientID == "" || cfg.Twitch.ClientSecret == "" {
-		stepNum++
-		fmt.Printf("[%d/%d] Twitch API Credentials\n", stepNum, totalSteps)
-		fmt.Println("Get your credentials at: https://dev.twitch.tv/console")
-		fmt.Println()
+type setupResultMsg struct {
+	seq   int
+	apply func(m *setupModel) tea.Cmd
+}
 
-		for {
-			clientID := promptString(reader, "Client ID", "")
-			if clientID == "" {
-				continue
-			}
-			clientSecret := promptString(reader, "Client Secret", "")
-			if clientSecret == "" {
-				continue
-			}
+type setupModel struct {
+	ctx         context.Context
+	path        string
+	cfg         *Config
+	steps       []setupStep
+	status      map[string]setupStatus
+	issues      []ConfigIssue
+	hadIssues   bool
+	botUsername string
+
+	queue   []string
+	pos     int
+	current string
+	screen  setupScreen
+
+	spinner   spinner.Model
+	busy      string
+	seq       int
+	cancelRun context.CancelFunc
+
+	saved bool
+	err   error
+}
 
-			fmt.Print("Validating credentials... ")
-			if err := validateTwitchCredentials(ctx, clientID, clientSecret); err != nil {
-				fmt.Printf("Error: %v\n", err)
-				if !promptRetry(reader) {
-					return fmt.Errorf("setup cancelled")
-				}
-				continue
-			}
+func setupInteractive(configPath string, isReconfigure bool) error {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return fmt.Errorf("interactive setup needs a terminal; create %s by hand (see README) or run the bot in a terminal", configPath)
+	}
+	cfg := &Config{}
+	var issues []ConfigIssue
+	if isReconfigure {
+		loaded, found, err := loadConfigLenient(configPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if loaded != nil {
+			cfg = loaded
+		}
+		issues = found
+	}
 
-			fmt.Println("OK")
-			cfg.Twitch.ClientID = clientID
-			cfg.Twitch.ClientSecret = clientSecret
-			break
+	m := newSetupModel(context.Background(), configPath, cfg, issues)
+	final, err := tea.NewProgram(m).Run()
+	if err != nil {
+		return err
+	}
+	if m = final.(*setupModel); m.err != nil {
+		return m.err
+	}
+	if !m.saved {
+		return errSetupCancelled
+	}
+	fmt.Printf("Configuration saved to %s\n", configPath)
+	return nil
+}
+
+func newSetupModel(ctx context.Context, path string, cfg *Config, issues []ConfigIssue) *setupModel {
+	m := &setupModel{
+		ctx:       ctx,
+		path:      path,
+		cfg:       cfg,
+		steps:     setupSteps(),
+		status:    map[string]setupStatus{},
+		issues:    issues,
+		hadIssues: len(issues) > 0,
+		spinner:   spinner.New(spinner.WithSpinner(spinner.Dot)),
+	}
+	for _, step := range m.steps {
+		if !step.done(cfg) {
+			m.queue = append(m.queue, step.name)
 		}
-		fmt.Println()
 	}
+	if len(m.queue) > 0 {
+		m.openStep(m.queue[0])
+	} else {
+		m.openReview()
+	}
+	return m
+}
 
-	if cfg.Twitch.Channel == "" {
-		stepNum++
-		fmt.Printf("[%d/%d] Twitch Channel\n", stepNum, totalSteps)
-		for {
-			channel := promptString(reader, "Enter channel name", "")
-			if channel == "" {
-				continue
-			}
+func (m *setupModel) Init() tea.Cmd {
+	return textinput.Blink
+}
 
-			fmt.Print("Checking channel... ")
-			if validateTwitchChannel(ctx, channel, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret) {
-				fmt.Println("OK")
-				cfg.Twitch.Channel = channel
-				break
-			}
-			fmt.Println("Error: Channel not found")
-			if !promptRetry(reader) {
-				return fmt.Errorf("setup cancelled")
-			}
+func (m *setupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case msg.Type == tea.KeyCtrlC:
+			m.abort()
+			m.err = errSetupCancelled
+			return m, tea.Quit
+		case m.busy != "" && msg.Type == tea.KeyEsc:
+			m.abort()
+			return m, nil
+		case m.busy != "":
+			return m, nil
+		}
+	case spinner.TickMsg:
+		if m.busy == "" {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	case setupResultMsg:
+		if msg.seq != m.seq || m.busy == "" {
+			return m, nil
 		}
-		fmt.Println()
+		m.busy = ""
+		m.cancelRun = nil
+		return m, msg.apply(m)
 	}
+	return m, m.screen.update(m, msg)
+}
 
-	if cfg.Telegram.BotToken == "" {
-		stepNum++
-		fmt.Printf("[%d/%d] Telegram Bot\n", stepNum, totalSteps)
-		fmt.Println("Create a bot via @BotFather on Telegram")
-		fmt.Println()
+func (m *setupModel) View() string {
+	var b strings.Builder
+	b.WriteString(setupTitleStyle.Render("Twitch Stream Monitor — Setup") + "\n")
+	switch {
+	case m.current == "":
+		b.WriteString("Review settings\n\n")
+	case m.pos >= 0:
+		b.WriteString(fmt.Sprintf("Step %d/%d · %s\n\n", m.pos+1, len(m.queue), m.step(m.current).title))
+	default:
+		b.WriteString(fmt.Sprintf("Edit · %s\n\n", m.step(m.current).title))
+	}
+	if (m.current == "" || m.pos == 0) && len(m.issues) > 0 {
+		b.WriteString(setupErrStyle.Render("The config file has problems:") + "\n")
+		for _, issue := range m.issues {
+			b.WriteString("  " + issue.String() + "\n")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(m.screen.view(m))
+	if m.busy != "" {
+		b.WriteString("\n" + m.spinner.View() + " " + m.busy + "…\n")
+		b.WriteString("\n" + setupDimStyle.Render("esc cancel · ctrl+c quit") + "\n")
+	} else {
+		b.WriteString("\n" + setupDimStyle.Render(m.screen.help()+" · ctrl+c quit") + "\n")
+	}
+	return b.String()
+}
 
-		for {
-			botToken := promptString(reader, "Bot Token", "")
-			if botToken == "" || len(botToken) < 20 {
-				fmt.Println("Error: Invalid format")
-				continue
-			}
+func (m *setupModel) step(name string) setupStep {
+	i := slices.IndexFunc(m.steps, func(s setupStep) bool { return s.name == name })
+	return m.steps[i]
+}
 
-			fmt.Print("Checking bot... ")
-			botUsername, err := validateTelegramToken(ctx, botToken)
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-				if !promptRetry(reader) {
-					return fmt.Errorf("setup cancelled")
-				}
-				continue
-			}
+func (m *setupModel) openStep(name string) tea.Cmd {
+	m.abort()
+	m.current = name
+	m.pos = slices.Index(m.queue, name)
+	m.screen = m.step(name).open(m)
+	return textinput.Blink
+}
 
-			fmt.Printf("OK (Bot: @%s)\n", botUsername)
-			cfg.Telegram.BotToken = botToken
-			break
-		}
-		fmt.Println()
+func (m *setupModel) editStep(name string) tea.Cmd {
+	cmd := m.openStep(name)
+	m.pos = -1
+	return cmd
+}
+
+func (m *setupModel) openReview() tea.Cmd {
+	m.abort()
+	m.current = ""
+	m.pos = -1
+	m.screen = &reviewScreen{}
+	return nil
+}
+
+func (m *setupModel) finishStep(err error) tea.Cmd {
+	m.status[m.current] = setupStatus{checked: true, err: err}
+	if m.pos >= 0 && m.pos+1 < len(m.queue) {
+		return m.openStep(m.queue[m.pos+1])
 	}
+	return m.openReview()
+}
 
-	if cfg.Telegram.ChatID == nil {
-		stepNum++
-		fmt.Printf("[%d/%d] Chat Configuration\n", stepNum, totalSteps)
-		fmt.Println()
+func (m *setupModel) back() tea.Cmd {
+	switch {
+	case m.pos > 0:
+		return m.openStep(m.queue[m.pos-1])
+	case m.pos < 0:
+		return m.openReview()
+	}
+	return nil
+}
 
-		botUsername, _ := validateTelegramToken(ctx, cfg.Telegram.BotToken)
+func (m *setupModel) run(label string, fn func(ctx context.Context) func(m *setupModel) tea.Cmd) tea.Cmd {
+	m.abort()
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.seq++
+	seq := m.seq
+	m.busy = label
+	m.cancelRun = cancel
+	return tea.Batch(m.spinner.Tick, func() tea.Msg {
+		return setupResultMsg{seq: seq, apply: fn(ctx)}
+	})
+}
 
-		fmt.Println("Choose setup method:")
-		fmt.Println("1. Automatic - for groups (bot will detect chat ID)")
-		fmt.Println("2. Manual - for channels (you provide chat ID)")
-		fmt.Println()
+func (m *setupModel) abort() {
+	if m.cancelRun != nil {
+		m.cancelRun()
+		m.cancelRun = nil
+	}
+	m.seq++
+	m.busy = ""
+}
 
-		method := promptString(reader, "Select method (1/2)", "1")
-		fmt.Println()
+func setupSteps() []setupStep {
+	return []setupStep{
+		{
+			name:  setupStepTwitch,
+			title: "Twitch API credentials",
+			done: func(cfg *Config) bool {
+				return cfg.Upstream.URL != "" || (cfg.Twitch.ClientID != "" && cfg.Twitch.ClientSecret != "")
+			},
+			summary: func(cfg *Config) string { return maskSecret(cfg.Twitch.ClientID) },
+			open:    (*setupModel).twitchScreen,
+		},
+		{
+			name:    setupStepChannel,
+			title:   "Twitch channel",
+			done:    func(cfg *Config) bool { return cfg.Twitch.Channel != "" },
+			summary: func(cfg *Config) string { return cfg.Twitch.Channel },
+			open:    (*setupModel).channelScreen,
+		},
+		{
+			name:    setupStepBot,
+			title:   "Telegram bot",
+			done:    func(cfg *Config) bool { return cfg.Telegram.BotToken != "" },
+			summary: func(cfg *Config) string { return maskSecret(cfg.Telegram.BotToken) },
+			open:    (*setupModel).botScreen,
+		},
+		{
+			name:  setupStepChat,
+			title: "Chat",
+			done:  func(cfg *Config) bool { return cfg.Telegram.ChatID != nil },
+			summary: func(cfg *Config) string {
+				if cfg.Telegram.ChatID == nil {
+					return ""
+				}
+				if cfg.Telegram.ThreadID != nil {
+					return fmt.Sprintf("%d (thread %d)", *cfg.Telegram.ChatID, *cfg.Telegram.ThreadID)
+				}
+				if cfg.Telegram.CommentStats {
+					return fmt.Sprintf("%d (stats in comments)", *cfg.Telegram.ChatID)
+				}
+				return strconv.FormatInt(*cfg.Telegram.ChatID, 10)
+			},
+			open: func(m *setupModel) setupScreen {
+				return newChatScreen(true, func(m *setupModel, chatID int64, threadID *int) tea.Cmd {
+					m.cfg.Telegram.ChatID = &chatID
+					m.cfg.Telegram.ThreadID = threadID
+					return m.finishStep(nil)
+				}, nil)
+			},
+		},
+		{
+			name:  setupStepChannels,
+			title: "Additional channels",
+			done:  func(cfg *Config) bool { return cfg.SetupCompleted || len(cfg.Channels) > 0 },
+			summary: func(cfg *Config) string {
+				pairs := make([]string, 0, len(cfg.Channels))
+				for _, t := range cfg.Channels {
+					pairs = append(pairs, t.Channel+" → "+formatTarget(t))
+				}
+				return strings.Join(pairs, ", ")
+			},
+			open: func(m *setupModel) setupScreen { return &channelsScreen{} },
+		},
+		{
+			name:    setupStepLanguage,
+			title:   "Language",
+			done:    func(cfg *Config) bool { return cfg.Language != "" },
+			summary: func(cfg *Config) string { return cfg.Language },
+			open:    (*setupModel).languageScreen,
+		},
+		{
+			name:  setupStepIntervals,
+			title: "Monitor settings",
+			done:  func(cfg *Config) bool { return cfg.CheckInterval != 0 && cfg.UpdateInterval != 0 },
+			summary: func(cfg *Config) string {
+				return fmt.Sprintf("check every %ds, update every %dm", cfg.CheckInterval, cfg.UpdateInterval)
+			},
+			open: (*setupModel).intervalsScreen,
+		},
+	}
+}
 
-		var chatID int64
-		var threadID *int
+func (m *setupModel) twitchScreen() setupScreen {
+	return newFormScreen(
+		[]string{"Get your credentials at https://dev.twitch.tv/console"},
+		[]*formField{
+			newFormField("Client ID", m.cfg.Twitch.ClientID, false, requireValue),
+			newFormField("Client Secret", m.cfg.Twitch.ClientSecret, true, requireValue),
+		},
+		func(m *setupModel, s *formScreen, v []string) tea.Cmd {
+			return m.run("Validating credentials", func(ctx context.Context) func(*setupModel) tea.Cmd {
+				err := validateTwitchCredentials(ctx, v[0], v[1])
+				return func(m *setupModel) tea.Cmd {
+					if err != nil {
+						s.err = err
+						return nil
+					}
+					m.cfg.Twitch.ClientID, m.cfg.Twitch.ClientSecret = v[0], v[1]
+					return m.finishStep(nil)
+				}
+			})
+		},
+	)
+}
 
-		if method == "2" {
-			fmt.Println("To get your channel chat ID:")
-			fmt.Println("1. Add your bot to the channel as administrator")
-			fmt.Println("2. Forward any channel message to @userinfobot")
-			fmt.Println("3. Copy the chat ID (number starting with -100)")
-			fmt.Println()
+func (m *setupModel) channelScreen() setupScreen {
+	return newFormScreen(
+		[]string{"The channel login, without the twitch.tv/ prefix"},
+		[]*formField{newFormField("Channel", m.cfg.Twitch.Channel, false, requireValue)},
+		func(m *setupModel, s *formScreen, v []string) tea.Cmd {
+			return m.checkChannel(s, v[0], func(m *setupModel) tea.Cmd {
+				m.cfg.Twitch.Channel = v[0]
+				return m.finishStep(nil)
+			})
+		},
+	)
+}
 
-			chatIDStr := promptString(reader, "Enter chat ID", "")
-			if chatIDStr == "" {
-				return fmt.Errorf("chat ID is required")
+func (m *setupModel) checkChannel(s *formScreen, channel string, next func(m *setupModel) tea.Cmd) tea.Cmd {
+	clientID, clientSecret, viaUpstream := m.cfg.Twitch.ClientID, m.cfg.Twitch.ClientSecret, m.cfg.Upstream.URL != ""
+	return m.run("Checking channel", func(ctx context.Context) func(*setupModel) tea.Cmd {
+		found := viaUpstream || validateTwitchChannel(ctx, channel, clientID, clientSecret)
+		return func(m *setupModel) tea.Cmd {
+			if !found {
+				s.err = errors.New("channel not found")
+				return nil
 			}
+			return next(m)
+		}
+	})
+}
 
-			var err error
-			chatID, err = strconv.ParseInt(chatIDStr, 10, 64)
-			if err != nil {
-				return fmt.Errorf("invalid chat ID format: %w", err)
+func (m *setupModel) botScreen() setupScreen {
+	return newFormScreen(
+		[]string{"Create a bot via @BotFather on Telegram"},
+		[]*formField{newFormField("Bot token", m.cfg.Telegram.BotToken, true, func(v string) error {
+			if len(v) < 20 || !strings.Contains(v, ":") {
+				return errors.New("invalid format")
 			}
-
-			threadIDStr := promptString(reader, "Enter thread ID (optional, press Enter to skip)", "")
-			if threadIDStr != "" {
-				if v, err := strconv.Atoi(threadIDStr); err == nil {
-					threadID = &v
+			return nil
+		})},
+		func(m *setupModel, s *formScreen, v []string) tea.Cmd {
+			return m.run("Checking bot", func(ctx context.Context) func(*setupModel) tea.Cmd {
+				username, err := validateTelegramToken(ctx, v[0])
+				return func(m *setupModel) tea.Cmd {
+					if err != nil {
+						s.err = err
+						return nil
+					}
+					m.botUsername = username
+					m.cfg.Telegram.BotToken = v[0]
+					return m.finishStep(nil)
 				}
+			})
+		},
+	)
+}
+
+func (m *setupModel) languageScreen() setupScreen {
+	languages := []string{"en", "ru"}
+	return &selectScreen{
+		intro:   []string{"Language of the Telegram messages"},
+		options: []string{"English", "Русский"},
+		cursor:  max(slices.Index(languages, m.cfg.Language), 0),
+		choose: func(m *setupModel, i int) tea.Cmd {
+			m.cfg.Language = languages[i]
+			return m.finishStep(nil)
+		},
+	}
+}
+
+func (m *setupModel) intervalsScreen() setupScreen {
+	return newFormScreen(
+		nil,
+		[]*formField{
+			newFormField("Check interval (seconds)", strconv.Itoa(cmp.Or(m.cfg.CheckInterval, 60)), false, checkInt(5, 3600)),
+			newFormField("Update interval (minutes)", strconv.Itoa(cmp.Or(m.cfg.UpdateInterval, 5)), false, checkInt(1, 1440)),
+		},
+		func(m *setupModel, s *formScreen, v []string) tea.Cmd {
+			check, _ := strconv.Atoi(v[0])
+			update, _ := strconv.Atoi(v[1])
+			if update*60 < check {
+				s.err = errors.New("update interval must not be shorter than the check interval")
+				return nil
 			}
-		} else {
-			if botUsername != "" {
-				fmt.Printf("1. Add @%s to your group as administrator\n", botUsername)
-			} else {
-				fmt.Println("1. Add your bot to the group as administrator")
+			m.cfg.CheckInterval = check
+			m.cfg.UpdateInterval = update
+			return m.finishStep(nil)
+		},
+	)
+}
+
+const (
+	chatMethod = iota
+	chatManual
+	chatWaiting
+	chatConfirm
+	chatComments
+)
+
+type chatScreen struct {
+	main     bool
+	phase    int
+	method   *selectScreen
+	form     *formScreen
+	watcher  *setupChatWatcher
+	pending  setupCandidate
+	chatID   int64
+	threadID *int
+	linked   int64
+	note     string
+	err      error
+	done     func(m *setupModel, chatID int64, threadID *int) tea.Cmd
+	onBack   func(m *setupModel) tea.Cmd
+}
+
+func newChatScreen(main bool, done func(m *setupModel, chatID int64, threadID *int) tea.Cmd, onBack func(m *setupModel) tea.Cmd) *chatScreen {
+	s := &chatScreen{main: main, done: done, onBack: onBack}
+	s.method = &selectScreen{
+		options: []string{
+			"Automatic — add the bot to a group or channel and it detects the chat",
+			"Manual — enter the chat ID",
+		},
+		choose: func(m *setupModel, i int) tea.Cmd {
+			s.err, s.note = nil, ""
+			if i == 0 {
+				return s.startWaiting(m)
+			}
+			s.phase = chatManual
+			s.form = s.manualForm()
+			return nil
+		},
+		onBack: func(m *setupModel) tea.Cmd {
+			if s.onBack != nil {
+				return s.onBack(m)
+			}
+			return m.back()
+		},
+	}
+	return s
+}
+
+func (s *chatScreen) manualForm() *formScreen {
+	f := newFormScreen(
+		[]string{
+			"1. Add your bot to the channel or group as administrator",
+			"2. Forward any message from it to @userinfobot",
+			"3. Copy the chat ID (a number starting with -100)",
+		},
+		[]*formField{
+			newFormField("Chat ID", "", false, func(v string) error {
+				if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+					return errors.New("chat ID must be a number")
+				}
+				return nil
+			}),
+			newFormField("Thread ID (optional)", "", false, func(v string) error {
+				if _, err := strconv.Atoi(v); v != "" && err != nil {
+					return errors.New("thread ID must be a number")
+				}
+				return nil
+			}),
+		},
+		func(m *setupModel, f *formScreen, v []string) tea.Cmd {
+			chatID, _ := strconv.ParseInt(v[0], 10, 64)
+			var threadID *int
+			if n, err := strconv.Atoi(v[1]); err == nil {
+				threadID = &n
 			}
-			fmt.Println("2. Send 'SETUP' command in the group")
-			fmt.Println()
-			fmt.Print("Waiting for SETUP command... ")
+			token := m.cfg.Telegram.BotToken
+			return m.run("Checking chat", func(ctx context.Context) func(*setupModel) tea.Cmd {
+				chat, err := getChat(token, chatID)
+				return func(m *setupModel) tea.Cmd {
+					if err == nil && chat.Type == "channel" {
+						s.note = "Channel: " + chat.Title
+						threadID = nil
+					}
+					return s.checkPermissions(m, chatID, threadID)
+				}
+			})
+		},
+	)
+	f.onBack = func(m *setupModel) tea.Cmd {
+		s.phase = chatMethod
+		return nil
+	}
+	return f
+}
 
-			var err error
-			chatID, threadID, err = waitForSetupCommand(ctx, cfg.Telegram.BotToken, 120)
+func (s *chatScreen) startWaiting(m *setupModel) tea.Cmd {
+	s.phase = chatWaiting
+	if s.watcher == nil {
+		s.watcher = &setupChatWatcher{token: m.cfg.Telegram.BotToken}
+	}
+	w, username := s.watcher, m.botUsername
+	return m.run("Waiting for the bot to be added", func(ctx context.Context) func(*setupModel) tea.Cmd {
+		if username == "" {
+			username, _ = validateTelegramToken(ctx, w.token)
+		}
+		candidate, err := w.next(ctx, time.Now().Add(2*time.Minute))
+		return func(m *setupModel) tea.Cmd {
+			m.botUsername = username
 			if err != nil {
-				fmt.Printf("Error: %v\n\n", err)
-				fmt.Println("Tip: If using a channel, restart and choose manual method (option 2)")
-				return fmt.Errorf("setup failed: %w", err)
+				s.phase = chatMethod
+				s.err = fmt.Errorf("%w; you can also enter the chat ID manually", err)
+				return nil
+			}
+			if candidate.confirm {
+				s.phase = chatConfirm
+				s.pending = candidate
+				return nil
 			}
+			s.note = "Received SETUP from " + describeChat(candidate.chat)
+			return s.checkPermissions(m, candidate.chat.ID, candidate.threadID)
 		}
+	})
+}
 
-		fmt.Printf("OK (Chat ID: %d)\n\n", chatID)
+func (s *chatScreen) checkPermissions(m *setupModel, chatID int64, threadID *int) tea.Cmd {
+	s.chatID, s.threadID = chatID, threadID
+	token := m.cfg.Telegram.BotToken
+	return m.run("Checking bot permissions", func(ctx context.Context) func(*setupModel) tea.Cmd {
+		err := checkBotPermissions(ctx, token, chatID)
+		return func(m *setupModel) tea.Cmd {
+			if err == nil {
+				return s.permissionsOK(m)
+			}
+			s.note = fmt.Sprintf("Missing permissions: %v. Grant the bot permission to send messages.", err)
+			return m.run("Waiting for permissions fix", func(ctx context.Context) func(*setupModel) tea.Cmd {
+				err := waitForPermissionsFix(ctx, token, chatID, 300)
+				return func(m *setupModel) tea.Cmd {
+					if err != nil {
+						s.phase = chatMethod
+						s.err = err
+						return nil
+					}
+					return s.permissionsOK(m)
+				}
+			})
+		}
+	})
+}
 
-		fmt.Print("Checking bot permissions... ")
-		if err := checkBotPermissions(ctx, cfg.Telegram.BotToken, chatID); err != nil {
-			fmt.Printf("Error\n\nMissing permissions: %v\n", err)
-			fmt.Println("Please grant the bot permission to send messages")
-			fmt.Println()
-			fmt.Print("Waiting for permissions fix... ")
-			if err := waitForPermissionsFix(ctx, cfg.Telegram.BotToken, chatID, 300); err != nil {
-				fmt.Printf("Error: %v\n", err)
-				return fmt.Errorf("setup failed: %w", err)
+func (s *chatScreen) permissionsOK(m *setupModel) tea.Cmd {
+	if !s.main {
+		return s.done(m, s.chatID, s.threadID)
+	}
+	token := m.cfg.Telegram.BotToken
+	chatID := s.chatID
+	return m.run("Checking for a discussion group", func(ctx context.Context) func(*setupModel) tea.Cmd {
+		chat, err := getChat(token, chatID)
+		return func(m *setupModel) tea.Cmd {
+			if err != nil || chat.Type != "channel" || chat.LinkedChatID == 0 {
+				m.cfg.Telegram.CommentStats = false
+				return s.done(m, s.chatID, s.threadID)
 			}
+			s.linked = chat.LinkedChatID
+			s.phase = chatComments
+			return nil
+		}
+	})
+}
+
+func (s *chatScreen) enableComments(m *setupModel) tea.Cmd {
+	token, linked := m.cfg.Telegram.BotToken, s.linked
+	return m.run("Checking bot permissions in the discussion group", func(ctx context.Context) func(*setupModel) tea.Cmd {
+		err := checkBotPermissions(ctx, token, linked)
+		if err != nil {
+			err = waitForPermissionsFix(ctx, token, linked, 300)
+		}
+		return func(m *setupModel) tea.Cmd {
+			m.cfg.Telegram.CommentStats = err == nil
+			return s.done(m, s.chatID, s.threadID)
 		}
+	})
+}
 
-		fmt.Print("OK\n")
-		cfg.Telegram.ChatID = &chatID
-		cfg.Telegram.ThreadID = threadID
+func (s *chatScreen) update(m *setupModel, msg tea.Msg) tea.Cmd {
+	switch s.phase {
+	case chatMethod:
+		return s.method.update(m, msg)
+	case chatManual:
+		return s.form.update(m, msg)
+	}
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return nil
+	}
+	switch key.String() {
+	case "esc":
+		s.phase = chatMethod
+		s.note = ""
+		return nil
+	case "y", "Y", "enter":
+		switch s.phase {
+		case chatConfirm:
+			return s.checkPermissions(m, s.pending.chat.ID, nil)
+		case chatComments:
+			return s.enableComments(m)
+		}
+	case "n", "N":
+		switch s.phase {
+		case chatConfirm:
+			return s.startWaiting(m)
+		case chatComments:
+			m.cfg.Telegram.CommentStats = false
+			return s.done(m, s.chatID, s.threadID)
+		}
 	}
+	return nil
+}
 
-	if cfg.Language == "" {
-		stepNum++
-		fmt.Printf("[%d/%d] Language\n", stepNum, totalSteps)
-		lang := promptString(reader, "Select language (en/ru)", "en")
-		if lang != "en" && lang != "ru" {
-			lang = "en"
+func (s *chatScreen) view(m *setupModel) string {
+	var b strings.Builder
+	switch s.phase {
+	case chatMethod:
+		b.WriteString(s.method.view(m))
+	case chatManual:
+		b.WriteString(s.form.view(m))
+	case chatWaiting:
+		bot := "your bot"
+		if m.botUsername != "" {
+			bot = "@" + m.botUsername
 		}
-		cfg.Language = lang
-		fmt.Println()
+		b.WriteString(fmt.Sprintf("1. Add %s to your group or channel as administrator\n", bot))
+		b.WriteString("2. Confirm the chat here, or send SETUP in the group (in a topic to use that topic)\n")
+	case chatConfirm:
+		b.WriteString(fmt.Sprintf("Bot was added to %s\n\nUse this chat? (y/n)\n", describeChat(s.pending.chat)))
+	case chatComments:
+		b.WriteString(fmt.Sprintf("This channel has a linked discussion group (%d).\n", s.linked))
+		b.WriteString("Detailed stats and clips can be posted as a comment under each announcement.\n\n")
+		b.WriteString("Post stats in comments? (y/n)\n")
+	}
+	if s.note != "" {
+		b.WriteString("\n" + s.note + "\n")
+	}
+	if s.err != nil {
+		b.WriteString("\n" + setupErrStyle.Render("✗ "+s.err.Error()) + "\n")
 	}
+	return b.String()
+}
 
-	if cfg.CheckInterval == 0 || cfg.UpdateInterval == 0 {
-		stepNum++
-		fmt.Printf("[%d/%d] Monitor Settings\n", stepNum, totalSteps)
+func (s *chatScreen) help() string {
+	switch s.phase {
+	case chatMethod:
+		return s.method.help()
+	case chatManual:
+		return s.form.help()
+	case chatConfirm, chatComments:
+		return "y yes · n no · esc back"
+	}
+	return "esc back"
+}
 
-		if cfg.CheckInterval == 0 {
-			s := promptString(reader, "Check interval (seconds)", "60")
-			v, err := strconv.Atoi(s)
-			if err != nil || v <= 0 {
-				v = 60
-			}
-			cfg.CheckInterval = v
+type channelsScreen struct {
+	cursor int
+	form   *formScreen
+	chat   *chatScreen
+	err    error
+}
+
+func (s *channelsScreen) update(m *setupModel, msg tea.Msg) tea.Cmd {
+	switch {
+	case s.chat != nil:
+		return s.chat.update(m, msg)
+	case s.form != nil:
+		return s.form.update(m, msg)
+	}
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return nil
+	}
+	targets := m.cfg.Channels
+	switch key.String() {
+	case "esc":
+		return m.back()
+	case "enter":
+		return m.finishStep(nil)
+	case "up", "k":
+		s.cursor = max(s.cursor-1, 0)
+	case "down", "j":
+		s.cursor = min(s.cursor+1, max(len(targets)-1, 0))
+	case "d", "delete", "backspace":
+		if s.cursor < len(targets) {
+			m.cfg.Channels = slices.Delete(targets, s.cursor, s.cursor+1)
+			s.cursor = min(s.cursor, max(len(m.cfg.Channels)-1, 0))
 		}
+	case "a":
+		s.err = nil
+		s.form = s.channelForm(m)
+	}
+	return nil
+}
 
-		if cfg.UpdateInterval == 0 {
-			s := promptString(reader, "Update interval (minutes)", "5")
-			v, err := strconv.Atoi(s)
-			if err != nil || v <= 0 {
-				v = 5
-			}
-			cfg.UpdateInterval = v
+func (s *channelsScreen) channelForm(m *setupModel) *formScreen {
+	f := newFormScreen(
+		nil,
+		[]*formField{newFormField("Twitch channel", m.cfg.Twitch.Channel, false, requireValue)},
+		func(m *setupModel, f *formScreen, v []string) tea.Cmd {
+			return m.checkChannel(f, v[0], func(m *setupModel) tea.Cmd {
+				channel := v[0]
+				s.form = nil
+				s.chat = newChatScreen(false, func(m *setupModel, chatID int64, threadID *int) tea.Cmd {
+					s.chat = nil
+					if channel == m.cfg.Twitch.Channel && m.cfg.Telegram.ChatID != nil && *m.cfg.Telegram.ChatID == chatID {
+						s.err = errors.New("this pair is already the main channel and chat")
+						return nil
+					}
+					m.cfg.Channels = append(m.cfg.Channels, ChannelTarget{Channel: channel, ChatID: chatID, ThreadID: threadID})
+					s.cursor = len(m.cfg.Channels) - 1
+					return nil
+				}, func(m *setupModel) tea.Cmd {
+					s.chat = nil
+					return nil
+				})
+				return nil
+			})
+		},
+	)
+	f.onBack = func(m *setupModel) tea.Cmd {
+		s.form = nil
+		return nil
+	}
+	return f
+}
+
+func (s *channelsScreen) view(m *setupModel) string {
+	switch {
+	case s.chat != nil:
+		return s.chat.view(m)
+	case s.form != nil:
+		return s.form.view(m)
+	}
+	var b strings.Builder
+	b.WriteString(setupDimStyle.Render("Announce more Twitch channels, or the same channel in more chats.") + "\n")
+	b.WriteString(setupDimStyle.Render("Each channel/chat pair is monitored separately.") + "\n\n")
+	if len(m.cfg.Channels) == 0 {
+		b.WriteString("  No additional channels\n")
+	}
+	for i, t := range m.cfg.Channels {
+		line := fmt.Sprintf("%d. %s → %s", i+1, t.Channel, formatTarget(t))
+		if i == s.cursor {
+			b.WriteString(setupFocusStyle.Render("> "+line) + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
 		}
-		fmt.Println()
 	}
+	if s.err != nil {
+		b.WriteString("\n" + setupErrStyle.Render("✗ "+s.err.Error()) + "\n")
+	}
+	return b.String()
+}
+
+func (s *channelsScreen) help() string {
+	switch {
+	case s.chat != nil:
+		return s.chat.help()
+	case s.form != nil:
+		return s.form.help()
+	}
+	return "a add · d remove · enter continue · esc back"
+}
+
+const (
+	reviewList = iota
+	reviewConfirm
+)
+
+type reviewScreen struct {
+	cursor int
+	phase  int
+	msgID  int
+	note   string
+	err    error
+}
 
-	cfg.SetupCompleted = true
-	if err := saveConfig(configPath, cfg); err != nil {
-		return fmt.Errorf("failed to save config: %w", err)
+func (s *reviewScreen) update(m *setupModel, msg tea.Msg) tea.Cmd {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return nil
+	}
+	if s.phase == reviewConfirm {
+		token, chatID, msgID := m.cfg.Telegram.BotToken, *m.cfg.Telegram.ChatID, s.msgID
+		cleanup := func() tea.Msg {
+			deleteMessage(token, chatID, msgID)
+			return nil
+		}
+		switch key.String() {
+		case "y", "Y", "enter":
+			s.phase = reviewList
+			m.status[setupStepChat] = setupStatus{checked: true}
+			return tea.Sequence(cleanup, s.save(m))
+		case "n", "N", "esc":
+			s.phase = reviewList
+			s.note = "Adjust the settings and save again"
+			return cleanup
+		}
+		return nil
 	}
 
-	fmt.Print("Configuration saved to config.json\n")
+	switch key.String() {
+	case "up", "k":
+		s.cursor = (s.cursor + len(m.steps) - 1) % len(m.steps)
+	case "down", "j", "tab":
+		s.cursor = (s.cursor + 1) % len(m.steps)
+	case "enter":
+		return m.editStep(m.steps[s.cursor].name)
+	case "s":
+		s.err, s.note = nil, ""
+		cfg := *m.cfg
+		return m.run("Sending test message", func(ctx context.Context) func(*setupModel) tea.Cmd {
+			msgID, err := sendSetupTestMessage(&cfg)
+			return func(m *setupModel) tea.Cmd {
+				if err != nil {
+					m.status[setupStepChat] = setupStatus{checked: true, err: err}
+					s.err = fmt.Errorf("failed to send test message: %w", err)
+					return nil
+				}
+				s.msgID = msgID
+				s.phase = reviewConfirm
+				return nil
+			}
+		})
+	case "q":
+		return tea.Quit
+	default:
+		if n, err := strconv.Atoi(key.String()); err == nil && n >= 1 && n <= len(m.steps) {
+			return m.editStep(m.steps[n-1].name)
+		}
+	}
 	return nil
 }
 
-func promptString(reader *bufio.Reader, prompt, defaultValue string) string {
-	if defaultValue != "" {
-		fmt.Printf("%s [%s]: ", prompt, defaultValue)
-	} else {
-		fmt.Printf("%s: ", prompt)
+func (s *reviewScreen) save(m *setupModel) tea.Cmd {
+	m.cfg.SetupCompleted = true
+	if m.hadIssues {
+		if err := backupConfig(m.path); err != nil {
+			s.err = fmt.Errorf("failed to back up config: %w", err)
+			return nil
+		}
+		m.hadIssues = false
+		s.note = fmt.Sprintf("The previous config was saved to %s.bak", m.path)
+	}
+	if err := saveConfig(m.path, m.cfg); err != nil {
+		s.err = fmt.Errorf("failed to save config: %w", err)
+		return nil
+	}
+	_, issues, err := loadConfigLenient(m.path)
+	if err != nil {
+		s.err = err
+		return nil
 	}
+	if m.issues = issues; len(issues) > 0 {
+		s.err = errors.New("the config was saved but still has problems; fix them above or edit the file")
+		return nil
+	}
+	m.saved = true
+	return tea.Quit
+}
+
+func (s *reviewScreen) view(m *setupModel) string {
+	var b strings.Builder
+	for i, step := range m.steps {
+		mark := "·"
+		switch st := m.status[step.name]; {
+		case st.err != nil:
+			mark = setupErrStyle.Render("✗")
+		case st.checked:
+			mark = setupOKStyle.Render("✓")
+		}
+		line := fmt.Sprintf("%d. %s %-24s %s", i+1, mark, step.title, step.summary(m.cfg))
+		if i == s.cursor {
+			b.WriteString(setupFocusStyle.Render("> ") + line + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	if s.phase == reviewConfirm {
+		b.WriteString("\nA test announcement was sent to the chat. Did it arrive and look right? (y/n)\n")
+	}
+	if s.note != "" {
+		b.WriteString("\n" + s.note + "\n")
+	}
+	if s.err != nil {
+		b.WriteString("\n" + setupErrStyle.Render("✗ "+s.err.Error()) + "\n")
+	}
+	return b.String()
+}
+
+func (s *reviewScreen) help() string {
+	if s.phase == reviewConfirm {
+		return "y yes · n no"
+	}
+	return "↑/↓ choose · enter edit · s save · q quit without saving"
+}
+
+func backupConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+".bak", data, 0600)
+}
 
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(input)
-	if input == "" {
-		return defaultValue
+func sendSetupTestMessage(cfg *Config) (int, error) {
+	if cfg.Telegram.ChatID == nil {
+		return 0, fmt.Errorf("chat is not configured")
+	}
+	info, _, _ := sampleStream(cfg, time.Now())
+	style := messageStyles[cmp.Or(cfg.Telegram.Style, "default")]
+	caption := "🧪 <b>Test</b>\n\n" + style.Start(info, newMessageFormat(cfg))
+	keyboard := watchKeyboard(getLocalization(cfg.Language).ButtonText, info.URL)
+
+	msgID, err := sendPhotoMessage(cfg.Telegram.BotToken, *cfg.Telegram.ChatID, cfg.Telegram.ThreadID, getThumbnailURL(cfg.Twitch.Channel), caption, keyboard, SendOptions{})
+	if err != nil {
+		msgID, err = sendTextMessageID(cfg.Telegram.BotToken, *cfg.Telegram.ChatID, cfg.Telegram.ThreadID, caption)
 	}
-	return input
+	return msgID, err
 }
 
-func promptRetry(reader *bufio.Reader) bool {
-	fmt.Print("Try again? (y/n): ")
-	input, _ := reader.ReadString('\n')
-	input = strings.ToLower(strings.TrimSpace(input))
-	return input == "y" || input == "yes" || input == ""
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0 && os.Getenv("NO_COLOR") == ""
+}
+
+func maskSecret(s string) string {
+	if len(s) <= 8 {
+		return strings.Repeat("•", len(s))
+	}
+	return s[:4] + "…" + s[len(s)-4:]
 }
 
 func validateTwitchChannel(ctx context.Context, channel, clientID, clientSecret string) bool {
@@ -293,7 +1002,7 @@ func validateTwitchChannel(ctx context.Context, channel, clientID, clientSecret
 	var resp struct {
 		Data []any `json:"data"`
 	}
-	url := fmt.Sprintf("https://api.twitch.tv/helix/users?login=%s", channel)
+	url := fmt.Sprintf("%s/users?login=%s", helixAPI, channel)
 	if err := twitchGet(ctx, url, clientID, clientSecret, &resp); err != nil {
 		return true
 	}
@@ -301,7 +1010,7 @@ func validateTwitchChannel(ctx context.Context, channel, clientID, clientSecret
 }
 
 func validateTwitchCredentials(ctx context.Context, clientID, clientSecret string) error {
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://id.twitch.tv/oauth2/token", nil)
+	req, err := http.NewRequestWithContext(ctx, "POST", twitchAuthAPI+"/oauth2/token", nil)
 	if err != nil {
 		return err
 	}
@@ -312,7 +1021,7 @@ func validateTwitchCredentials(ctx context.Context, clientID, clientSecret strin
 	req.URL.RawQuery = q.Encode()
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := httpClient.Do(req)
+	resp, err := twitchHTTP.Do(req)
 	if err != nil {
 		return err
 	}
@@ -325,13 +1034,13 @@ func validateTwitchCredentials(ctx context.Context, clientID, clientSecret strin
 }
 
 func validateTelegramToken(ctx context.Context, token string) (string, error) {
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/getMe", token)
+	url := fmt.Sprintf("%s/bot%s/getMe", telegramAPI, token)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return "", err
 	}
 
-	resp, err := httpClient.Do(req)
+	resp, err := telegramHTTP.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -355,59 +1064,59 @@ func validateTelegramToken(ctx context.Context, token string) (string, error) {
 	return result.Result.Username, nil
 }
 
-func waitForSetupCommand(ctx context.Context, token string, timeoutSeconds int) (int64, *int, error) {
-	baseURL := fmt.Sprintf("https://api.telegram.org/bot%s", token)
+func describeChat(chat TelegramChat) string {
+	switch {
+	case chat.Title != "":
+		return fmt.Sprintf("%s (Chat ID: %d)", chat.Title, chat.ID)
+	case chat.Username != "":
+		return fmt.Sprintf("@%s (%d)", chat.Username, chat.ID)
+	}
+	return fmt.Sprintf("Chat ID: %d", chat.ID)
+}
+
+type setupCandidate struct {
+	chat     TelegramChat
+	threadID *int
+	confirm  bool
+}
+
+type setupChatWatcher struct {
+	token  string
+	offset int
+	primed bool
+}
+
+func (w *setupChatWatcher) next(ctx context.Context, deadline time.Time) (setupCandidate, error) {
+	baseURL := fmt.Sprintf("%s/bot%s", telegramAPI, w.token)
 	setupClient := &http.Client{Timeout: 35 * time.Second}
 
-	offset := 0
-	if req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/getUpdates?offset=0", baseURL), nil); err == nil {
-		if resp, err := setupClient.Do(req); err == nil {
-			body, _ := io.ReadAll(resp.Body)
-			resp.Body.Close()
-			var updates TelegramResponse
-			if json.Unmarshal(body, &updates) == nil {
-				var list []TelegramUpdate
-				json.Unmarshal(updates.Result, &list)
-				if len(list) > 0 {
-					offset = list[len(list)-1].UpdateID + 1
-				}
-			}
+	if !w.primed {
+		w.primed = true
+		if list, err := w.fetch(ctx, setupClient, fmt.Sprintf("%s/getUpdates?offset=0", baseURL)); err == nil && len(list) > 0 {
+			w.offset = list[len(list)-1].UpdateID + 1
 		}
 	}
 
-	deadline := time.Now().Add(time.Duration(timeoutSeconds) * time.Second)
-
 	for time.Now().Before(deadline) {
-		url := fmt.Sprintf("%s/getUpdates?offset=%d&timeout=30", baseURL, offset)
-		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-		if err != nil {
-			return 0, nil, err
-		}
-
-		resp, err := setupClient.Do(req)
+		reqURL := fmt.Sprintf("%s/getUpdates?offset=%d&timeout=30&allowed_updates=%s", baseURL, w.offset, url.QueryEscape(`["message","my_chat_member"]`))
+		list, err := w.fetch(ctx, setupClient, reqURL)
 		if err != nil {
 			select {
 			case <-ctx.Done():
-				return 0, nil, ctx.Err()
+				return setupCandidate{}, ctx.Err()
 			case <-time.After(2 * time.Second):
 				continue
 			}
 		}
 
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-
-		var updates TelegramResponse
-		if json.Unmarshal(body, &updates) != nil {
-			time.Sleep(2 * time.Second)
-			continue
-		}
-
-		var list []TelegramUpdate
-		json.Unmarshal(updates.Result, &list)
-
 		for _, update := range list {
-			offset = update.UpdateID + 1
+			w.offset = update.UpdateID + 1
+			if m := update.MyChatMember; m != nil {
+				if m.Chat.Type == "private" || m.OldChatMember.CanPost() || !m.NewChatMember.CanPost() {
+					continue
+				}
+				return setupCandidate{chat: m.Chat, confirm: true}, nil
+			}
 			if update.Message == nil || update.Message.Text == "" {
 				continue
 			}
@@ -415,25 +1124,38 @@ func waitForSetupCommand(ctx context.Context, token string, timeoutSeconds int)
 			if text != "SETUP" && text != "/SETUP" {
 				continue
 			}
+			return setupCandidate{chat: update.Message.Chat, threadID: update.Message.MessageThreadID}, nil
+		}
 
-			msg := update.Message
-			chatInfo := fmt.Sprintf("Chat ID: %d", msg.Chat.ID)
-			if msg.Chat.Title != "" {
-				chatInfo = fmt.Sprintf("%s (%s)", msg.Chat.Title, chatInfo)
-			} else if msg.Chat.Username != "" {
-				chatInfo = fmt.Sprintf("@%s (%d)", msg.Chat.Username, msg.Chat.ID)
-			}
-			fmt.Printf("\nReceived SETUP from: %s\n", chatInfo)
-			if msg.MessageThreadID != nil {
-				fmt.Printf("Thread ID: %d\n", *msg.MessageThreadID)
-			}
-			return msg.Chat.ID, msg.MessageThreadID, nil
+		select {
+		case <-ctx.Done():
+			return setupCandidate{}, ctx.Err()
+		case <-time.After(time.Second):
 		}
+	}
 
-		time.Sleep(1 * time.Second)
+	return setupCandidate{}, fmt.Errorf("timeout waiting for SETUP command")
+}
+
+func (w *setupChatWatcher) fetch(ctx context.Context, client *http.Client, reqURL string) ([]TelegramUpdate, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	return 0, nil, fmt.Errorf("timeout waiting for SETUP command")
+	body, _ := io.ReadAll(resp.Body)
+	var updates TelegramResponse
+	if err := json.Unmarshal(body, &updates); err != nil {
+		return nil, err
+	}
+	var list []TelegramUpdate
+	json.Unmarshal(updates.Result, &list)
+	return list, nil
 }
 
 func checkBotPermissions(ctx context.Context, token string, chatID int64) error {
@@ -444,14 +1166,14 @@ func checkBotPermissions(ctx context.Context, token string, chatID int64) error
 		"user_id": botID,
 	})
 
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/getChatMember", token)
+	url := fmt.Sprintf("%s/bot%s/getChatMember", telegramAPI, token)
 	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(payload)))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := httpClient.Do(req)
+	resp, err := telegramHTTP.Do(req)
 	if err != nil {
 		return err
 	}
@@ -480,13 +1202,13 @@ func checkBotPermissions(ctx context.Context, token string, chatID int64) error
 }
 
 func getBotUserID(ctx context.Context, token string) int64 {
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/getMe", token)
+	url := fmt.Sprintf("%s/bot%s/getMe", telegramAPI, token)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return 0
 	}
 
-	resp, err := httpClient.Do(req)
+	resp, err := telegramHTTP.Do(req)
 	if err != nil {
 		return 0
 	}
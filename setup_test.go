@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func pressKeys(t *testing.T, m *setupModel, keys ...string) {
+	t.Helper()
+	for _, k := range keys {
+		var msg tea.KeyMsg
+		switch k {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		_, cmd := m.Update(msg)
+		runSetupCmd(m, cmd)
+	}
+}
+
+func runSetupCmd(m *setupModel, cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	switch msg := cmd().(type) {
+	case tea.BatchMsg:
+		for _, c := range msg {
+			runSetupCmd(m, c)
+		}
+	case setupResultMsg:
+		_, next := m.Update(msg)
+		runSetupCmd(m, next)
+	}
+}
+
+func TestSetupModelWalkthrough(t *testing.T) {
+	var sent []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = append(sent, r.URL.Path)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`))
+	}))
+	defer srv.Close()
+	defer func(old string) { telegramAPI = old }(telegramAPI)
+	telegramAPI = srv.URL
+
+	chatID := int64(-1001)
+	cfg := &Config{SetupCompleted: true}
+	cfg.Twitch.ClientID, cfg.Twitch.ClientSecret, cfg.Twitch.Channel = "id", "secret", "somechannel"
+	cfg.Telegram.BotToken, cfg.Telegram.ChatID = "123:abc", &chatID
+
+	m := newSetupModel(context.Background(), "config.json", cfg, nil)
+	if len(m.queue) != 2 || m.current != setupStepLanguage {
+		t.Fatalf("queue = %v, current = %q", m.queue, m.current)
+	}
+
+	pressKeys(t, m, "2")
+	if cfg.Language != "ru" || m.current != setupStepIntervals {
+		t.Fatalf("language = %q, current = %q", cfg.Language, m.current)
+	}
+	pressKeys(t, m, "esc")
+	if m.current != setupStepLanguage {
+		t.Fatalf("esc went to %q", m.current)
+	}
+	pressKeys(t, m, "enter", "enter", "enter")
+	if m.current != "" || cfg.CheckInterval != 60 || cfg.UpdateInterval != 5 {
+		t.Fatalf("current = %q, intervals = %d/%d", m.current, cfg.CheckInterval, cfg.UpdateInterval)
+	}
+
+	pressKeys(t, m, "s")
+	if len(sent) == 0 {
+		t.Fatal("no test message was sent")
+	}
+	if m.status[setupStepChat].err == nil {
+		t.Fatalf("chat step not marked as failed: %+v", m.status)
+	}
+	for name, st := range m.status {
+		if name != setupStepChat && st.err != nil {
+			t.Fatalf("step %q marked as failed", name)
+		}
+	}
+	if view := m.View(); !strings.Contains(view, "failed to send test message") {
+		t.Fatalf("error not shown:\n%s", view)
+	}
+
+	pressKeys(t, m, "4")
+	if m.current != setupStepChat || m.pos != -1 {
+		t.Fatalf("editing opened %q at %d", m.current, m.pos)
+	}
+	pressKeys(t, m, "esc")
+	if m.current != "" {
+		t.Fatalf("esc from edit went to %q", m.current)
+	}
+}
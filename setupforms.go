@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	setupTitleStyle = lipgloss.NewStyle().Bold(true)
+	setupOKStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	setupErrStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	setupDimStyle   = lipgloss.NewStyle().Faint(true)
+	setupFocusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("5")).Bold(true)
+)
+
+type setupScreen interface {
+	update(m *setupModel, msg tea.Msg) tea.Cmd
+	view(m *setupModel) string
+	help() string
+}
+
+type formField struct {
+	label   string
+	input   textinput.Model
+	check   func(string) error
+	err     error
+	touched bool
+}
+
+type formScreen struct {
+	intro  []string
+	fields []*formField
+	focus  int
+	err    error
+	submit func(m *setupModel, s *formScreen, values []string) tea.Cmd
+	onBack func(m *setupModel) tea.Cmd
+}
+
+func newFormField(label, value string, secret bool, check func(string) error) *formField {
+	in := textinput.New()
+	in.Prompt = ""
+	in.CharLimit = 256
+	in.Width = 48
+	in.SetValue(value)
+	if secret {
+		in.EchoMode = textinput.EchoPassword
+		in.EchoCharacter = '•'
+	}
+	f := &formField{label: label, input: in, check: check}
+	if value != "" {
+		f.validate()
+	}
+	return f
+}
+
+func (f *formField) validate() bool {
+	f.touched = true
+	f.err = nil
+	if f.check != nil {
+		f.err = f.check(strings.TrimSpace(f.input.Value()))
+	}
+	return f.err == nil
+}
+
+func newFormScreen(intro []string, fields []*formField, submit func(m *setupModel, s *formScreen, values []string) tea.Cmd) *formScreen {
+	s := &formScreen{intro: intro, fields: fields, submit: submit}
+	s.fields[0].input.Focus()
+	return s
+}
+
+func (s *formScreen) setFocus(i int) tea.Cmd {
+	s.fields[s.focus].input.Blur()
+	s.focus = (i + len(s.fields)) % len(s.fields)
+	return s.fields[s.focus].input.Focus()
+}
+
+func (s *formScreen) values() []string {
+	values := make([]string, len(s.fields))
+	for i, f := range s.fields {
+		values[i] = strings.TrimSpace(f.input.Value())
+	}
+	return values
+}
+
+func (s *formScreen) update(m *setupModel, msg tea.Msg) tea.Cmd {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		var cmd tea.Cmd
+		s.fields[s.focus].input, cmd = s.fields[s.focus].input.Update(msg)
+		return cmd
+	}
+	switch key.String() {
+	case "esc":
+		if s.onBack != nil {
+			return s.onBack(m)
+		}
+		return m.back()
+	case "tab", "down":
+		return s.setFocus(s.focus + 1)
+	case "shift+tab", "up":
+		return s.setFocus(s.focus - 1)
+	case "enter":
+		if !s.fields[s.focus].validate() {
+			return nil
+		}
+		if s.focus < len(s.fields)-1 {
+			return s.setFocus(s.focus + 1)
+		}
+		for i, f := range s.fields {
+			if !f.validate() {
+				return s.setFocus(i)
+			}
+		}
+		s.err = nil
+		return s.submit(m, s, s.values())
+	}
+	var cmd tea.Cmd
+	f := s.fields[s.focus]
+	f.input, cmd = f.input.Update(msg)
+	if f.touched {
+		f.validate()
+	}
+	return cmd
+}
+
+func (s *formScreen) view(m *setupModel) string {
+	var b strings.Builder
+	for _, line := range s.intro {
+		b.WriteString(setupDimStyle.Render(line) + "\n")
+	}
+	if len(s.intro) > 0 {
+		b.WriteString("\n")
+	}
+	width := 0
+	for _, f := range s.fields {
+		width = max(width, lipgloss.Width(f.label))
+	}
+	for i, f := range s.fields {
+		cursor, label := "  ", fmt.Sprintf("%-*s", width, f.label)
+		if i == s.focus {
+			cursor, label = setupFocusStyle.Render("> "), setupFocusStyle.Render(label)
+		}
+		b.WriteString(cursor + label + "  " + f.input.View() + "  " + fieldStatus(f) + "\n")
+	}
+	if s.err != nil {
+		b.WriteString("\n" + setupErrStyle.Render("✗ "+s.err.Error()) + "\n")
+	}
+	return b.String()
+}
+
+func (s *formScreen) help() string {
+	return "enter next · tab switch field · esc back"
+}
+
+func fieldStatus(f *formField) string {
+	switch {
+	case !f.touched:
+		return ""
+	case f.err != nil:
+		return setupErrStyle.Render("✗ " + f.err.Error())
+	}
+	return setupOKStyle.Render("✓")
+}
+
+func requireValue(v string) error {
+	if v == "" {
+		return errors.New("a value is required")
+	}
+	return nil
+}
+
+func checkInt(lo, hi int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < lo || n > hi {
+			return fmt.Errorf("enter a number from %d to %d", lo, hi)
+		}
+		return nil
+	}
+}
+
+type selectScreen struct {
+	intro   []string
+	options []string
+	cursor  int
+	choose  func(m *setupModel, i int) tea.Cmd
+	onBack  func(m *setupModel) tea.Cmd
+}
+
+func (s *selectScreen) update(m *setupModel, msg tea.Msg) tea.Cmd {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return nil
+	}
+	switch key.String() {
+	case "esc":
+		if s.onBack != nil {
+			return s.onBack(m)
+		}
+		return m.back()
+	case "up", "k":
+		s.cursor = (s.cursor + len(s.options) - 1) % len(s.options)
+	case "down", "j", "tab":
+		s.cursor = (s.cursor + 1) % len(s.options)
+	case "enter":
+		return s.choose(m, s.cursor)
+	default:
+		if n, err := strconv.Atoi(key.String()); err == nil && n >= 1 && n <= len(s.options) {
+			s.cursor = n - 1
+			return s.choose(m, s.cursor)
+		}
+	}
+	return nil
+}
+
+func (s *selectScreen) view(m *setupModel) string {
+	var b strings.Builder
+	for _, line := range s.intro {
+		b.WriteString(setupDimStyle.Render(line) + "\n")
+	}
+	if len(s.intro) > 0 {
+		b.WriteString("\n")
+	}
+	for i, opt := range s.options {
+		if i == s.cursor {
+			b.WriteString(setupFocusStyle.Render(fmt.Sprintf("> %d. %s", i+1, opt)) + "\n")
+		} else {
+			b.WriteString(fmt.Sprintf("  %d. %s\n", i+1, opt))
+		}
+	}
+	return b.String()
+}
+
+func (s *selectScreen) help() string {
+	return "↑/↓ choose · enter select · esc back"
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type ShortenerConfig struct {
+	URL    string `json:"url"`
+	APIKey string `json:"api_key"`
+	Domain string `json:"domain"`
+}
+
+type Shortener struct {
+	cfg ShortenerConfig
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+func newShortener(cfg ShortenerConfig) *Shortener {
+	if cfg.URL == "" {
+		return nil
+	}
+	return &Shortener{cfg: cfg, cache: map[string]string{}}
+}
+
+func (s *Shortener) ShortenClips(ctx context.Context, clips []ClipInfo) []ClipInfo {
+	if s == nil || len(clips) == 0 {
+		return clips
+	}
+	out := make([]ClipInfo, len(clips))
+	for i, c := range clips {
+		out[i] = c
+		short, err := s.Shorten(ctx, c.URL)
+		if err != nil {
+			slog.Warn("failed to shorten clip URL", "url", c.URL, "error", err)
+			continue
+		}
+		out[i].URL = short
+	}
+	return out
+}
+
+func (s *Shortener) Shorten(ctx context.Context, long string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if short, ok := s.cache[long]; ok {
+		return short, nil
+	}
+
+	payload := map[string]any{"longUrl": long, "findIfExists": true}
+	if s.cfg.Domain != "" {
+		payload["domain"] = s.cfg.Domain
+	}
+	body, _ := json.Marshal(payload)
+	endpoint := strings.TrimSuffix(s.cfg.URL, "/") + "/rest/v3/short-urls"
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", s.cfg.APIKey)
+
+	resp, err := externalHTTP.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("shortener error (%d): %s", resp.StatusCode, data)
+	}
+	var result struct {
+		ShortURL string `json:"shortUrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if result.ShortURL == "" {
+		return "", fmt.Errorf("shortener returned no URL")
+	}
+	s.cache[long] = result.ShortURL
+	return result.ShortURL, nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+type Scenario struct {
+	IntervalSeconds int            `json:"interval_seconds"`
+	UpdateEvery     int            `json:"update_every"`
+	BroadcasterID   string         `json:"broadcaster_id"`
+	Steps           []ScenarioStep `json:"steps"`
+}
+
+type ScenarioStep struct {
+	Viewers int      `json:"viewers"`
+	Game    string   `json:"game"`
+	Title   string   `json:"title"`
+	Tags    []string `json:"tags"`
+	Offline bool     `json:"offline"`
+	Repeat  int      `json:"repeat"`
+}
+
+func loadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sc Scenario
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario %s: %w", path, err)
+	}
+	if sc.IntervalSeconds == 0 {
+		sc.IntervalSeconds = 5
+	}
+	if sc.UpdateEvery == 0 {
+		sc.UpdateEvery = 1
+	}
+	if sc.IntervalSeconds < 0 || sc.UpdateEvery < 0 {
+		return nil, errors.New("scenario interval_seconds and update_every must be positive")
+	}
+	if len(sc.Steps) == 0 {
+		return nil, errors.New("scenario has no steps")
+	}
+	for i, step := range sc.Steps {
+		if step.Viewers < 0 || step.Repeat < 0 {
+			return nil, fmt.Errorf("steps[%d]: viewers and repeat must not be negative", i)
+		}
+	}
+	return &sc, nil
+}
+
+type ScenarioSource struct {
+	cfg           *Config
+	broadcasterID string
+	states        []*StreamInfo
+	pos           int
+	liveChecks    int
+	startedAt     time.Time
+	done          func()
+}
+
+func newScenarioSource(cfg *Config, sc *Scenario, done func()) *ScenarioSource {
+	var states []*StreamInfo
+	current := StreamInfo{
+		Channel: cfg.Twitch.Channel,
+		URL:     fmt.Sprintf("https://twitch.tv/%s", cfg.Twitch.Channel),
+	}
+	for _, step := range sc.Steps {
+		var state *StreamInfo
+		if !step.Offline {
+			current.Viewers = step.Viewers
+			if step.Game != "" {
+				current.Game = step.Game
+			}
+			if step.Title != "" {
+				current.Title = step.Title
+			}
+			if step.Tags != nil {
+				current.Tags = step.Tags
+			}
+			info := current
+			state = &info
+		}
+		for range max(step.Repeat, 1) {
+			states = append(states, state)
+		}
+	}
+	if states[len(states)-1] != nil {
+		states = append(states, nil)
+	}
+	return &ScenarioSource{cfg: cfg, broadcasterID: sc.BroadcasterID, states: states, done: done}
+}
+
+func (s *ScenarioSource) Stream(ctx context.Context) (*StreamInfo, error) {
+	if s.pos >= len(s.states) {
+		slog.Info("scenario finished")
+		s.done()
+		return nil, nil
+	}
+	state := s.states[s.pos]
+	s.pos++
+	if state == nil {
+		slog.Info("simulation step", "step", s.pos, "of", len(s.states), "live", false)
+		s.liveChecks = 0
+		s.startedAt = time.Time{}
+		return nil, nil
+	}
+	if s.startedAt.IsZero() {
+		s.startedAt = time.Now()
+	}
+	info := *state
+	info.StartedAt = s.startedAt
+	info.Uptime = formatDuration(time.Duration(s.liveChecks*s.cfg.CheckInterval)*time.Second, s.cfg.Language)
+	s.liveChecks++
+	slog.Info("simulation step", "step", s.pos, "of", len(s.states), "live", true, "viewers", info.Viewers, "game", info.Game)
+	return &info, nil
+}
+
+func (s *ScenarioSource) BroadcasterID(ctx context.Context) (string, error) {
+	if s.broadcasterID != "" {
+		return s.broadcasterID, nil
+	}
+	return twitchSource{s.cfg}.BroadcasterID(ctx)
+}
+
+func simulateCommand(configPath string, args []string) error {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	scenarioPath := fs.String("scenario", "", "Scenario file with the scripted stream states")
+	chatID := fs.Int64("chat", 0, "Test chat ID to post to (defaults to telegram.admin_chat_id)")
+	threadID := fs.Int("thread", 0, "Topic ID in the test chat")
+	fs.Parse(args)
+	if *scenarioPath == "" {
+		return errors.New("--scenario is required")
+	}
+
+	sc, err := loadScenario(*scenarioPath)
+	if err != nil {
+		return err
+	}
+	base, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	cfg := *base
+	switch {
+	case *chatID != 0:
+		cfg.Telegram.ChatID = chatID
+	case base.Telegram.AdminChatID != nil:
+		cfg.Telegram.ChatID = base.Telegram.AdminChatID
+	default:
+		return errors.New("--chat is required when telegram.admin_chat_id is not set")
+	}
+	cfg.Telegram.ThreadID = nil
+	if *threadID != 0 {
+		cfg.Telegram.ThreadID = threadID
+	}
+	cfg.CheckInterval = 60
+	cfg.SampleInterval = 1
+	cfg.UpdateInterval = sc.UpdateEvery
+	cfg.AnnounceDelay = 0
+
+	configureEndpoints(cfg.HTTP.Endpoints)
+	if err := configureHTTPClient(twitchHTTP, cfg.HTTP.Twitch); err != nil {
+		return err
+	}
+	if err := configureHTTPClient(telegramHTTP, cfg.HTTP.Telegram); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	bus := &EventBus{}
+	bus.Subscribe(newTelegramNotifier(&cfg, nil, nil, nil, nil).Handle)
+	if len(cfg.Telegram.Milestones) > 0 {
+		bus.Subscribe(newMilestoneReactor(&cfg).Handle)
+	}
+	bus.Subscribe(logStreamStats)
+
+	control := newMonitorControl()
+	go func() {
+		ticker := time.NewTicker(time.Duration(sc.IntervalSeconds) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				control.Wake()
+			}
+		}
+	}()
+
+	slog.Info("starting simulation", "scenario", *scenarioPath, "steps", len(sc.Steps), "chat_id", *cfg.Telegram.ChatID)
+	monitorLoop(ctx, &cfg, newScenarioSource(&cfg, sc, cancel), bus, control)
+	return nil
+}
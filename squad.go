@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+)
+
+type SquadConfig struct {
+	Enabled bool `json:"enabled"`
+	Buttons bool `json:"buttons"`
+}
+
+type squadCache struct {
+	ids    map[string]string
+	logins []string
+}
+
+func (n *TelegramNotifier) resolveSquad(ctx context.Context, ev Event) {
+	cfg := n.cfg
+	if !cfg.Squad.Enabled || ev.Info == nil {
+		return
+	}
+	ids, err := getSharedChatParticipants(ctx, ev.Session.BroadcasterID, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+	if err != nil {
+		slog.Warn("failed to get squad members", "error", err)
+		n.mu.Lock()
+		ev.Info.Squad = n.squad.logins
+		n.mu.Unlock()
+		return
+	}
+
+	var unknown []string
+	n.mu.Lock()
+	for _, id := range ids {
+		if _, ok := n.squad.ids[id]; !ok && id != ev.Session.BroadcasterID {
+			unknown = append(unknown, id)
+		}
+	}
+	n.mu.Unlock()
+	var users []TwitchUser
+	if len(unknown) > 0 {
+		users, err = getTwitchUsers(ctx, "id", unknown, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+		if err != nil {
+			slog.Warn("failed to look up squad members", "ids", unknown, "error", err)
+		}
+		for _, u := range users {
+			cacheTwitchUser(u)
+		}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, u := range users {
+		if n.squad.ids == nil {
+			n.squad.ids = map[string]string{}
+		}
+		n.squad.ids[u.ID] = u.Login
+	}
+
+	var logins []string
+	for _, id := range ids {
+		if id == ev.Session.BroadcasterID {
+			continue
+		}
+		if login, ok := n.squad.ids[id]; ok {
+			logins = append(logins, login)
+		}
+	}
+
+	if len(logins) != len(n.squad.logins) {
+		slog.Info("squad changed", "members", logins)
+	}
+	n.squad.logins = logins
+	ev.Info.Squad = logins
+}
+
+func squadKeyboard(members []string) [][]InlineButton {
+	var rows [][]InlineButton
+	for i, m := range members {
+		if i%3 == 0 {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], InlineButton{Text: m, URL: "https://twitch.tv/" + m})
+	}
+	return rows
+}
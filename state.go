@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+type StoredToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type StoredAnnouncement struct {
+	ChatID    int64     `json:"chat_id"`
+	MessageID int       `json:"message_id"`
+	Bot       int       `json:"bot,omitempty"`
+	ThreadID  *int      `json:"thread_id,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+type State struct {
+	Tokens        map[string]StoredToken        `json:"tokens,omitempty"`
+	Announcements map[string]StoredAnnouncement `json:"announcements,omitempty"`
+	QuietPostedAt time.Time                     `json:"quiet_posted_at,omitzero"`
+}
+
+const stateFileMode = 0600
+
+type StateStore struct {
+	mu   sync.Mutex
+	path string
+	data State
+}
+
+var stateStore *StateStore
+
+func openStateStore(path string) (*StateStore, error) {
+	s := &StateStore{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.data); err != nil {
+		return nil, err
+	}
+	if fi, err := os.Stat(path); err == nil && fi.Mode().Perm()&^stateFileMode != 0 {
+		slog.Warn("state file is readable by other users, restricting permissions", "path", path, "mode", fi.Mode().Perm())
+		if err := os.Chmod(path, stateFileMode); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *StateStore) View(fn func(*State)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.data)
+}
+
+func (s *StateStore) Update(fn func(*State)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.data)
+
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	os.Remove(tmp)
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, stateFileMode)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
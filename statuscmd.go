@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+type StatusCommands struct {
+	cfg     *Config
+	format  MessageFormat
+	archive *SessionArchive
+	live    *LiveState
+
+	mu            sync.Mutex
+	broadcasterID string
+}
+
+func newStatusCommands(cfg *Config, archive *SessionArchive, live *LiveState) *StatusCommands {
+	return &StatusCommands{cfg: cfg, format: newMessageFormat(cfg), archive: archive, live: live}
+}
+
+func (s *StatusCommands) Register(r *CommandRouter) {
+	r.Handle(Command{Name: "status", Handler: s.Status})
+	r.Handle(Command{Name: "stats", Handler: s.Stats})
+	r.Handle(Command{Name: "schedule", Handler: s.Schedule})
+	r.Handle(Command{Name: "chart", Handler: s.Chart})
+}
+
+func (s *StatusCommands) Status(ctx context.Context, msg *TelegramMessage, args string) (string, error) {
+	lang := replyLanguage(s.cfg, msg)
+	snap, ok := s.live.Snapshot()
+	if !ok {
+		return fmt.Sprintf("<b>%s</b> — %s", escapeHTML(s.cfg.Twitch.Channel), getReplyLocalization(lang).Offline), nil
+	}
+	return formatLiveMessage(LiveSummary{Info: &snap.Info, AvgViewers: snap.AvgViewers(), History: snap.ViewerHistory}, s.replyFormat(lang)), nil
+}
+
+func (s *StatusCommands) Chart(ctx context.Context, msg *TelegramMessage, args string) (string, error) {
+	lang := replyLanguage(s.cfg, msg)
+	mf := s.replyFormat(lang)
+	var history []ViewerDataPoint
+	var marks []StreamMark
+	var caption string
+	if snap, ok := s.live.Snapshot(); ok {
+		history, marks = snap.ViewerHistory, snap.Marks
+		caption = fmt.Sprintf("<b>%s</b>\n%s", escapeHTML(snap.Info.Channel), formatLiveStats(&snap.Info, snap.AvgViewers(), snap.ViewerHistory, mf))
+		if r := formatViewerRange(history, mf); r != "" {
+			caption += "\n" + r
+		}
+	} else {
+		records, err := s.archive.Load(ctx)
+		if err != nil {
+			return "", err
+		}
+		if len(records) == 0 {
+			return getReplyLocalization(lang).NoSessions, nil
+		}
+		rec := records[len(records)-1]
+		history, marks = rec.ViewerHistory, rec.Marks
+		caption = formatEndMessage(EndSummary{
+			Channel:    rec.Channel,
+			Duration:   formatDuration(rec.EndTime.Sub(rec.StartTime), lang),
+			AvgViewers: rec.AvgViewers,
+			MaxViewers: rec.PeakViewers,
+			PeakAt:     formatDuration(rec.PeakAt.Sub(rec.StartTime), lang),
+			Game:       rec.Game,
+			Marks:      markEntries(rec.Marks, rec.StartTime, lang),
+		}, mf)
+	}
+	if len(history) < 2 {
+		return getReplyLocalization(lang).NoChartData, nil
+	}
+	data, err := renderViewerChart(history, marks)
+	if err != nil {
+		return "", err
+	}
+	if err := sendFileReply(s.cfg.Telegram.BotToken, "sendPhoto", "photo", msg.Chat.ID, msg.MessageID, "chart.png", data, caption); err != nil {
+		return "", err
+	}
+	return "", nil
+}
+
+func (s *StatusCommands) Stats(ctx context.Context, msg *TelegramMessage, args string) (string, error) {
+	records, err := s.archive.Load(ctx)
+	if err != nil {
+		return "", err
+	}
+	lang := replyLanguage(s.cfg, msg)
+	if len(records) == 0 {
+		return getReplyLocalization(lang).NoSessions, nil
+	}
+	rec := records[len(records)-1]
+	text := formatEndMessage(EndSummary{
+		Channel:    rec.Channel,
+		Duration:   formatDuration(rec.EndTime.Sub(rec.StartTime), lang),
+		AvgViewers: rec.AvgViewers,
+		MaxViewers: rec.PeakViewers,
+		PeakAt:     formatDuration(rec.PeakAt.Sub(rec.StartTime), lang),
+		Retention:  rec.Retention,
+		Watched:    rec.Watched(),
+		Game:       rec.Game,
+		Title:      rec.Title,
+		History:    rec.ViewerHistory,
+	}, s.replyFormat(lang))
+
+	rl := getReplyLocalization(lang)
+	now := clock.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	week := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	text += fmt.Sprintf("\n\n⏱ %s: %s · %s: %s",
+		rl.WatchedWeek, formatHours(watchedSince(records, week)),
+		rl.WatchedMonth, formatHours(watchedSince(records, month)))
+	return text, nil
+}
+
+func (s *StatusCommands) Schedule(ctx context.Context, msg *TelegramMessage, args string) (string, error) {
+	cfg := s.cfg
+	s.mu.Lock()
+	broadcasterID := s.broadcasterID
+	s.mu.Unlock()
+	if snap, ok := s.live.Snapshot(); ok {
+		broadcasterID = snap.BroadcasterID
+	}
+	if broadcasterID == "" {
+		id, err := getBroadcasterID(ctx, cfg.Twitch.Channel, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+		if err != nil {
+			return "", err
+		}
+		broadcasterID = id
+		s.mu.Lock()
+		s.broadcasterID = id
+		s.mu.Unlock()
+	}
+
+	segments, err := getSchedule(ctx, broadcasterID, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+	if err != nil {
+		return "", err
+	}
+	lines := formatScheduleLines(segments, clock.Now(), 0, s.replyFormat(replyLanguage(cfg, msg)))
+	if len(lines) == 0 {
+		return replyLocalization(cfg, msg).NoSchedule, nil
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (s *StatusCommands) replyFormat(lang string) MessageFormat {
+	mf := s.format
+	mf.Localization = getLocalization(lang)
+	return mf
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type StatusGraph struct {
+	cfg     *Config
+	archive *SessionArchive
+	live    *LiveState
+
+	mu            sync.Mutex
+	broadcasterID string
+}
+
+func newStatusGraph(cfg *Config, archive *SessionArchive, live *LiveState) *GraphQLHandler {
+	g := &StatusGraph{cfg: cfg, archive: archive, live: live}
+	return &GraphQLHandler{root: g.query}
+}
+
+func (g *StatusGraph) query() gqlObject {
+	return gqlObject{
+		"channel": gqlValue(g.cfg.Twitch.Channel),
+		"live": func(ctx context.Context, args map[string]any) (any, error) {
+			snap, ok := g.live.Snapshot()
+			if !ok {
+				return gqlObject(nil), nil
+			}
+			return g.liveObject(snap), nil
+		},
+		"sessions": func(ctx context.Context, args map[string]any) (any, error) {
+			last, err := gqlIntArg(args, "last", 10)
+			if err != nil {
+				return nil, err
+			}
+			records, err := g.archive.Load(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if last >= 0 && len(records) > last {
+				records = records[len(records)-last:]
+			}
+			objs := make([]gqlObject, 0, len(records))
+			for i := len(records) - 1; i >= 0; i-- {
+				objs = append(objs, g.sessionObject(records[i]))
+			}
+			return objs, nil
+		},
+	}
+}
+
+func (g *StatusGraph) liveObject(snap LiveSnapshot) gqlObject {
+	return gqlObject{
+		"title":        gqlValue(snap.Info.Title),
+		"game":         gqlValue(snap.Info.Game),
+		"viewers":      gqlValue(snap.Info.Viewers),
+		"tags":         gqlValue(snap.Info.Tags),
+		"startedAt":    gqlValue(snap.StartTime.Format(time.RFC3339)),
+		"avgViewers":   gqlValue(snap.AvgViewers()),
+		"peakViewers":  gqlValue(snap.PeakViewers()),
+		"viewerPoints": gqlValue(viewerPointObjects(snap.ViewerHistory)),
+		"clips": func(ctx context.Context, args map[string]any) (any, error) {
+			return g.clips(ctx, snap.BroadcasterID, snap.StartTime, time.Now())
+		},
+	}
+}
+
+func (g *StatusGraph) sessionObject(rec SessionRecord) gqlObject {
+	titles := make([]gqlObject, 0, len(rec.TitleHistory))
+	for _, t := range rec.TitleHistory {
+		titles = append(titles, gqlObject{
+			"time":    gqlValue(t.Timestamp.Format(time.RFC3339)),
+			"title":   gqlValue(t.Title),
+			"viewers": gqlValue(t.Viewers),
+		})
+	}
+	return gqlObject{
+		"startTime":    gqlValue(rec.StartTime.Format(time.RFC3339)),
+		"endTime":      gqlValue(rec.EndTime.Format(time.RFC3339)),
+		"game":         gqlValue(rec.Game),
+		"title":        gqlValue(rec.Title),
+		"avgViewers":   gqlValue(rec.AvgViewers),
+		"peakViewers":  gqlValue(rec.PeakViewers),
+		"peakAt":       gqlValue(rec.PeakAt.Format(time.RFC3339)),
+		"retention":    gqlValue(rec.Retention),
+		"rating":       gqlValue(rec.Rating),
+		"titles":       gqlValue(titles),
+		"viewerPoints": gqlValue(viewerPointObjects(rec.ViewerHistory)),
+		"clips": func(ctx context.Context, args map[string]any) (any, error) {
+			id, err := g.getBroadcasterID(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return g.clips(ctx, id, rec.StartTime, rec.EndTime)
+		},
+	}
+}
+
+func (g *StatusGraph) clips(ctx context.Context, broadcasterID string, start, end time.Time) ([]gqlObject, error) {
+	cfg := g.cfg
+	clips, err := getClipsBetween(ctx, broadcasterID, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clips: %w", err)
+	}
+	objs := make([]gqlObject, 0, len(clips))
+	for _, c := range clips {
+		objs = append(objs, gqlObject{
+			"url":     gqlValue(c.URL),
+			"title":   gqlValue(c.Title),
+			"creator": gqlValue(c.Creator),
+			"views":   gqlValue(c.Views),
+		})
+	}
+	return objs, nil
+}
+
+func (g *StatusGraph) getBroadcasterID(ctx context.Context) (string, error) {
+	if snap, ok := g.live.Snapshot(); ok {
+		return snap.BroadcasterID, nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.broadcasterID == "" {
+		cfg := g.cfg
+		id, err := getBroadcasterID(ctx, cfg.Twitch.Channel, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+		if err != nil {
+			return "", err
+		}
+		g.broadcasterID = id
+	}
+	return g.broadcasterID, nil
+}
+
+func viewerPointObjects(history []ViewerDataPoint) []gqlObject {
+	objs := make([]gqlObject, 0, len(history))
+	for _, p := range history {
+		objs = append(objs, gqlObject{
+			"time":  gqlValue(p.Timestamp.Format(time.RFC3339)),
+			"count": gqlValue(p.Count),
+		})
+	}
+	return objs
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SteamConfig struct {
+	Enabled bool              `json:"enabled"`
+	Search  bool              `json:"search"`
+	Map     map[string]string `json:"map"`
+}
+
+type SteamResolver struct {
+	cfg SteamConfig
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+func newSteamResolver(cfg SteamConfig) *SteamResolver {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &SteamResolver{cfg: cfg, cache: map[string]string{}}
+}
+
+func (s *SteamResolver) StoreURL(game string) string {
+	if game == "" {
+		return ""
+	}
+	for name, v := range s.cfg.Map {
+		if strings.EqualFold(name, game) {
+			return steamURL(v)
+		}
+	}
+	if !s.cfg.Search {
+		return ""
+	}
+
+	key := strings.ToLower(game)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.cache[key]; ok {
+		return v
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	storeURL, err := searchSteam(ctx, game)
+	if err != nil {
+		slog.Warn("steam search failed", "game", game, "error", err)
+		return ""
+	}
+	s.cache[key] = storeURL
+	return storeURL
+}
+
+func steamURL(v string) string {
+	if strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://") {
+		return v
+	}
+	return fmt.Sprintf("https://store.steampowered.com/app/%s/", v)
+}
+
+func searchSteam(ctx context.Context, game string) (string, error) {
+	reqURL := "https://store.steampowered.com/api/storesearch/?l=english&cc=US&term=" + url.QueryEscape(game)
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := externalHTTP.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("steam API error (%d)", resp.StatusCode)
+	}
+
+	var result struct {
+		Items []struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	for _, item := range result.Items {
+		if strings.EqualFold(item.Name, game) {
+			return fmt.Sprintf("https://store.steampowered.com/app/%d/", item.ID), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"log/slog"
+)
+
+const (
+	storyWidth  = 1080
+	storyHeight = 1920
+)
+
+type StoryConfig struct {
+	Enabled              bool   `json:"enabled"`
+	BusinessConnectionID string `json:"business_connection_id"`
+	ActiveHours          int    `json:"active_hours"`
+}
+
+type StoryArea struct {
+	X, Y, Width, Height float64
+}
+
+func storyImage(thumbnail []byte) ([]byte, StoryArea, error) {
+	src, err := jpeg.Decode(bytes.NewReader(thumbnail))
+	if err != nil {
+		return nil, StoryArea{}, err
+	}
+	scaled := scaleImage(src, storyWidth)
+	b := scaled.Bounds()
+	canvas := image.NewRGBA(image.Rect(0, 0, storyWidth, storyHeight))
+	draw.Draw(canvas, canvas.Bounds(), image.Black, image.Point{}, draw.Src)
+	offset := image.Pt((storyWidth-b.Dx())/2, (storyHeight-b.Dy())/2)
+	draw.Draw(canvas, b.Sub(b.Min).Add(offset), scaled, b.Min, draw.Src)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, canvas, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, StoryArea{}, err
+	}
+	area := StoryArea{
+		X:      50,
+		Y:      50,
+		Width:  100 * float64(b.Dx()) / storyWidth,
+		Height: 100 * float64(b.Dy()) / storyHeight,
+	}
+	return buf.Bytes(), area, nil
+}
+
+func (n *TelegramNotifier) postStory(ctx context.Context, ev Event) {
+	cfg := n.cfg
+	thumbnail, err := downloadImage(ctx, getThumbnailURL(ev.Channel))
+	if err != nil {
+		slog.Warn("failed to download story thumbnail", "error", err)
+		return
+	}
+	photo, area, err := storyImage(thumbnail)
+	if err != nil {
+		slog.Warn("failed to prepare story image", "error", err)
+		return
+	}
+
+	story := cfg.Telegram.Story
+	caption := formatCompactStart(ev.Info, n.format)
+	err = retryWithBackoff(ctx, cfg.HTTP.Telegram.Retry, func() error {
+		return postStory(cfg.Telegram.BotToken, story.BusinessConnectionID, photo, caption, watchURL(cfg, ev.Info), area, story.ActiveHours*3600)
+	}, "post story")
+	if err != nil {
+		slog.Error("failed to post story", "error", err)
+		return
+	}
+	slog.Info("story posted", "active_hours", story.ActiveHours)
+}
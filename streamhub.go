@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var streamHub *StreamHub
+
+type StreamHub struct {
+	cfg      *Config
+	channels []string
+
+	mu       sync.Mutex
+	polledAt time.Time
+	polling  chan struct{}
+	err      error
+	streams  map[string]*StreamInfo
+}
+
+func newStreamHub(cfg *Config, channels []string) *StreamHub {
+	return &StreamHub{cfg: cfg, channels: channels}
+}
+
+func (h *StreamHub) Source(channel string) StreamSource {
+	return hubSource{hub: h, channel: strings.ToLower(channel)}
+}
+
+func (h *StreamHub) refresh(ctx context.Context) error {
+	fresh := time.Duration(h.cfg.CheckInterval) * time.Second / 2
+	for {
+		h.mu.Lock()
+		if !h.polledAt.IsZero() && clock.Now().Sub(h.polledAt) < fresh {
+			err := h.err
+			h.mu.Unlock()
+			return err
+		}
+		if h.polling == nil {
+			h.polling = make(chan struct{})
+			h.mu.Unlock()
+			h.poll(ctx)
+			return h.lastErr()
+		}
+		polling := h.polling
+		h.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-polling:
+		}
+	}
+}
+
+func (h *StreamHub) poll(ctx context.Context) {
+	cfg := h.cfg
+	ctx, span := startSpan(ctx, "poll streams", spanKindInternal)
+	span.SetAttr("twitch.channels", len(h.channels))
+	streams, err := getStreams(ctx, h.channels, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+	span.End(err)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	close(h.polling)
+	h.polling = nil
+	h.polledAt = clock.Now()
+	h.err = err
+	if err != nil {
+		slog.Error("failed to poll streams", "channels", len(h.channels), "error", err)
+		return
+	}
+	h.streams = make(map[string]*StreamInfo, len(streams))
+	for _, s := range streams {
+		h.streams[strings.ToLower(s.UserLogin)] = newStreamInfo(s, cfg.Language)
+	}
+	slog.Debug("streams polled", "channels", len(h.channels), "live", len(streams))
+}
+
+func (h *StreamHub) lastErr() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.err
+}
+
+type hubSource struct {
+	hub     *StreamHub
+	channel string
+}
+
+func (s hubSource) Stream(ctx context.Context) (*StreamInfo, error) {
+	h := s.hub
+	if err := h.refresh(ctx); err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	info := h.streams[s.channel]
+	if info == nil {
+		return nil, nil
+	}
+	c := *info
+	c.Tags = slices.Clone(info.Tags)
+	return &c, nil
+}
+
+func (s hubSource) BroadcasterID(ctx context.Context) (string, error) {
+	cfg := s.hub.cfg
+	return getBroadcasterID(ctx, s.channel, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStreamHubOnePollPerTick(t *testing.T) {
+	helix := newFakeHelix(t)
+	c := useManualClock(t, time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC))
+	cfg := loadTestConfig(t, strings.Replace(testConfigJSON, `"language": "en",`, `"language": "en", "channels": [{"channel": "OtherChannel", "chat_id": -200}, {"channel": "somechannel", "chat_id": -300}],`, 1))
+	helix.AddUser("42", "somechannel")
+	helix.AddUser("43", "otherchannel")
+	helix.SetLive("otherchannel", TwitchStream{Title: "Other", ViewerCount: 5, StartedAt: c.Now()})
+
+	hub := newStreamHub(cfg, monitoredChannels(cfg))
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for _, t := range append([]ChannelTarget{{Channel: cfg.Twitch.Channel, ChatID: -100500}}, cfg.Channels...) {
+		target := targetConfig(cfg, t)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			monitorLoop(ctx, target, hub.Source(target.Twitch.Channel), &EventBus{}, newMonitorControl())
+		}()
+	}
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	const ticks = 4
+	for range ticks {
+		c.BlockUntil(3)
+		c.Advance(time.Duration(cfg.CheckInterval) * time.Second)
+	}
+	c.BlockUntil(3)
+
+	polls := helix.Requests("/helix/streams")
+	if len(polls) != ticks+1 {
+		t.Fatalf("got %d streams requests for %d checks of 3 targets: %v", len(polls), ticks+1, polls)
+	}
+	if !strings.Contains(polls[0], "user_login=otherchannel") || !strings.Contains(polls[0], "user_login=somechannel") {
+		t.Fatalf("poll does not cover every channel: %s", polls[0])
+	}
+}
+
+func TestStreamHubChunksLogins(t *testing.T) {
+	helix := newFakeHelix(t)
+	useManualClock(t, time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC))
+	cfg := loadTestConfig(t, testConfigJSON)
+
+	var channels []string
+	for i := range 250 {
+		channels = append(channels, fmt.Sprintf("channel%03d", i))
+	}
+	helix.AddUser("7", "channel123")
+	helix.SetLive("channel123", TwitchStream{Title: "Live", ViewerCount: 1})
+
+	hub := newStreamHub(cfg, channels)
+	info, err := hub.Source("Channel123").Stream(context.Background())
+	if err != nil || info == nil || info.Title != "Live" {
+		t.Fatalf("info = %+v, err = %v", info, err)
+	}
+	if info, err := hub.Source("channel124").Stream(context.Background()); err != nil || info != nil {
+		t.Fatalf("offline channel: info = %+v, err = %v", info, err)
+	}
+
+	polls := helix.Requests("/helix/streams")
+	if len(polls) != 3 {
+		t.Fatalf("got %d requests for 250 channels", len(polls))
+	}
+	for _, p := range polls {
+		if n := strings.Count(p, "user_login="); n > helixMaxQueryIDs {
+			t.Fatalf("request has %d logins", n)
+		}
+	}
+}
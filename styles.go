@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type MessageStyle struct {
+	Start func(info *StreamInfo, mf MessageFormat) string
+	Live  func(sum LiveSummary, mf MessageFormat) string
+	End   func(sum EndSummary, mf MessageFormat) string
+}
+
+var messageStyles = map[string]MessageStyle{
+	"default": {
+		Start: formatStartMessage,
+		Live:  formatLiveMessage,
+		End:   formatEndMessage,
+	},
+	"compact": {
+		Start: formatCompactStart,
+		Live:  formatCompactLive,
+		End:   formatCompactEnd,
+	},
+	"detailed": {
+		Start: formatStartMessage,
+		Live:  formatDetailedLive,
+		End:   formatDetailedEnd,
+	},
+}
+
+func formatCompactStart(info *StreamInfo, mf MessageFormat) string {
+	line := formatHeader(info, mf.StartedStreaming, mf)
+	if info.Title != "" {
+		line += " — " + formatTitle(info.Title)
+	}
+	return line
+}
+
+func formatCompactLive(sum LiveSummary, mf MessageFormat) string {
+	line := formatHeader(sum.Info, liveStatus(sum, mf), mf)
+	if sum.Info.Uptime != "" {
+		line += " · " + sum.Info.Uptime
+	}
+	if sum.Info.Viewers > 0 {
+		line += fmt.Sprintf(" · %s %s", formatViewers(sum.Info.Viewers), mf.Viewers)
+	}
+	return line
+}
+
+func formatCompactEnd(sum EndSummary, mf MessageFormat) string {
+	line := fmt.Sprintf("<b>%s</b> • %s", escapeHTML(sum.Channel), mf.StreamEnded)
+	if sum.Duration != "" {
+		line += " · " + sum.Duration
+	}
+	if sum.AvgViewers > 0 {
+		line += fmt.Sprintf(" · %s %s", formatViewers(sum.AvgViewers), mf.Avg)
+	}
+	return line
+}
+
+func formatDetailedLive(sum LiveSummary, mf MessageFormat) string {
+	mf.HourlyGrowth = true
+	msg := formatLiveMessage(sum, mf)
+	if extra := formatViewerRange(sum.History, mf); extra != "" {
+		msg = insertAfterSection(msg, formatLiveStats(sum.Info, sum.AvgViewers, sum.History, mf), extra)
+	}
+	return msg
+}
+
+func formatDetailedEnd(sum EndSummary, mf MessageFormat) string {
+	msg := formatEndMessage(sum, mf)
+	var extra []string
+	if r := formatViewerRange(sum.History, mf); r != "" {
+		extra = append(extra, r)
+	}
+	if change, ok := viewerChange(sum.History, time.Hour); ok {
+		extra = append(extra, fmt.Sprintf("%s %s", formatViewerChange(change), mf.LastHour))
+	}
+	if len(extra) == 0 {
+		return msg
+	}
+	return insertAfterSection(msg, formatEndStats(sum, mf), strings.Join(extra, "\n"))
+}
+
+func formatViewerRange(history []ViewerDataPoint, mf MessageFormat) string {
+	if len(history) < 2 {
+		return ""
+	}
+	lo, hi := history[0].Count, history[0].Count
+	for _, p := range history[1:] {
+		lo = min(lo, p.Count)
+		hi = max(hi, p.Count)
+	}
+	return fmt.Sprintf("📉 %s – 📈 %s %s", formatViewers(lo), formatViewers(hi), mf.Viewers)
+}
+
+func insertAfterSection(msg, section, extra string) string {
+	if section == "" || !strings.Contains(msg, section) {
+		return msg + "\n\n" + extra
+	}
+	return strings.Replace(msg, section, section+"\n"+extra, 1)
+}
+
+func validStyle(name string) bool {
+	_, ok := messageStyles[name]
+	return ok
+}
+
+func styleNames() []string {
+	names := make([]string, 0, len(messageStyles))
+	for name := range messageStyles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const teaserExpiry = time.Hour
+
+type upcomingStream struct {
+	Start time.Time
+	Title string
+	Game  string
+}
+
+type Teaser struct {
+	cfg    *Config
+	format MessageFormat
+	cron   *CronSchedule
+
+	mu            sync.Mutex
+	live          bool
+	messageID     int
+	postedFor     time.Time
+	upcoming      *upcomingStream
+	countdown     string
+	announceAt    time.Time
+	broadcasterID string
+	schedule      []TwitchScheduleSegment
+	fetchedAt     time.Time
+}
+
+func newTeaser(cfg *Config) (*Teaser, error) {
+	t := &Teaser{cfg: cfg, format: newMessageFormat(cfg)}
+	if cfg.Teaser.Source == "cron" {
+		c, err := parseCron(cfg.Teaser.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("invalid teaser cron: %w", err)
+		}
+		t.cron = c
+	}
+	return t, nil
+}
+
+func (t *Teaser) Run(ctx context.Context) {
+	for {
+		t.tick(ctx, clock.Now())
+		sleep(ctx, time.Minute)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (t *Teaser) Handle(ctx context.Context, ev Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch ev.Type {
+	case EventStreamStarted:
+		t.live = true
+		t.announceAt = ev.Time.Add(time.Duration(t.cfg.AnnounceDelay) * time.Second)
+		if t.messageID != 0 && !ev.Time.Before(t.announceAt) {
+			t.finish(ev)
+		}
+	case EventStreamUpdated:
+		if t.messageID != 0 && !ev.Time.Before(t.announceAt) {
+			t.finish(ev)
+		}
+	case EventStreamEnded:
+		t.live = false
+	}
+}
+
+func (t *Teaser) tick(ctx context.Context, now time.Time) {
+	t.mu.Lock()
+	live, messageID, upcoming, shown, postedFor := t.live, t.messageID, t.upcoming, t.countdown, t.postedFor
+	t.mu.Unlock()
+
+	if live {
+		return
+	}
+	if messageID != 0 && now.Before(upcoming.Start) {
+		if countdown, ok := t.updateCountdown(messageID, upcoming, shown, now); ok {
+			t.mu.Lock()
+			if t.messageID == messageID {
+				t.countdown = countdown
+			}
+			t.mu.Unlock()
+		}
+	}
+
+	if messageID != 0 && !now.Before(upcoming.Start.Add(teaserExpiry)) {
+		t.mu.Lock()
+		expired := t.messageID == messageID
+		if expired {
+			t.messageID = 0
+			t.upcoming = nil
+		}
+		t.mu.Unlock()
+		if expired {
+			slog.Info("scheduled stream did not start, removing teaser", "starts_at", upcoming.Start)
+			if err := deleteMessage(t.cfg.Telegram.BotToken, *t.cfg.Telegram.ChatID, messageID); err != nil {
+				slog.Warn("failed to delete teaser", "error", err)
+			}
+		}
+	}
+
+	next, err := t.nextStream(ctx, now)
+	if err != nil {
+		slog.Warn("failed to resolve stream schedule", "error", err)
+		return
+	}
+	if next == nil || !next.Start.After(now) || next.Start.Equal(postedFor) {
+		return
+	}
+	if now.Before(next.Start.Add(-time.Duration(t.cfg.Teaser.MinutesBefore) * time.Minute)) {
+		return
+	}
+
+	cfg := t.cfg
+	user, err := getTwitchUser(ctx, cfg.Twitch.Channel, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+	if err != nil {
+		slog.Warn("failed to get channel info for teaser", "error", err)
+		return
+	}
+	imageURL := user.OfflineImageURL
+	if imageURL == "" {
+		imageURL = user.ProfileImageURL
+	}
+
+	countdown := formatDuration(next.Start.Sub(now), cfg.Language)
+	message := formatTeaserMessage(cfg.Twitch.Channel, next.Title, next.Game, next.Start, countdown, t.format)
+	sentID, err := sendPhotoMessage(
+		cfg.Telegram.BotToken, *cfg.Telegram.ChatID, cfg.Telegram.ThreadID,
+		imageURL, message, watchKeyboard(t.format.ButtonText, trackedURL(cfg.LinkTracking, fmt.Sprintf("https://twitch.tv/%s", cfg.Twitch.Channel))), sendOptionsFor(cfg, nil),
+	)
+	if err != nil {
+		slog.Error("failed to send teaser", "error", err)
+		return
+	}
+
+	t.mu.Lock()
+	stale := t.live
+	previous := 0
+	if !stale {
+		previous = t.messageID
+		t.messageID = sentID
+		t.postedFor = next.Start
+		t.upcoming = next
+		t.countdown = countdown
+	}
+	t.mu.Unlock()
+	if stale {
+		slog.Info("stream started while the teaser was being sent, removing it")
+		if err := deleteMessage(cfg.Telegram.BotToken, *cfg.Telegram.ChatID, sentID); err != nil {
+			slog.Warn("failed to delete teaser", "error", err)
+		}
+		return
+	}
+	if previous != 0 {
+		if err := deleteMessage(cfg.Telegram.BotToken, *cfg.Telegram.ChatID, previous); err != nil {
+			slog.Warn("failed to delete previous teaser", "error", err)
+		}
+	}
+	slog.Info("teaser sent", "starts_at", next.Start)
+}
+
+func (t *Teaser) updateCountdown(messageID int, upcoming *upcomingStream, shown string, now time.Time) (string, bool) {
+	cfg := t.cfg
+	countdown := formatDuration(upcoming.Start.Sub(now), cfg.Language)
+	if countdown == shown {
+		return "", false
+	}
+
+	message := formatTeaserMessage(cfg.Twitch.Channel, upcoming.Title, upcoming.Game, upcoming.Start, countdown, t.format)
+	streamURL := fmt.Sprintf("https://twitch.tv/%s", cfg.Twitch.Channel)
+	if err := editMessageCaption(cfg.Telegram.BotToken, *cfg.Telegram.ChatID, messageID, message, watchKeyboard(t.format.ButtonText, trackedURL(cfg.LinkTracking, streamURL))); err != nil {
+		slog.Warn("failed to update teaser countdown", "error", err)
+		return "", false
+	}
+	return countdown, true
+}
+
+func (t *Teaser) finish(ev Event) {
+	cfg := t.cfg
+	switch cfg.Teaser.OnStart {
+	case "replace", "edit":
+		message := messageStyles[t.cfg.Telegram.Style].Start(ev.Info, t.format)
+		keyboard := watchKeyboard(t.format.ButtonText, trackedURL(cfg.LinkTracking, ev.Info.URL))
+		var err error
+		if cfg.Teaser.OnStart == "replace" {
+			err = editPhotoMessage(
+				cfg.Telegram.BotToken, *cfg.Telegram.ChatID, t.messageID,
+				getThumbnailURL(ev.Channel), message, keyboard, sendOptionsFor(cfg, ev.Info),
+			)
+		} else {
+			err = editMessageCaption(cfg.Telegram.BotToken, *cfg.Telegram.ChatID, t.messageID, message, keyboard)
+		}
+		if err != nil {
+			slog.Warn("failed to convert teaser into announcement", "mode", cfg.Teaser.OnStart, "error", err)
+			break
+		}
+		slog.Info("teaser converted into start notification", "mode", cfg.Teaser.OnStart)
+		ev.Session.MessageID = t.messageID
+		ev.Session.Bot = 0
+		ev.Session.ThreadID = cfg.Telegram.ThreadID
+		rememberAnnouncement(cfg, ev)
+	default:
+		if err := deleteMessage(cfg.Telegram.BotToken, *cfg.Telegram.ChatID, t.messageID); err != nil {
+			slog.Warn("failed to delete teaser", "error", err)
+		}
+	}
+	t.messageID = 0
+}
+
+func (t *Teaser) nextStream(ctx context.Context, now time.Time) (*upcomingStream, error) {
+	if t.cron != nil {
+		start := t.cron.Next(now)
+		if start.IsZero() {
+			return nil, nil
+		}
+		return &upcomingStream{Start: start}, nil
+	}
+
+	cfg := t.cfg
+	if t.broadcasterID == "" {
+		id, err := getBroadcasterID(ctx, cfg.Twitch.Channel, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+		if err != nil {
+			return nil, err
+		}
+		t.broadcasterID = id
+	}
+	if now.Sub(t.fetchedAt) > 15*time.Minute {
+		segments, err := getSchedule(ctx, t.broadcasterID, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+		if err != nil {
+			return nil, err
+		}
+		t.schedule = segments
+		t.fetchedAt = now
+	}
+
+	for _, s := range t.schedule {
+		if s.CanceledUntil != nil || !s.StartTime.After(now) {
+			continue
+		}
+		next := &upcomingStream{Start: s.StartTime, Title: s.Title}
+		if s.Category != nil {
+			next.Game = s.Category.Name
+		}
+		return next, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func useStateStore(t *testing.T) *StateStore {
+	t.Helper()
+	store, err := openStateStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := stateStore
+	stateStore = store
+	t.Cleanup(func() { stateStore = old })
+	return store
+}
+
+func TestTeaserBecomesAnnouncement(t *testing.T) {
+	start := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	useManualClock(t, start)
+	for _, mode := range []string{"replace", "edit"} {
+		t.Run(mode, func(t *testing.T) {
+			newFakeHelix(t)
+			bot := newFakeBotAPI(t)
+			store := useStateStore(t)
+			cfg := loadTestConfig(t, `{
+				"twitch": {"channel": "somechannel"},
+				"telegram": {"bot_token": "123:abc", "chat_id": -100500},
+				"language": "en",
+				"teaser": {"enabled": true, "on_start": "`+mode+`"}
+			}`)
+			teaser, err := newTeaser(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			teaser.messageID = 55
+			bus := &EventBus{}
+			bus.Subscribe(teaser.Handle)
+			bus.Subscribe(newTelegramNotifier(cfg, nil, nil, nil, nil).Handle)
+
+			info := &StreamInfo{Channel: "somechannel", URL: "https://twitch.tv/somechannel", Title: "Live now", StartedAt: start}
+			session := &StreamSession{StartTime: start, BroadcasterID: "42"}
+			bus.Publish(context.Background(), Event{Type: EventStreamStarted, Time: start, Channel: "somechannel", Info: info, Session: session})
+
+			if calls := bot.Calls("sendPhoto", "sendMessage"); len(calls) != 0 {
+				t.Fatalf("a second announcement was posted: %v", calls)
+			}
+			edits := bot.Calls("editMessageCaption", "editMessageMedia")
+			if len(edits) != 1 || edits[0].Params["message_id"] != "55" {
+				t.Fatalf("teaser edits = %v", edits)
+			}
+			if session.MessageID != 55 {
+				t.Fatalf("session message = %d", session.MessageID)
+			}
+			var stored StoredAnnouncement
+			store.View(func(st *State) { stored = st.Announcements[announcementKey(cfg, "somechannel")] })
+			if stored.MessageID != 55 || !stored.StartedAt.Equal(start) {
+				t.Fatalf("stored announcement = %+v", stored)
+			}
+		})
+	}
+}
+
+func TestCronTeaserExpiresWhenStreamNeverStarts(t *testing.T) {
+	now := time.Date(2026, 1, 2, 19, 50, 0, 0, time.UTC)
+	useManualClock(t, now)
+	helix := newFakeHelix(t)
+	helix.AddUser("42", "somechannel")
+	user := helix.users["somechannel"]
+	user.OfflineImageURL = previewsCDN + "/previews-ttv/offline.jpg"
+	helix.users["somechannel"] = user
+	bot := newFakeBotAPI(t)
+	cfg := loadTestConfig(t, `{
+		"twitch": {"channel": "somechannel"},
+		"telegram": {"bot_token": "123:abc", "chat_id": -100500},
+		"language": "en",
+		"teaser": {"enabled": true, "source": "cron", "cron": "0 20 * * *"}
+	}`)
+
+	restarted, err := newTeaser(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted.tick(context.Background(), now.Add(10*time.Minute))
+	if calls := bot.Calls("sendPhoto"); len(calls) != 0 {
+		t.Fatalf("teaser posted for a slot that already started: %v", calls)
+	}
+
+	teaser, err := newTeaser(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	teaser.tick(context.Background(), now)
+	if calls := bot.Calls("sendPhoto"); len(calls) != 1 {
+		t.Fatalf("teaser sends = %v", calls)
+	}
+
+	teaser.tick(context.Background(), now.Add(10*time.Minute+teaserExpiry))
+	deletes := bot.Calls("deleteMessage")
+	if len(deletes) != 1 || deletes[0].Params["message_id"] != "101" {
+		t.Fatalf("expired teaser deletes = %v", deletes)
+	}
+	if teaser.messageID != 0 {
+		t.Fatalf("expired teaser still tracked: %d", teaser.messageID)
+	}
+
+	teaser.tick(context.Background(), now.Add(24*time.Hour))
+	if calls := bot.Calls("sendPhoto"); len(calls) != 2 {
+		t.Fatalf("next day teaser sends = %v", calls)
+	}
+	if len(bot.Calls("deleteMessage")) != 1 {
+		t.Fatalf("unexpected deletes = %v", bot.Calls("deleteMessage"))
+	}
+}
+
+func TestTeaserScheduleCacheFollowsClock(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	useManualClock(t, now)
+	helix := newFakeHelix(t)
+	helix.AddUser("42", "somechannel")
+	cfg := loadTestConfig(t, `{
+		"twitch": {"channel": "somechannel"},
+		"telegram": {"bot_token": "123:abc", "chat_id": -100500},
+		"teaser": {"enabled": true}
+	}`)
+	teaser, err := newTeaser(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []struct {
+		after    time.Duration
+		requests int
+	}{{0, 1}, {10 * time.Minute, 1}, {16 * time.Minute, 2}} {
+		teaser.tick(context.Background(), now.Add(step.after))
+		if got := len(helix.Requests("/helix/schedule")); got != step.requests {
+			t.Fatalf("after %v: %d schedule requests, want %d", step.after, got, step.requests)
+		}
+	}
+}
@@ -4,23 +4,210 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
+const telegramCaptionLimit = 1024
+
+type InlineButton struct {
+	Text         string `json:"text"`
+	URL          string `json:"url,omitempty"`
+	CallbackData string `json:"callback_data,omitempty"`
+}
+
+type SendOptions struct {
+	Spoiler        bool
+	ProtectContent bool
+}
+
+type TelegramChat struct {
+	ID           int64  `json:"id"`
+	Type         string `json:"type"`
+	Title        string `json:"title"`
+	Username     string `json:"username"`
+	LinkedChatID int64  `json:"linked_chat_id"`
+}
+
+type TelegramUser struct {
+	ID           int64  `json:"id"`
+	Username     string `json:"username"`
+	FirstName    string `json:"first_name"`
+	LanguageCode string `json:"language_code"`
+}
+
 type TelegramMessage struct {
-	MessageID int `json:"message_id"`
+	MessageID          int           `json:"message_id"`
+	MessageThreadID    *int          `json:"message_thread_id"`
+	Chat               TelegramChat  `json:"chat"`
+	From               *TelegramUser `json:"from"`
+	Text               string        `json:"text"`
+	IsAutomaticForward bool          `json:"is_automatic_forward"`
+	ForwardOrigin      *struct {
+		Type      string        `json:"type"`
+		Chat      *TelegramChat `json:"chat"`
+		MessageID int           `json:"message_id"`
+	} `json:"forward_origin"`
+	ReplyToMessage *TelegramMessage `json:"reply_to_message"`
 }
 
 type TelegramResponse struct {
-	Ok     bool            `json:"ok"`
-	Result json.RawMessage `json:"result"`
+	Ok          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+	Parameters  *struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+type TelegramError struct {
+	Code        int
+	Description string
+	RetryAfter  int
+}
+
+func (e *TelegramError) Error() string {
+	return fmt.Sprintf("telegram API error (%d): %s", e.Code, e.Description)
+}
+
+func (e *TelegramError) Permanent() bool {
+	switch e.Code {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return true
+	case http.StatusBadRequest:
+		desc := strings.ToLower(e.Description)
+		for _, s := range []string{
+			"chat not found", "message to edit not found", "not enough rights", "have no rights", "bot was kicked", "thread not found", "message can't be edited",
+			"caption is too long", "message is too long", "can't parse entities", "wrong file identifier", "wrong type of the web page content",
+		} {
+			if strings.Contains(desc, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *TelegramError) NoAccess() bool {
+	if e.Code == http.StatusForbidden {
+		return true
+	}
+	if e.Code != http.StatusBadRequest {
+		return false
+	}
+	desc := strings.ToLower(e.Description)
+	for _, s := range []string{"chat not found", "not enough rights", "have no rights", "bot was kicked", "chat_write_forbidden"} {
+		if strings.Contains(desc, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *TelegramError) NotModified() bool {
+	return e.Code == http.StatusBadRequest && strings.Contains(e.Description, "message is not modified")
+}
+
+func (e *TelegramError) EditExpired() bool {
+	return e.Code == http.StatusBadRequest && strings.Contains(e.Description, "message can't be edited")
+}
+
+func (e *TelegramError) MessageNotFound() bool {
+	return e.Code == http.StatusBadRequest && strings.Contains(e.Description, "message to edit not found")
 }
 
-func sendPhotoMessage(token string, chatID int64, threadID *int, photoURL, caption, buttonURL, buttonText string) (int, error) {
+func fitCaption(caption string) string {
+	if _, fits := cutCaption(caption, telegramCaptionLimit); fits {
+		return caption
+	}
+	cut, _ := cutCaption(caption, telegramCaptionLimit-1)
+	return cut
+}
+
+func cutCaption(caption string, limit int) (string, bool) {
+	var (
+		out  strings.Builder
+		open []string
+		n    int
+	)
+	for i := 0; i < len(caption); {
+		if caption[i] == '<' {
+			end := strings.IndexByte(caption[i:], '>')
+			if end < 0 {
+				end = len(caption) - i - 1
+			}
+			tag := caption[i : i+end+1]
+			if strings.HasPrefix(tag, "</") {
+				if len(open) > 0 {
+					open = open[:len(open)-1]
+				}
+			} else {
+				name, _, _ := strings.Cut(strings.Trim(tag, "</>"), " ")
+				open = append(open, name)
+			}
+			out.WriteString(tag)
+			i += end + 1
+			continue
+		}
+		size, units := 1, 1
+		if caption[i] == '&' {
+			if end := strings.IndexByte(caption[i:], ';'); end > 0 {
+				size = end + 1
+			}
+		} else {
+			r, w := utf8.DecodeRuneInString(caption[i:])
+			size, units = w, utf16.RuneLen(r)
+		}
+		if n+units > limit {
+			text := strings.TrimRightFunc(out.String(), unicode.IsSpace)
+			out.Reset()
+			out.WriteString(text + "…")
+			for j := len(open) - 1; j >= 0; j-- {
+				out.WriteString("</" + open[j] + ">")
+			}
+			return out.String(), false
+		}
+		n += units
+		out.WriteString(caption[i : i+size])
+		i += size
+	}
+	return caption, true
+}
+
+func parseTelegramResponse(resp *http.Response) (json.RawMessage, error) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var result TelegramResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, &TelegramError{Code: resp.StatusCode, Description: string(body)}
+		}
+		return nil, err
+	}
+	if !result.Ok {
+		tgErr := &TelegramError{Code: result.ErrorCode, Description: result.Description}
+		if tgErr.Code == 0 {
+			tgErr.Code = resp.StatusCode
+		}
+		if result.Parameters != nil {
+			tgErr.RetryAfter = result.Parameters.RetryAfter
+		}
+		return nil, tgErr
+	}
+	return result.Result, nil
+}
+
+func sendPhotoMessage(token string, chatID int64, threadID *int, photoURL, caption string, keyboard [][]InlineButton, opts SendOptions) (int, error) {
 	ctx := context.Background()
 	imageData, err := downloadImage(ctx, photoURL)
 	if err != nil {
@@ -31,15 +218,20 @@ func sendPhotoMessage(token string, chatID int64, threadID *int, photoURL, capti
 	writer := multipart.NewWriter(&body)
 
 	writer.WriteField("chat_id", fmt.Sprintf("%d", chatID))
-	writer.WriteField("caption", caption)
+	writer.WriteField("caption", fitCaption(caption))
 	writer.WriteField("parse_mode", "HTML")
 
 	if threadID != nil {
 		writer.WriteField("message_thread_id", fmt.Sprintf("%d", *threadID))
 	}
-	if buttonURL != "" {
-		keyboard := buildKeyboard(buttonText, buttonURL)
-		kb, _ := json.Marshal(keyboard)
+	if opts.Spoiler {
+		writer.WriteField("has_spoiler", "true")
+	}
+	if opts.ProtectContent {
+		writer.WriteField("protect_content", "true")
+	}
+	if len(keyboard) > 0 {
+		kb, _ := json.Marshal(buildKeyboard(keyboard))
 		writer.WriteField("reply_markup", string(kb))
 	}
 
@@ -47,31 +239,27 @@ func sendPhotoMessage(token string, chatID int64, threadID *int, photoURL, capti
 	part.Write(imageData)
 	writer.Close()
 
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendPhoto", token)
+	url := fmt.Sprintf("%s/bot%s/sendPhoto", telegramAPI, token)
 	req, _ := http.NewRequest("POST", url, &body)
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	resp, err := httpClient.Do(req)
+	resp, err := telegramHTTP.Do(req)
 	if err != nil {
 		return 0, err
 	}
 	defer resp.Body.Close()
 
-	respBody, _ := io.ReadAll(resp.Body)
-	var result TelegramResponse
-	if err := json.Unmarshal(respBody, &result); err != nil {
+	result, err := parseTelegramResponse(resp)
+	if err != nil {
 		return 0, err
 	}
-	if !result.Ok {
-		return 0, fmt.Errorf("telegram API error: %s", string(respBody))
-	}
 
 	var msg TelegramMessage
-	json.Unmarshal(result.Result, &msg)
+	json.Unmarshal(result, &msg)
 	return msg.MessageID, nil
 }
 
-func editPhotoMessage(token string, chatID int64, messageID int, photoURL, caption, buttonURL, buttonText string) error {
+func editPhotoMessage(token string, chatID int64, messageID int, photoURL, caption string, keyboard [][]InlineButton, opts SendOptions) error {
 	ctx := context.Background()
 	imageData, err := downloadImage(ctx, photoURL)
 	if err != nil {
@@ -79,16 +267,18 @@ func editPhotoMessage(token string, chatID int64, messageID int, photoURL, capti
 	}
 
 	type mediaObject struct {
-		Type      string `json:"type"`
-		Media     string `json:"media"`
-		Caption   string `json:"caption"`
-		ParseMode string `json:"parse_mode"`
+		Type       string `json:"type"`
+		Media      string `json:"media"`
+		Caption    string `json:"caption"`
+		ParseMode  string `json:"parse_mode"`
+		HasSpoiler bool   `json:"has_spoiler,omitempty"`
 	}
 	mediaJSON, _ := json.Marshal(mediaObject{
-		Type:      "photo",
-		Media:     "attach://photo",
-		Caption:   caption,
-		ParseMode: "HTML",
+		Type:       "photo",
+		Media:      "attach://photo",
+		Caption:    fitCaption(caption),
+		ParseMode:  "HTML",
+		HasSpoiler: opts.Spoiler,
 	})
 
 	var body bytes.Buffer
@@ -98,8 +288,8 @@ func editPhotoMessage(token string, chatID int64, messageID int, photoURL, capti
 	writer.WriteField("message_id", fmt.Sprintf("%d", messageID))
 	writer.WriteField("media", string(mediaJSON))
 
-	if buttonURL != "" {
-		kb, _ := json.Marshal(buildKeyboard(buttonText, buttonURL))
+	if len(keyboard) > 0 {
+		kb, _ := json.Marshal(buildKeyboard(keyboard))
 		writer.WriteField("reply_markup", string(kb))
 	}
 
@@ -107,54 +297,372 @@ func editPhotoMessage(token string, chatID int64, messageID int, photoURL, capti
 	part.Write(imageData)
 	writer.Close()
 
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/editMessageMedia", token)
+	url := fmt.Sprintf("%s/bot%s/editMessageMedia", telegramAPI, token)
 	req, _ := http.NewRequest("POST", url, &body)
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	resp, err := httpClient.Do(req)
+	resp, err := telegramHTTP.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("telegram API error: %s", string(respBody))
-	}
-	return nil
+	_, err = parseTelegramResponse(resp)
+	return ignoreNotModified(err)
 }
 
-func editMessageCaption(token string, chatID int64, messageID int, caption, buttonURL, buttonText string) error {
+func editMessageCaption(token string, chatID int64, messageID int, caption string, keyboard [][]InlineButton) error {
 	payload := map[string]any{
 		"chat_id":    chatID,
 		"message_id": messageID,
-		"caption":    caption,
+		"caption":    fitCaption(caption),
 		"parse_mode": "HTML",
 	}
-	if buttonURL != "" {
-		payload["reply_markup"] = buildKeyboard(buttonText, buttonURL)
+	if len(keyboard) > 0 {
+		payload["reply_markup"] = buildKeyboard(keyboard)
 	}
 
 	jsonData, _ := json.Marshal(payload)
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/editMessageCaption", token)
+	url := fmt.Sprintf("%s/bot%s/editMessageCaption", telegramAPI, token)
 
-	resp, err := httpClient.Post(url, "application/json", strings.NewReader(string(jsonData)))
+	resp, err := telegramHTTP.Post(url, "application/json", strings.NewReader(string(jsonData)))
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("telegram API error: %s", string(respBody))
+	_, err = parseTelegramResponse(resp)
+	return ignoreNotModified(err)
+}
+
+func telegramCall(token, method string, payload map[string]any) (json.RawMessage, error) {
+	jsonData, _ := json.Marshal(payload)
+	url := fmt.Sprintf("%s/bot%s/%s", telegramAPI, token, method)
+
+	resp, err := telegramHTTP.Post(url, "application/json", strings.NewReader(string(jsonData)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return parseTelegramResponse(resp)
+}
+
+func sendTextMessage(token string, chatID int64, threadID *int, text string) error {
+	_, err := sendTextMessageID(token, chatID, threadID, text)
+	return err
+}
+
+func sendTextMessageID(token string, chatID int64, threadID *int, text string) (int, error) {
+	payload := map[string]any{
+		"chat_id":    chatID,
+		"text":       text,
+		"parse_mode": "HTML",
+	}
+	if threadID != nil {
+		payload["message_thread_id"] = *threadID
+	}
+
+	result, err := telegramCall(token, "sendMessage", payload)
+	if err != nil {
+		return 0, err
+	}
+	var msg TelegramMessage
+	if err := json.Unmarshal(result, &msg); err != nil {
+		return 0, err
+	}
+	return msg.MessageID, nil
+}
+
+func sendFileReply(token, method, field string, chatID int64, replyTo int, filename string, data []byte, caption string) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	writer.WriteField("chat_id", fmt.Sprintf("%d", chatID))
+	writer.WriteField("caption", fitCaption(caption))
+	writer.WriteField("parse_mode", "HTML")
+	if replyTo != 0 {
+		rp, _ := json.Marshal(map[string]any{"message_id": replyTo, "allow_sending_without_reply": true})
+		writer.WriteField("reply_parameters", string(rp))
+	}
+
+	part, _ := writer.CreateFormFile(field, filename)
+	part.Write(data)
+	writer.Close()
+
+	url := fmt.Sprintf("%s/bot%s/%s", telegramAPI, token, method)
+	req, _ := http.NewRequest("POST", url, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := telegramHTTP.Do(req)
+	if err != nil {
+		return err
 	}
-	return nil
+	defer resp.Body.Close()
+
+	_, err = parseTelegramResponse(resp)
+	return err
 }
 
-func buildKeyboard(text, url string) map[string]any {
-	return map[string]any{
-		"inline_keyboard": [][]map[string]string{
-			{{"text": text, "url": url}},
+func postStory(token, businessConnectionID string, photo []byte, caption, linkURL string, area StoryArea, activePeriod int) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	content, _ := json.Marshal(map[string]any{"type": "photo", "photo": "attach://story"})
+	areas, _ := json.Marshal([]map[string]any{{
+		"position": map[string]any{
+			"x_percentage":             area.X,
+			"y_percentage":             area.Y,
+			"width_percentage":         area.Width,
+			"height_percentage":        area.Height,
+			"rotation_angle":           0,
+			"corner_radius_percentage": 0,
 		},
+		"type": map[string]any{"type": "link", "url": linkURL},
+	}})
+	writer.WriteField("business_connection_id", businessConnectionID)
+	writer.WriteField("content", string(content))
+	writer.WriteField("active_period", fmt.Sprintf("%d", activePeriod))
+	writer.WriteField("caption", caption)
+	writer.WriteField("parse_mode", "HTML")
+	writer.WriteField("areas", string(areas))
+
+	part, _ := writer.CreateFormFile("story", "story.jpg")
+	part.Write(photo)
+	writer.Close()
+
+	url := fmt.Sprintf("%s/bot%s/postStory", telegramAPI, token)
+	req, _ := http.NewRequest("POST", url, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := telegramHTTP.Do(req)
+	if err != nil {
+		return err
 	}
+	defer resp.Body.Close()
+
+	_, err = parseTelegramResponse(resp)
+	return err
+}
+
+func sendPaidMedia(token string, chatID int64, threadID *int, stars int, mediaType, mediaURL, caption string) (int, error) {
+	payload := map[string]any{
+		"chat_id":    chatID,
+		"star_count": stars,
+		"media":      []map[string]string{{"type": mediaType, "media": mediaURL}},
+		"caption":    fitCaption(caption),
+		"parse_mode": "HTML",
+	}
+	if threadID != nil {
+		payload["message_thread_id"] = *threadID
+	}
+	result, err := telegramCall(token, "sendPaidMedia", payload)
+	if err != nil {
+		return 0, err
+	}
+	var msg TelegramMessage
+	if err := json.Unmarshal(result, &msg); err != nil {
+		return 0, err
+	}
+	return msg.MessageID, nil
+}
+
+func sendReply(token string, chatID int64, replyTo int, text string) error {
+	_, err := telegramCall(token, "sendMessage", map[string]any{
+		"chat_id":    chatID,
+		"text":       text,
+		"parse_mode": "HTML",
+		"reply_parameters": map[string]any{
+			"message_id": replyTo,
+		},
+	})
+	return err
+}
+
+func sendKeyboardMessage(token string, chatID int64, threadID *int, replyTo int, text string, keyboard [][]InlineButton) (int, error) {
+	payload := map[string]any{
+		"chat_id":      chatID,
+		"text":         text,
+		"parse_mode":   "HTML",
+		"reply_markup": buildKeyboard(keyboard),
+	}
+	if replyTo != 0 {
+		payload["reply_parameters"] = map[string]any{"message_id": replyTo}
+	} else if threadID != nil {
+		payload["message_thread_id"] = *threadID
+	}
+
+	result, err := telegramCall(token, "sendMessage", payload)
+	if err != nil {
+		return 0, err
+	}
+	var msg struct {
+		MessageID int `json:"message_id"`
+	}
+	if err := json.Unmarshal(result, &msg); err != nil {
+		return 0, err
+	}
+	return msg.MessageID, nil
+}
+
+func editMessageText(token string, chatID int64, messageID int, text string, keyboard [][]InlineButton) error {
+	payload := map[string]any{
+		"chat_id":    chatID,
+		"message_id": messageID,
+		"text":       text,
+		"parse_mode": "HTML",
+	}
+	if len(keyboard) > 0 {
+		payload["reply_markup"] = buildKeyboard(keyboard)
+	}
+	_, err := telegramCall(token, "editMessageText", payload)
+	return ignoreNotModified(err)
+}
+
+func setMessageReaction(token string, chatID int64, messageID int, emoji string) error {
+	_, err := telegramCall(token, "setMessageReaction", map[string]any{
+		"chat_id":    chatID,
+		"message_id": messageID,
+		"reaction":   []map[string]string{{"type": "emoji", "emoji": emoji}},
+	})
+	return err
+}
+
+func getChat(token string, chatID int64) (*TelegramChat, error) {
+	result, err := telegramCall(token, "getChat", map[string]any{"chat_id": chatID})
+	if err != nil {
+		return nil, err
+	}
+	var chat TelegramChat
+	if err := json.Unmarshal(result, &chat); err != nil {
+		return nil, err
+	}
+	return &chat, nil
+}
+
+type ChatMember struct {
+	Status          string `json:"status"`
+	CanPostMessages *bool  `json:"can_post_messages"`
+	CanSendMessages *bool  `json:"can_send_messages"`
+}
+
+func (m *ChatMember) CanPost() bool {
+	switch m.Status {
+	case "creator", "member":
+		return true
+	case "administrator":
+		return m.CanPostMessages == nil || *m.CanPostMessages
+	case "restricted":
+		return m.CanSendMessages != nil && *m.CanSendMessages
+	}
+	return false
+}
+
+func getChatMemberCount(token string, chatID int64) (int, error) {
+	result, err := telegramCall(token, "getChatMemberCount", map[string]any{"chat_id": chatID})
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	if err := json.Unmarshal(result, &count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func getChatMember(token string, chatID, userID int64) (*ChatMember, error) {
+	result, err := telegramCall(token, "getChatMember", map[string]any{"chat_id": chatID, "user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	var member ChatMember
+	if err := json.Unmarshal(result, &member); err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func answerCallbackQuery(token, queryID, text string) error {
+	_, err := telegramCall(token, "answerCallbackQuery", map[string]any{
+		"callback_query_id": queryID,
+		"text":              text,
+	})
+	return err
+}
+
+func deleteMessage(token string, chatID int64, messageID int) error {
+	_, err := telegramCall(token, "deleteMessage", map[string]any{
+		"chat_id":    chatID,
+		"message_id": messageID,
+	})
+	return err
+}
+
+func createForumTopic(token string, chatID int64, name string) (int, error) {
+	result, err := telegramCall(token, "createForumTopic", map[string]any{
+		"chat_id": chatID,
+		"name":    name,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var topic struct {
+		MessageThreadID int `json:"message_thread_id"`
+	}
+	if err := json.Unmarshal(result, &topic); err != nil {
+		return 0, err
+	}
+	return topic.MessageThreadID, nil
+}
+
+func editForumTopic(token string, chatID int64, threadID int, name, iconID string) error {
+	payload := map[string]any{
+		"chat_id":           chatID,
+		"message_thread_id": threadID,
+	}
+	if name != "" {
+		payload["name"] = name
+	}
+	if iconID != "" {
+		payload["icon_custom_emoji_id"] = iconID
+	}
+	_, err := telegramCall(token, "editForumTopic", payload)
+	return ignoreNotModified(err)
+}
+
+func setMyCommands(token string, chatID int64, languageCode string, commands []map[string]string) error {
+	params := map[string]any{
+		"commands": commands,
+		"scope":    map[string]any{"type": "chat", "chat_id": chatID},
+	}
+	if languageCode != "" {
+		params["language_code"] = languageCode
+	}
+	_, err := telegramCall(token, "setMyCommands", params)
+	return err
+}
+
+func closeForumTopic(token string, chatID int64, threadID int) error {
+	_, err := telegramCall(token, "closeForumTopic", map[string]any{
+		"chat_id":           chatID,
+		"message_thread_id": threadID,
+	})
+	return err
+}
+
+func ignoreNotModified(err error) error {
+	var tgErr *TelegramError
+	if errors.As(err, &tgErr) && tgErr.NotModified() {
+		return nil
+	}
+	return err
+}
+
+func buildKeyboard(rows [][]InlineButton) map[string]any {
+	return map[string]any{"inline_keyboard": rows}
+}
+
+func watchKeyboard(text, url string) [][]InlineButton {
+	return [][]InlineButton{{{Text: text, URL: url}}}
 }
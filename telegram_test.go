@@ -0,0 +1,62 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+var captionTag = regexp.MustCompile(`<[^>]*>`)
+
+func captionLength(s string) int {
+	return len(utf16.Encode([]rune(html.UnescapeString(captionTag.ReplaceAllString(s, "")))))
+}
+
+func TestFitCaption(t *testing.T) {
+	a := func(n int) string { return strings.Repeat("a", n) }
+	for name, tc := range map[string]struct {
+		caption string
+		want    string
+	}{
+		"exactly at the limit":       {a(1024), a(1024)},
+		"one over the limit":         {a(1025), a(1023) + "…"},
+		"surrogate pair fits":        {a(1022) + "😀", a(1022) + "😀"},
+		"surrogate pair at limit":    {a(1023) + "😀", a(1023) + "…"},
+		"surrogate pair split":       {a(1022) + "😀b", a(1022) + "…"},
+		"entity counts as one":       {a(1023) + "&amp;", a(1023) + "&amp;"},
+		"cut before an entity":       {a(1023) + "&amp;b", a(1023) + "…"},
+		"cut inside a tag":           {"<b>" + a(1030) + "</b>", "<b>" + a(1023) + "…</b>"},
+		"tags do not count":          {"<b>" + a(1024) + "</b>", "<b>" + a(1024) + "</b>"},
+		"nested tags are closed":     {`<b><a href="https://twitch.tv/x">` + a(1030) + "</a></b>", `<b><a href="https://twitch.tv/x">` + a(1023) + "…</a></b>"},
+		"closed tags are not closed": {"<b>x</b>" + a(1030), "<b>x</b>" + a(1022) + "…"},
+		"trailing space is trimmed":  {a(1020) + "   bbbb", a(1020) + "…"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := fitCaption(tc.caption)
+			if got != tc.want {
+				t.Fatalf("fitCaption() = %q…%q, want %q…%q", head(got), tail(got), head(tc.want), tail(tc.want))
+			}
+			if n := captionLength(got); n > telegramCaptionLimit {
+				t.Fatalf("caption is %d units long", n)
+			}
+			if err := checkTelegramHTML(got); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestCutCaptionReportsFit(t *testing.T) {
+	if got, fits := cutCaption("<i>abc</i>", 3); !fits || got != "<i>abc</i>" {
+		t.Fatalf("cutCaption() = %q, %v", got, fits)
+	}
+	if got, fits := cutCaption("<i>abcd</i>", 3); fits || got != "<i>abc…</i>" {
+		t.Fatalf("cutCaption() = %q, %v", got, fits)
+	}
+}
+
+func head(s string) string { return s[:min(len(s), 40)] }
+
+func tail(s string) string { return s[max(0, len(s)-40):] }
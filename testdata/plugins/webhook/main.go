@@ -0,0 +1,63 @@
+package main
+
+import "unsafe"
+
+//go:wasmimport twitch2tg event_len
+func eventLen() uint32
+
+//go:wasmimport twitch2tg event_read
+func eventRead(ptr unsafe.Pointer)
+
+//go:wasmimport twitch2tg log
+func hostLog(level uint32, ptr unsafe.Pointer, size uint32)
+
+//go:wasmimport twitch2tg setting
+func setting(keyPtr unsafe.Pointer, keySize uint32, bufPtr unsafe.Pointer, bufSize uint32) int32
+
+//go:wasmimport twitch2tg http_post
+func httpPost(urlPtr unsafe.Pointer, urlSize uint32, typePtr unsafe.Pointer, typeSize uint32, bodyPtr unsafe.Pointer, bodySize uint32) int32
+
+func main() {}
+
+func ptr(b []byte) unsafe.Pointer {
+	if len(b) == 0 {
+		return nil
+	}
+	return unsafe.Pointer(&b[0])
+}
+
+func getSetting(key string) (string, bool) {
+	k := []byte(key)
+	n := setting(ptr(k), uint32(len(k)), nil, 0)
+	if n < 0 {
+		return "", false
+	}
+	buf := make([]byte, n)
+	setting(ptr(k), uint32(len(k)), ptr(buf), uint32(len(buf)))
+	return string(buf), true
+}
+
+func logf(level uint32, msg string) {
+	b := []byte(msg)
+	hostLog(level, ptr(b), uint32(len(b)))
+}
+
+//go:wasmexport on_event
+func onEvent() int32 {
+	event := make([]byte, eventLen())
+	eventRead(ptr(event))
+
+	url, ok := getSetting("url")
+	if !ok {
+		logf(2, "url setting is missing")
+		return 1
+	}
+	u, ct := []byte(url), []byte("application/json")
+	status := httpPost(ptr(u), uint32(len(u)), ptr(ct), uint32(len(ct)), ptr(event), uint32(len(event)))
+	if status != 204 {
+		logf(1, "unexpected status")
+		return 2
+	}
+	logf(0, "event delivered")
+	return 0
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+)
+
+type TimelapseConfig struct {
+	Enabled   bool   `json:"enabled"`
+	Format    string `json:"format"`
+	FPS       int    `json:"fps"`
+	Width     int    `json:"width"`
+	MaxFrames int    `json:"max_frames"`
+}
+
+func (a *ScreenshotArchive) sendTimelapse(ctx context.Context, ev Event) {
+	cfg := a.cfg
+	tl := cfg.Screenshots.Timelapse
+	dir := sessionDir(cfg.Screenshots.Dir, ev.Channel, ev.Session.StartTime)
+	keys, err := a.store.List(ctx, dir)
+	if err != nil {
+		slog.Warn("failed to list screenshots for timelapse", "error", err)
+		return
+	}
+	slices.Sort(keys)
+	keys = sampleFrames(keys, tl.MaxFrames)
+	if len(keys) < 2 {
+		return
+	}
+
+	var frames [][]byte
+	for _, key := range keys {
+		data, err := a.store.Get(ctx, key)
+		if err != nil {
+			slog.Warn("failed to read screenshot", "key", key, "error", err)
+			continue
+		}
+		frames = append(frames, data)
+	}
+
+	var data []byte
+	var method, field, filename string
+	switch tl.Format {
+	case "mp4":
+		data, err = encodeMP4(ctx, frames, tl.FPS, tl.Width)
+		method, field, filename = "sendVideo", "video", "timelapse.mp4"
+	default:
+		data, err = encodeGIF(frames, tl.FPS, tl.Width)
+		method, field, filename = "sendAnimation", "animation", "timelapse.gif"
+	}
+	if err != nil {
+		slog.Error("failed to build timelapse", "error", err)
+		return
+	}
+
+	caption := fmt.Sprintf("⏩ <b>%s</b>", escapeHTML(ev.Channel))
+	if err := sendFileReply(cfg.Telegram.BotToken, method, field, *cfg.Telegram.ChatID, ev.Session.MessageID, filename, data, caption); err != nil {
+		slog.Error("failed to send timelapse", "error", err)
+		return
+	}
+	slog.Info("timelapse sent", "frames", len(frames), "bytes", len(data))
+}
+
+func sampleFrames(keys []string, limit int) []string {
+	if limit <= 0 || len(keys) <= limit {
+		return keys
+	}
+	sampled := make([]string, 0, limit)
+	for i := range limit {
+		sampled = append(sampled, keys[i*len(keys)/limit])
+	}
+	return sampled
+}
+
+func encodeGIF(frames [][]byte, fps, width int) ([]byte, error) {
+	anim := &gif.GIF{}
+	delay := 100 / max(fps, 1)
+	for _, data := range frames {
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			slog.Warn("skipping unreadable screenshot", "error", err)
+			continue
+		}
+		scaled := scaleImage(img, width)
+		paletted := image.NewPaletted(scaled.Bounds(), palette.Plan9)
+		draw.FloydSteinberg.Draw(paletted, scaled.Bounds(), scaled, image.Point{})
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, delay)
+	}
+	if len(anim.Image) == 0 {
+		return nil, errors.New("no decodable frames")
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func scaleImage(src image.Image, width int) image.Image {
+	b := src.Bounds()
+	if width <= 0 || b.Dx() <= width {
+		return src
+	}
+	height := b.Dy() * width / b.Dx()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			dst.Set(x, y, src.At(b.Min.X+x*b.Dx()/width, b.Min.Y+y*b.Dy()/height))
+		}
+	}
+	return dst
+}
+
+func encodeMP4(ctx context.Context, frames [][]byte, fps, width int) ([]byte, error) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, errors.New("mp4 timelapse requires ffmpeg in PATH")
+	}
+	dir, err := os.MkdirTemp("", "timelapse")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	for i, data := range frames {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("frame%05d.jpg", i)), data, 0644); err != nil {
+			return nil, err
+		}
+	}
+	out := filepath.Join(dir, "timelapse.mp4")
+	cmd := exec.CommandContext(ctx, ffmpeg, "-y", "-loglevel", "error",
+		"-framerate", fmt.Sprint(max(fps, 1)),
+		"-i", filepath.Join(dir, "frame%05d.jpg"),
+		"-vf", fmt.Sprintf("scale=%d:-2", width),
+		"-pix_fmt", "yuv420p", "-movflags", "+faststart",
+		out,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w: %s", err, output)
+	}
+	return os.ReadFile(out)
+}
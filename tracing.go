@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+
+	spanStatusOK    = 1
+	spanStatusError = 2
+)
+
+type Tracer struct {
+	endpoint    string
+	serviceName string
+	headers     map[string]string
+	client      *http.Client
+
+	mu      sync.Mutex
+	pending []otlpSpan
+}
+
+type Span struct {
+	tracer   *Tracer
+	traceID  string
+	spanID   string
+	parentID string
+	name     string
+	kind     int
+	start    time.Time
+	attrs    []otlpAttribute
+}
+
+type spanContextKey struct{}
+
+type otlpAttribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceID      string          `json:"traceId"`
+	SpanID       string          `json:"spanId"`
+	ParentSpanID string          `json:"parentSpanId,omitempty"`
+	Name         string          `json:"name"`
+	Kind         int             `json:"kind"`
+	Start        string          `json:"startTimeUnixNano"`
+	End          string          `json:"endTimeUnixNano"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+	Status       struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	} `json:"status"`
+}
+
+var tracer *Tracer
+
+func newTracer(endpoint, serviceName string, headers map[string]string) *Tracer {
+	return &Tracer{
+		endpoint:    strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		serviceName: serviceName,
+		headers:     headers,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (t *Tracer) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			t.flush(flushCtx)
+			cancel()
+			return
+		case <-time.After(5 * time.Second):
+			t.flush(ctx)
+		}
+	}
+}
+
+func (t *Tracer) flush(ctx context.Context) {
+	t.mu.Lock()
+	spans := t.pending
+	t.pending = nil
+	t.mu.Unlock()
+
+	if len(spans) == 0 {
+		return
+	}
+
+	payload := map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": []otlpAttribute{stringAttr("service.name", t.serviceName)},
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "twitch2tg-bot"},
+				"spans": spans,
+			}},
+		}},
+	}
+	data, _ := json.Marshal(payload)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.endpoint, bytes.NewReader(data))
+	if err != nil {
+		slog.Warn("failed to export traces", "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		slog.Warn("failed to export traces", "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		slog.Warn("failed to export traces", "status", resp.StatusCode)
+	}
+}
+
+func startSpan(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	if tracer == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		tracer: tracer,
+		spanID: randomHex(8),
+		name:   name,
+		kind:   kind,
+		start:  time.Now(),
+	}
+	if parent, ok := ctx.Value(spanContextKey{}).(*Span); ok && parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		span.traceID = randomHex(16)
+	}
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+func (s *Span) SetAttr(key string, value any) {
+	if s == nil {
+		return
+	}
+	switch v := value.(type) {
+	case int:
+		s.attrs = append(s.attrs, otlpAttribute{Key: key, Value: map[string]any{"intValue": strconv.Itoa(v)}})
+	case bool:
+		s.attrs = append(s.attrs, otlpAttribute{Key: key, Value: map[string]any{"boolValue": v}})
+	default:
+		s.attrs = append(s.attrs, stringAttr(key, fmt.Sprint(v)))
+	}
+}
+
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+
+	out := otlpSpan{
+		TraceID:      s.traceID,
+		SpanID:       s.spanID,
+		ParentSpanID: s.parentID,
+		Name:         s.name,
+		Kind:         s.kind,
+		Start:        strconv.FormatInt(s.start.UnixNano(), 10),
+		End:          strconv.FormatInt(time.Now().UnixNano(), 10),
+		Attributes:   s.attrs,
+	}
+	out.Status.Code = spanStatusOK
+	if err != nil {
+		out.Status.Code = spanStatusError
+		out.Status.Message = spanErrorMessage(err)
+	}
+
+	s.tracer.mu.Lock()
+	s.tracer.pending = append(s.tracer.pending, out)
+	s.tracer.mu.Unlock()
+}
+
+func stringAttr(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: map[string]any{"stringValue": value}}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type tracingTransport struct {
+	base http.RoundTripper
+}
+
+func (t tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := redactBotToken(req.URL.Path)
+	ctx, span := startSpan(req.Context(), req.Method+" "+req.URL.Host+path, spanKindClient)
+	span.SetAttr("http.request.method", req.Method)
+	span.SetAttr("server.address", req.URL.Host)
+	span.SetAttr("url.path", path)
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		span.End(err)
+		return nil, err
+	}
+
+	span.SetAttr("http.response.status_code", resp.StatusCode)
+	if resp.StatusCode >= 400 {
+		span.End(fmt.Errorf("HTTP %d", resp.StatusCode))
+	} else {
+		span.End(nil)
+	}
+	return resp, nil
+}
+
+func spanErrorMessage(err error) string {
+	msg := err.Error()
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return msg
+	}
+	u, perr := url.Parse(urlErr.URL)
+	if perr != nil {
+		return strings.ReplaceAll(msg, urlErr.URL, "<url>")
+	}
+	return strings.ReplaceAll(msg, urlErr.URL, u.Scheme+"://"+u.Host+redactBotToken(u.Path))
+}
+
+func redactBotToken(path string) string {
+	if !strings.HasPrefix(path, "/bot") {
+		return path
+	}
+	if i := strings.Index(path[1:], "/"); i >= 0 {
+		return "/bot<token>" + path[i+1:]
+	}
+	return "/bot<token>"
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSpanErrorHidesSecrets(t *testing.T) {
+	old := tracer
+	tracer = newTracer("http://127.0.0.1:1", "test", nil)
+	t.Cleanup(func() { tracer = old })
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	defer func(old string) { twitchAuthAPI = old }(twitchAuthAPI)
+	twitchAuthAPI = "http://" + addr
+
+	_, span := startSpan(context.Background(), "mint token", spanKindInternal)
+	_, mintErr := mintAccessToken(context.Background(), "client", "SECRET")
+	if mintErr == nil {
+		t.Fatal("mint against a closed port succeeded")
+	}
+	span.End(mintErr)
+
+	_, span = startSpan(context.Background(), "send message", spanKindInternal)
+	span.End(&url.Error{
+		Op:  "Post",
+		URL: "https://api.telegram.org/bot123:abc/sendMessage?client_secret=SECRET",
+		Err: errors.New("connection refused"),
+	})
+
+	for _, s := range tracer.pending {
+		if strings.Contains(s.Status.Message, "SECRET") || strings.Contains(s.Status.Message, "123:abc") {
+			t.Fatalf("span %q leaks a secret: %s", s.Name, s.Status.Message)
+		}
+	}
+	if msg := tracer.pending[1].Status.Message; !strings.Contains(msg, "api.telegram.org/bot<token>/sendMessage") {
+		t.Fatalf("redacted message = %q", msg)
+	}
+}
@@ -3,26 +3,41 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
 
 type StreamInfo struct {
-	Channel string
-	URL     string
-	Title   string
-	Game    string
-	Viewers int
-	Uptime  string
-	Tags    []string
+	Channel   string
+	URL       string
+	Title     string
+	Game      string
+	Viewers   int
+	Uptime    string
+	Tags      []string
+	StartedAt time.Time
+	Mature    bool
+	Partners  []string
+	Squad     []string
+	ChatModes ChatModes
+	Followers *FollowerGoal
 }
 
 type ClipInfo struct {
-	URL   string
-	Title string
+	URL          string `json:"url"`
+	Title        string `json:"title"`
+	Creator      string `json:"creator,omitempty"`
+	Language     string `json:"language,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	Views        int    `json:"views"`
 }
 
 type TwitchAuthResponse struct {
@@ -31,57 +46,183 @@ type TwitchAuthResponse struct {
 }
 
 type TwitchStream struct {
+	UserID      string    `json:"user_id"`
 	UserLogin   string    `json:"user_login"`
 	GameName    string    `json:"game_name"`
 	Title       string    `json:"title"`
 	ViewerCount int       `json:"viewer_count"`
 	StartedAt   time.Time `json:"started_at"`
 	Tags        []string  `json:"tags"`
+	IsMature    bool      `json:"is_mature"`
 }
 
 type TwitchStreamsResponse struct {
-	Data []TwitchStream `json:"data"`
+	Data       []TwitchStream `json:"data"`
+	Pagination struct {
+		Cursor string `json:"cursor"`
+	} `json:"pagination"`
+}
+
+type TwitchUser struct {
+	ID              string `json:"id"`
+	Login           string `json:"login"`
+	DisplayName     string `json:"display_name"`
+	ProfileImageURL string `json:"profile_image_url"`
+	OfflineImageURL string `json:"offline_image_url"`
+	Description     string `json:"description"`
+}
+
+type TwitchScheduleSegment struct {
+	ID            string     `json:"id"`
+	StartTime     time.Time  `json:"start_time"`
+	EndTime       *time.Time `json:"end_time"`
+	Title         string     `json:"title"`
+	CanceledUntil *string    `json:"canceled_until"`
+	Category      *struct {
+		Name string `json:"name"`
+	} `json:"category"`
+}
+
+type TwitchScheduleResponse struct {
+	Data struct {
+		Segments []TwitchScheduleSegment `json:"segments"`
+	} `json:"data"`
+}
+
+type TwitchAPIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *TwitchAPIError) Error() string {
+	return fmt.Sprintf("twitch API error (%d): %s", e.StatusCode, e.Body)
+}
+
+func (e *TwitchAPIError) Permanent() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500 && e.StatusCode != http.StatusTooManyRequests
 }
 
 type TwitchClip struct {
-	URL       string    `json:"url"`
-	Title     string    `json:"title"`
-	ViewCount int       `json:"view_count"`
-	CreatedAt time.Time `json:"created_at"`
+	URL          string    `json:"url"`
+	Title        string    `json:"title"`
+	CreatorName  string    `json:"creator_name"`
+	Language     string    `json:"language"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ViewCount    int       `json:"view_count"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 type TwitchClipsResponse struct {
 	Data []TwitchClip `json:"data"`
 }
 
+type TwitchVideo struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var errBroadcasterNotFound = errors.New("broadcaster not found")
+
+type TwitchCredential struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+type twitchToken struct {
+	accessToken string
+	expiresAt   time.Time
+}
+
+var (
+	tokenMu     sync.Mutex
+	tokenCache  = map[string]*twitchToken{}
+	credMu      sync.Mutex
+	credentials []TwitchCredential
+	credCurrent int
+)
+
+const tokenRefreshMargin = 10 * time.Minute
+
+const helixMaxQueryIDs = 100
+
 var (
-	tokenMu           sync.Mutex
-	twitchAccessToken string
-	tokenExpiresAt    time.Time
+	userCacheMu sync.Mutex
+	userCache   = map[string]TwitchUser{}
 )
 
-var httpClient = &http.Client{Timeout: 15 * time.Second}
+func setTwitchCredentials(creds []TwitchCredential) {
+	credMu.Lock()
+	defer credMu.Unlock()
+	credentials = creds
+	credCurrent = 0
+}
+
+func credentialsFor(clientID, clientSecret string) []TwitchCredential {
+	credMu.Lock()
+	defer credMu.Unlock()
+
+	if len(credentials) == 0 {
+		return []TwitchCredential{{ClientID: clientID, ClientSecret: clientSecret}}
+	}
+	ordered := make([]TwitchCredential, 0, len(credentials))
+	for i := range credentials {
+		ordered = append(ordered, credentials[(credCurrent+i)%len(credentials)])
+	}
+	return ordered
+}
+
+func rotateCredentials(from string) {
+	credMu.Lock()
+	defer credMu.Unlock()
+
+	if len(credentials) < 2 || credentials[credCurrent].ClientID != from {
+		return
+	}
+	credCurrent = (credCurrent + 1) % len(credentials)
+	slog.Warn("twitch rate limit hit, rotating credentials", "client_id", credentials[credCurrent].ClientID)
+}
 
 func getAccessToken(ctx context.Context, clientID, clientSecret string) (string, error) {
+	if token, ok := cachedAccessToken(clientID); ok {
+		return token, nil
+	}
+	return mintAccessToken(ctx, clientID, clientSecret)
+}
+
+func cachedAccessToken(clientID string) (string, bool) {
 	tokenMu.Lock()
 	defer tokenMu.Unlock()
 
-	if twitchAccessToken != "" && time.Now().Before(tokenExpiresAt) {
-		return twitchAccessToken, nil
+	if t := tokenCache[clientID]; t != nil && clock.Now().Before(t.expiresAt) {
+		return t.accessToken, true
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://id.twitch.tv/oauth2/token", nil)
+	if stateStore != nil {
+		var stored StoredToken
+		var ok bool
+		stateStore.View(func(st *State) { stored, ok = st.Tokens[clientID] })
+		if ok && clock.Now().Before(stored.ExpiresAt) {
+			tokenCache[clientID] = &twitchToken{accessToken: stored.AccessToken, expiresAt: stored.ExpiresAt}
+			return stored.AccessToken, true
+		}
+	}
+	return "", false
+}
+
+func mintAccessToken(ctx context.Context, clientID, clientSecret string) (string, error) {
+	form := url.Values{
+		"client_id":     {clientID},
+		"client_secret": {clientSecret},
+		"grant_type":    {"client_credentials"},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", twitchAuthAPI+"/oauth2/token", strings.NewReader(form.Encode()))
 	if err != nil {
 		return "", err
 	}
-	q := req.URL.Query()
-	q.Set("client_id", clientID)
-	q.Set("client_secret", clientSecret)
-	q.Set("grant_type", "client_credentials")
-	req.URL.RawQuery = q.Encode()
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := httpClient.Do(req)
+	resp, err := twitchHTTP.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -97,17 +238,108 @@ func getAccessToken(ctx context.Context, clientID, clientSecret string) (string,
 		return "", err
 	}
 
-	twitchAccessToken = auth.AccessToken
-	tokenExpiresAt = time.Now().Add(time.Duration(auth.ExpiresIn-300) * time.Second)
-	return twitchAccessToken, nil
+	expiresAt := clock.Now().Add(time.Duration(auth.ExpiresIn-300) * time.Second)
+	tokenMu.Lock()
+	tokenCache[clientID] = &twitchToken{accessToken: auth.AccessToken, expiresAt: expiresAt}
+	tokenMu.Unlock()
+
+	if stateStore != nil {
+		err := stateStore.Update(func(st *State) {
+			if st.Tokens == nil {
+				st.Tokens = map[string]StoredToken{}
+			}
+			st.Tokens[clientID] = StoredToken{AccessToken: auth.AccessToken, ExpiresAt: expiresAt}
+		})
+		if err != nil {
+			slog.Warn("failed to persist twitch token", "error", err)
+		}
+	}
+	return auth.AccessToken, nil
+}
+
+func dropAccessToken(clientID string) {
+	tokenMu.Lock()
+	delete(tokenCache, clientID)
+	tokenMu.Unlock()
+
+	if stateStore != nil {
+		err := stateStore.Update(func(st *State) { delete(st.Tokens, clientID) })
+		if err != nil {
+			slog.Warn("failed to drop persisted twitch token", "error", err)
+		}
+	}
+}
+
+func runTokenRefresher(ctx context.Context, creds []TwitchCredential) {
+	for {
+		for _, cred := range creds {
+			tokenMu.Lock()
+			t := tokenCache[cred.ClientID]
+			stale := t != nil && t.expiresAt.Sub(clock.Now()) < tokenRefreshMargin
+			tokenMu.Unlock()
+			if !stale {
+				continue
+			}
+			if _, err := mintAccessToken(ctx, cred.ClientID, cred.ClientSecret); err != nil {
+				slog.Warn("failed to refresh twitch token", "client_id", cred.ClientID, "error", err)
+			} else {
+				slog.Info("twitch token refreshed", "client_id", cred.ClientID)
+			}
+		}
+
+		sleep(ctx, time.Minute)
+		if ctx.Err() != nil {
+			return
+		}
+	}
 }
 
 func twitchGet(ctx context.Context, url, clientID, clientSecret string, out any) error {
-	token, err := getAccessToken(ctx, clientID, clientSecret)
+	if upstream != nil {
+		return errUpstreamMode
+	}
+	if twitchRetry.MaxAttempts == 1 {
+		return twitchGetRotating(ctx, url, clientID, clientSecret, out)
+	}
+	return retryWithBackoff(ctx, twitchRetry, func() error {
+		return twitchGetRotating(ctx, url, clientID, clientSecret, out)
+	}, "twitch request")
+}
+
+func twitchGetRotating(ctx context.Context, url, clientID, clientSecret string, out any) error {
+	var err error
+	for _, cred := range credentialsFor(clientID, clientSecret) {
+		err = twitchGetWith(ctx, url, cred, out)
+		var apiErr *TwitchAPIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+			return err
+		}
+		rotateCredentials(cred.ClientID)
+	}
+	return err
+}
+
+func twitchGetWith(ctx context.Context, url string, cred TwitchCredential, out any) error {
+	token, err := getAccessToken(ctx, cred.ClientID, cred.ClientSecret)
 	if err != nil {
 		return err
 	}
+	err = twitchGetToken(ctx, url, cred.ClientID, token, out)
+	var apiErr *TwitchAPIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		return err
+	}
 
+	slog.Warn("twitch rejected the app token, minting a new one", "client_id", cred.ClientID)
+	dropAccessToken(cred.ClientID)
+	token, err = mintAccessToken(ctx, cred.ClientID, cred.ClientSecret)
+	if err != nil {
+		return err
+	}
+	return twitchGetToken(ctx, url, cred.ClientID, token, out)
+}
+
+func twitchGetToken(ctx context.Context, url, clientID, token string, out any) error {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return err
@@ -115,25 +347,25 @@ func twitchGet(ctx context.Context, url, clientID, clientSecret string, out any)
 	req.Header.Set("Client-ID", clientID)
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	resp, err := httpClient.Do(req)
+	resp, err := twitchHTTP.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusTooManyRequests {
-		return fmt.Errorf("rate limited (429)")
+		return &TwitchAPIError{StatusCode: resp.StatusCode, Body: "rate limited"}
 	}
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("twitch API error (%d): %s", resp.StatusCode, body)
+		return &TwitchAPIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return json.NewDecoder(resp.Body).Decode(out)
 }
 
 func getStreamInfo(ctx context.Context, channel, clientID, clientSecret, lang string) (*StreamInfo, error) {
-	url := fmt.Sprintf("https://api.twitch.tv/helix/streams?user_login=%s", channel)
+	url := fmt.Sprintf("%s/streams?user_login=%s", helixAPI, channel)
 
 	var resp TwitchStreamsResponse
 	if err := twitchGet(ctx, url, clientID, clientSecret, &resp); err != nil {
@@ -144,41 +376,261 @@ func getStreamInfo(ctx context.Context, channel, clientID, clientSecret, lang st
 		return nil, nil
 	}
 
-	s := resp.Data[0]
+	return newStreamInfo(resp.Data[0], lang), nil
+}
+
+func newStreamInfo(s TwitchStream, lang string) *StreamInfo {
 	return &StreamInfo{
-		Channel: s.UserLogin,
-		URL:     fmt.Sprintf("https://twitch.tv/%s", s.UserLogin),
-		Title:   s.Title,
-		Game:    s.GameName,
-		Viewers: s.ViewerCount,
-		Uptime:  formatDuration(time.Since(s.StartedAt), lang),
-		Tags:    s.Tags,
-	}, nil
+		Channel:   s.UserLogin,
+		URL:       fmt.Sprintf("https://twitch.tv/%s", s.UserLogin),
+		Title:     s.Title,
+		Game:      s.GameName,
+		Viewers:   s.ViewerCount,
+		Uptime:    formatDuration(time.Since(s.StartedAt), lang),
+		Tags:      s.Tags,
+		StartedAt: s.StartedAt,
+		Mature:    s.IsMature,
+	}
+}
+
+func getLiveChannels(ctx context.Context, channels []string, clientID, clientSecret string) ([]string, error) {
+	streams, err := getStreams(ctx, channels, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	live := make([]string, 0, len(streams))
+	for _, s := range streams {
+		live = append(live, s.UserLogin)
+	}
+	return live, nil
+}
+
+func getStreams(ctx context.Context, channels []string, clientID, clientSecret string) ([]TwitchStream, error) {
+	var streams []TwitchStream
+	for start := 0; start < len(channels); start += helixMaxQueryIDs {
+		batch := channels[start:min(start+helixMaxQueryIDs, len(channels))]
+		query := make([]string, 0, len(batch)+1)
+		query = append(query, fmt.Sprintf("first=%d", helixMaxQueryIDs))
+		for _, ch := range batch {
+			query = append(query, "user_login="+url.QueryEscape(ch))
+		}
+
+		cursor := ""
+		for {
+			reqURL := helixAPI + "/streams?" + strings.Join(query, "&")
+			if cursor != "" {
+				reqURL += "&after=" + url.QueryEscape(cursor)
+			}
+			var resp TwitchStreamsResponse
+			if err := twitchGet(ctx, reqURL, clientID, clientSecret, &resp); err != nil {
+				return nil, err
+			}
+			streams = append(streams, resp.Data...)
+			if resp.Pagination.Cursor == "" || len(resp.Data) == 0 {
+				break
+			}
+			cursor = resp.Pagination.Cursor
+		}
+	}
+	return streams, nil
+}
+
+func getTwitchUser(ctx context.Context, channel, clientID, clientSecret string) (*TwitchUser, error) {
+	url := fmt.Sprintf("%s/users?login=%s", helixAPI, channel)
+
+	var resp struct {
+		Data []TwitchUser `json:"data"`
+	}
+	if err := twitchGet(ctx, url, clientID, clientSecret, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, fmt.Errorf("%w: %s", errBroadcasterNotFound, channel)
+	}
+	return &resp.Data[0], nil
+}
+
+func getTwitchUsers(ctx context.Context, field string, values []string, clientID, clientSecret string) ([]TwitchUser, error) {
+	var users []TwitchUser
+	for start := 0; start < len(values); start += helixMaxQueryIDs {
+		batch := values[start:min(start+helixMaxQueryIDs, len(values))]
+		query := make([]string, len(batch))
+		for i, v := range batch {
+			query[i] = field + "=" + url.QueryEscape(v)
+		}
+
+		var resp struct {
+			Data []TwitchUser `json:"data"`
+		}
+		if err := twitchGet(ctx, helixAPI+"/users?"+strings.Join(query, "&"), clientID, clientSecret, &resp); err != nil {
+			return nil, err
+		}
+		users = append(users, resp.Data...)
+	}
+	return users, nil
+}
+
+func preloadTwitchUsers(ctx context.Context, cfg *Config) {
+	logins := []string{strings.ToLower(cfg.Twitch.Channel)}
+	for _, t := range cfg.Channels {
+		logins = append(logins, strings.ToLower(t.Channel))
+	}
+	slices.Sort(logins)
+	logins = slices.Compact(logins)
+
+	users, err := getTwitchUsers(ctx, "login", logins, cfg.Twitch.ClientID, cfg.Twitch.ClientSecret)
+	if err != nil {
+		slog.Warn("failed to preload twitch users", "error", err)
+		return
+	}
+	for _, u := range users {
+		cacheTwitchUser(u)
+	}
+	slog.Info("twitch users cached", "requested", len(logins), "found", len(users))
+}
+
+func cacheTwitchUser(u TwitchUser) {
+	userCacheMu.Lock()
+	defer userCacheMu.Unlock()
+	userCache[strings.ToLower(u.Login)] = u
+}
+
+func cachedTwitchUser(login string) (TwitchUser, bool) {
+	userCacheMu.Lock()
+	defer userCacheMu.Unlock()
+	u, ok := userCache[strings.ToLower(login)]
+	return u, ok
+}
+
+func getTwitchUserByID(ctx context.Context, id, clientID, clientSecret string) (*TwitchUser, error) {
+	url := fmt.Sprintf("%s/users?id=%s", helixAPI, id)
+
+	var resp struct {
+		Data []TwitchUser `json:"data"`
+	}
+	if err := twitchGet(ctx, url, clientID, clientSecret, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, nil
+	}
+	return &resp.Data[0], nil
 }
 
 func getBroadcasterID(ctx context.Context, channel, clientID, clientSecret string) (string, error) {
-	url := fmt.Sprintf("https://api.twitch.tv/helix/users?login=%s", channel)
+	if user, ok := cachedTwitchUser(channel); ok {
+		return user.ID, nil
+	}
+	user, err := getTwitchUser(ctx, channel, clientID, clientSecret)
+	if err != nil {
+		return "", err
+	}
+	cacheTwitchUser(*user)
+	return user.ID, nil
+}
+
+func getContentLabels(ctx context.Context, broadcasterID, clientID, clientSecret string) ([]string, error) {
+	url := fmt.Sprintf("%s/channels?broadcaster_id=%s", helixAPI, broadcasterID)
 
 	var resp struct {
 		Data []struct {
-			ID string `json:"id"`
+			ContentClassificationLabels []string `json:"content_classification_labels"`
 		} `json:"data"`
 	}
 	if err := twitchGet(ctx, url, clientID, clientSecret, &resp); err != nil {
-		return "", err
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, nil
+	}
+	return resp.Data[0].ContentClassificationLabels, nil
+}
+
+func getSharedChatParticipants(ctx context.Context, broadcasterID, clientID, clientSecret string) ([]string, error) {
+	url := fmt.Sprintf("%s/shared_chat/session?broadcaster_id=%s", helixAPI, broadcasterID)
+
+	var resp struct {
+		Data []struct {
+			Participants []struct {
+				BroadcasterID string `json:"broadcaster_id"`
+			} `json:"participants"`
+		} `json:"data"`
+	}
+	if err := twitchGet(ctx, url, clientID, clientSecret, &resp); err != nil {
+		return nil, err
 	}
 	if len(resp.Data) == 0 {
-		return "", fmt.Errorf("broadcaster not found: %s", channel)
+		return nil, nil
+	}
+	ids := make([]string, 0, len(resp.Data[0].Participants))
+	for _, p := range resp.Data[0].Participants {
+		ids = append(ids, p.BroadcasterID)
 	}
-	return resp.Data[0].ID, nil
+	return ids, nil
+}
+
+type TwitchChatSettings struct {
+	EmoteMode            bool `json:"emote_mode"`
+	FollowerMode         bool `json:"follower_mode"`
+	FollowerModeDuration *int `json:"follower_mode_duration"`
+	SlowMode             bool `json:"slow_mode"`
+	SlowModeWaitTime     *int `json:"slow_mode_wait_time"`
+	SubscriberMode       bool `json:"subscriber_mode"`
+	UniqueChatMode       bool `json:"unique_chat_mode"`
+}
+
+func getFollowerCount(ctx context.Context, broadcasterID, clientID, clientSecret string) (int, error) {
+	url := fmt.Sprintf("%s/channels/followers?broadcaster_id=%s&first=1", helixAPI, broadcasterID)
+
+	var resp struct {
+		Total int `json:"total"`
+	}
+	if err := twitchGet(ctx, url, clientID, clientSecret, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Total, nil
+}
+
+func getChatSettings(ctx context.Context, broadcasterID, clientID, clientSecret string) (*TwitchChatSettings, error) {
+	url := fmt.Sprintf("%s/chat/settings?broadcaster_id=%s", helixAPI, broadcasterID)
+
+	var resp struct {
+		Data []TwitchChatSettings `json:"data"`
+	}
+	if err := twitchGet(ctx, url, clientID, clientSecret, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, errors.New("no chat settings returned")
+	}
+	return &resp.Data[0], nil
+}
+
+func getSchedule(ctx context.Context, broadcasterID, clientID, clientSecret string) ([]TwitchScheduleSegment, error) {
+	url := fmt.Sprintf("%s/schedule?broadcaster_id=%s&first=10", helixAPI, broadcasterID)
+
+	var resp TwitchScheduleResponse
+	if err := twitchGet(ctx, url, clientID, clientSecret, &resp); err != nil {
+		var apiErr *TwitchAPIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return resp.Data.Segments, nil
 }
 
 func getRecentClips(ctx context.Context, broadcasterID, clientID, clientSecret string, since time.Time) ([]ClipInfo, error) {
+	return getClipsBetween(ctx, broadcasterID, clientID, clientSecret, since, clock.Now())
+}
+
+func getClipsBetween(ctx context.Context, broadcasterID, clientID, clientSecret string, start, end time.Time) ([]ClipInfo, error) {
 	url := fmt.Sprintf(
-		"https://api.twitch.tv/helix/clips?broadcaster_id=%s&started_at=%s&ended_at=%s&first=20",
+		"%s/clips?broadcaster_id=%s&started_at=%s&ended_at=%s&first=20",
+		helixAPI,
 		broadcasterID,
-		since.UTC().Format(time.RFC3339),
-		time.Now().UTC().Format(time.RFC3339),
+		start.UTC().Format(time.RFC3339),
+		end.UTC().Format(time.RFC3339),
 	)
 
 	var resp TwitchClipsResponse
@@ -191,11 +643,57 @@ func getRecentClips(ctx context.Context, broadcasterID, clientID, clientSecret s
 
 	clips := make([]ClipInfo, 0, len(resp.Data))
 	for _, c := range resp.Data {
-		clips = append(clips, ClipInfo{URL: c.URL, Title: c.Title})
+		clips = append(clips, newClipInfo(c))
 	}
 	return clips, nil
 }
 
+func newClipInfo(c TwitchClip) ClipInfo {
+	return ClipInfo{
+		URL:          c.URL,
+		Title:        c.Title,
+		Creator:      c.CreatorName,
+		Language:     c.Language,
+		ThumbnailURL: c.ThumbnailURL,
+		Views:        c.ViewCount,
+	}
+}
+
+func getClip(ctx context.Context, id, clientID, clientSecret string) (*ClipInfo, error) {
+	url := fmt.Sprintf("%s/clips?id=%s", helixAPI, id)
+
+	var resp TwitchClipsResponse
+	if err := twitchGet(ctx, url, clientID, clientSecret, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, nil
+	}
+	clip := newClipInfo(resp.Data[0])
+	return &clip, nil
+}
+
+func getLatestArchive(ctx context.Context, broadcasterID, clientID, clientSecret string) (*TwitchVideo, error) {
+	url := fmt.Sprintf("%s/videos?user_id=%s&type=archive&first=1", helixAPI, broadcasterID)
+
+	var resp struct {
+		Data []TwitchVideo `json:"data"`
+	}
+	if err := twitchGet(ctx, url, clientID, clientSecret, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, nil
+	}
+	return &resp.Data[0], nil
+}
+
+func vodTimestamp(d time.Duration) string {
+	d = max(d, 0).Truncate(time.Second)
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	return fmt.Sprintf("%dh%dm%ds", h, m, s)
+}
+
 func formatDuration(d time.Duration, lang string) string {
 	hours := int(d.Hours())
 	minutes := int(d.Minutes()) % 60
@@ -213,8 +711,8 @@ func formatDuration(d time.Duration, lang string) string {
 }
 
 func getThumbnailURL(channel string) string {
-	return fmt.Sprintf("https://static-cdn.jtvnw.net/previews-ttv/live_user_%s-1920x1080.jpg?t=%d",
-		channel, time.Now().Unix())
+	return fmt.Sprintf("%s/previews-ttv/live_user_%s-1920x1080.jpg?t=%d",
+		previewsCDN, channel, clock.Now().Unix())
 }
 
 func downloadImage(ctx context.Context, url string) ([]byte, error) {
@@ -222,7 +720,7 @@ func downloadImage(ctx context.Context, url string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	resp, err := httpClient.Do(req)
+	resp, err := twitchHTTP.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRevokedAppTokenIsReminted(t *testing.T) {
+	helix := newFakeHelix(t)
+	helix.AddUser("42", "somechannel")
+	now := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	useManualClock(t, now)
+
+	store, err := openStateStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Update(func(st *State) {
+		st.Tokens = map[string]StoredToken{"client": {AccessToken: "revoked-token", ExpiresAt: now.Add(60 * 24 * time.Hour)}}
+	})
+	old := stateStore
+	stateStore = store
+	t.Cleanup(func() { stateStore = old })
+
+	user, err := getTwitchUser(context.Background(), "somechannel", "client", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user == nil || user.ID != "42" {
+		t.Fatalf("user = %+v", user)
+	}
+	if n := len(helix.Requests("/oauth2/token")); n != 1 {
+		t.Fatalf("token minted %d times", n)
+	}
+	if n := len(helix.Requests("/helix/users")); n != 2 {
+		t.Fatalf("users requested %d times", n)
+	}
+	var stored StoredToken
+	store.View(func(st *State) { stored = st.Tokens["client"] })
+	if stored.AccessToken != "fake-token" {
+		t.Fatalf("persisted token = %q", stored.AccessToken)
+	}
+	if token, _ := cachedAccessToken("client"); token != "fake-token" {
+		t.Fatalf("cached token = %q", token)
+	}
+}
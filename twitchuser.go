@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const userTokenKey = "user"
+
+var userTokenMu sync.Mutex
+
+func getUserToken(cfg *Config) (StoredToken, error) {
+	var tok StoredToken
+	if stateStore != nil {
+		stateStore.View(func(st *State) { tok = st.Tokens[userTokenKey] })
+	}
+	if tok.AccessToken == "" {
+		tok = StoredToken{AccessToken: cfg.Twitch.UserToken, RefreshToken: cfg.Twitch.RefreshToken}
+	}
+	if tok.AccessToken == "" {
+		return tok, errors.New("twitch user token is not configured")
+	}
+	return tok, nil
+}
+
+func refreshUserToken(ctx context.Context, cfg *Config, refreshToken string) (string, error) {
+	if refreshToken == "" {
+		return "", errors.New("twitch user token expired and no refresh token is configured")
+	}
+	form := url.Values{
+		"client_id":     {cfg.Twitch.ClientID},
+		"client_secret": {cfg.Twitch.ClientSecret},
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", twitchAuthAPI+"/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := twitchHTTP.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("user token refresh failed (%d): %s", resp.StatusCode, body)
+	}
+
+	var auth struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&auth); err != nil {
+		return "", err
+	}
+
+	if stateStore != nil {
+		err := stateStore.Update(func(st *State) {
+			if st.Tokens == nil {
+				st.Tokens = map[string]StoredToken{}
+			}
+			st.Tokens[userTokenKey] = StoredToken{
+				AccessToken:  auth.AccessToken,
+				RefreshToken: auth.RefreshToken,
+				ExpiresAt:    clock.Now().Add(time.Duration(auth.ExpiresIn) * time.Second),
+			}
+		})
+		if err != nil {
+			slog.Warn("failed to persist twitch user token", "error", err)
+		}
+	}
+	slog.Info("twitch user token refreshed")
+	return auth.AccessToken, nil
+}
+
+func twitchUserRequest(ctx context.Context, cfg *Config, method, reqURL string, body, out any) error {
+	if upstream != nil {
+		return errUpstreamMode
+	}
+	userTokenMu.Lock()
+	defer userTokenMu.Unlock()
+
+	tok, err := getUserToken(cfg)
+	if err != nil {
+		return err
+	}
+	access := tok.AccessToken
+	if !tok.ExpiresAt.IsZero() && tok.ExpiresAt.Sub(clock.Now()) < tokenRefreshMargin {
+		if access, err = refreshUserToken(ctx, cfg, tok.RefreshToken); err != nil {
+			return err
+		}
+	}
+
+	var payload []byte
+	if body != nil {
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, reqURL, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Client-ID", cfg.Twitch.ClientID)
+		req.Header.Set("Authorization", "Bearer "+access)
+
+		resp, err := twitchHTTP.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			resp.Body.Close()
+			if access, err = refreshUserToken(ctx, cfg, tok.RefreshToken); err != nil {
+				return err
+			}
+			continue
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			body, _ := io.ReadAll(resp.Body)
+			return &TwitchAPIError{StatusCode: resp.StatusCode, Body: string(body)}
+		}
+		if out == nil {
+			return nil
+		}
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+}
+
+func createClip(ctx context.Context, cfg *Config, broadcasterID string) (string, error) {
+	var resp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	reqURL := fmt.Sprintf("%s/clips?broadcaster_id=%s", helixAPI, broadcasterID)
+	if err := twitchUserRequest(ctx, cfg, "POST", reqURL, nil, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Data) == 0 {
+		return "", errors.New("create clip returned no data")
+	}
+	return resp.Data[0].ID, nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+)
+
+type TelegramUpdate struct {
+	UpdateID      int              `json:"update_id"`
+	Message       *TelegramMessage `json:"message"`
+	ChannelPost   *TelegramMessage `json:"channel_post"`
+	CallbackQuery *struct {
+		ID      string           `json:"id"`
+		From    TelegramUser     `json:"from"`
+		Message *TelegramMessage `json:"message"`
+		Data    string           `json:"data"`
+	} `json:"callback_query"`
+	MyChatMember *struct {
+		Chat          TelegramChat `json:"chat"`
+		OldChatMember ChatMember   `json:"old_chat_member"`
+		NewChatMember ChatMember   `json:"new_chat_member"`
+	} `json:"my_chat_member"`
+	MessageReaction *struct {
+		Chat        TelegramChat   `json:"chat"`
+		MessageID   int            `json:"message_id"`
+		OldReaction []ReactionType `json:"old_reaction"`
+		NewReaction []ReactionType `json:"new_reaction"`
+	} `json:"message_reaction"`
+	MessageReactionCount *struct {
+		Chat      TelegramChat `json:"chat"`
+		MessageID int          `json:"message_id"`
+		Reactions []struct {
+			Type       ReactionType `json:"type"`
+			TotalCount int          `json:"total_count"`
+		} `json:"reactions"`
+	} `json:"message_reaction_count"`
+}
+
+type ReactionType struct {
+	Type          string `json:"type"`
+	Emoji         string `json:"emoji"`
+	CustomEmojiID string `json:"custom_emoji_id"`
+}
+
+func (r ReactionType) Key() string {
+	switch r.Type {
+	case "emoji":
+		return r.Emoji
+	case "custom_emoji":
+		return "custom:" + r.CustomEmojiID
+	default:
+		return r.Type
+	}
+}
+
+type UpdateHandler func(ctx context.Context, u TelegramUpdate)
+
+type UpdatePoller struct {
+	token    string
+	client   *http.Client
+	mu       sync.Mutex
+	handlers []UpdateHandler
+	allowed  []string
+}
+
+func newUpdatePoller(token string) *UpdatePoller {
+	return &UpdatePoller{
+		token:  token,
+		client: &http.Client{Timeout: 40 * time.Second, Transport: telegramHTTP.Transport},
+	}
+}
+
+func (p *UpdatePoller) Subscribe(h UpdateHandler, updateTypes ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers = append(p.handlers, h)
+	for _, t := range updateTypes {
+		if !slices.Contains(p.allowed, t) {
+			p.allowed = append(p.allowed, t)
+		}
+	}
+}
+
+func (p *UpdatePoller) Active() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.handlers) > 0
+}
+
+func (p *UpdatePoller) Run(ctx context.Context) {
+	p.mu.Lock()
+	allowed, _ := json.Marshal(p.allowed)
+	p.mu.Unlock()
+
+	offset := 0
+	for ctx.Err() == nil {
+		updates, err := p.fetch(ctx, offset, string(allowed))
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Warn("failed to get telegram updates", "error", err)
+				sleep(ctx, 5*time.Second)
+			}
+			continue
+		}
+
+		p.mu.Lock()
+		handlers := p.handlers
+		p.mu.Unlock()
+
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			for _, h := range handlers {
+				h(ctx, u)
+			}
+		}
+	}
+}
+
+func (p *UpdatePoller) fetch(ctx context.Context, offset int, allowed string) ([]TelegramUpdate, error) {
+	q := url.Values{}
+	q.Set("offset", fmt.Sprintf("%d", offset))
+	q.Set("timeout", "30")
+	q.Set("allowed_updates", allowed)
+	reqURL := fmt.Sprintf("%s/bot%s/getUpdates?%s", telegramAPI, p.token, q.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result, err := parseTelegramResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	var updates []TelegramUpdate
+	if err := json.Unmarshal(result, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	upstreamReadTimeout = 3 * busKeepaliveInterval
+	upstreamMaxBackoff  = time.Minute
+)
+
+var (
+	errUpstreamDisconnected = errors.New("not connected to the upstream event bus")
+	errUpstreamMode         = errors.New("the twitch API is not used while upstream.url is set")
+)
+
+var upstream *UpstreamClient
+
+type UpstreamConfig struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+type UpstreamClient struct {
+	cfg      *Config
+	channels []string
+
+	mu        sync.Mutex
+	connected bool
+	streams   map[string]EventPayload
+	clips     map[string][]ClipInfo
+}
+
+func newUpstreamClient(cfg *Config, channels []string) *UpstreamClient {
+	return &UpstreamClient{cfg: cfg, channels: channels, clips: map[string][]ClipInfo{}}
+}
+
+func (u *UpstreamClient) Run(ctx context.Context) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		started := clock.Now()
+		err := u.session(ctx)
+		u.mu.Lock()
+		u.connected = false
+		u.mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		if clock.Now().Sub(started) > upstreamMaxBackoff {
+			backoff = time.Second
+		}
+		slog.Warn("upstream event bus connection lost", "error", err, "retry_in", backoff)
+		sleep(ctx, backoff)
+		backoff = min(backoff*2, upstreamMaxBackoff)
+	}
+}
+
+func (u *UpstreamClient) endpoint() (string, error) {
+	endpoint, err := url.Parse(u.cfg.Upstream.URL)
+	if err != nil {
+		return "", err
+	}
+	q := endpoint.Query()
+	q.Set("channels", strings.Join(u.channels, ","))
+	endpoint.RawQuery = q.Encode()
+	return endpoint.String(), nil
+}
+
+func (u *UpstreamClient) session(ctx context.Context) error {
+	endpoint, err := u.endpoint()
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	if u.cfg.Upstream.Token != "" {
+		header.Set("Authorization", "Bearer "+u.cfg.Upstream.Token)
+	}
+	conn, err := dialWebSocket(endpoint, header, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(upstreamReadTimeout))
+	data, err := conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+	var snapshot []EventPayload
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("invalid upstream snapshot: %w", err)
+	}
+	u.mu.Lock()
+	u.streams = make(map[string]EventPayload, len(snapshot))
+	for _, p := range snapshot {
+		u.streams[strings.ToLower(p.Channel)] = p
+	}
+	u.connected = true
+	u.mu.Unlock()
+	slog.Info("connected to upstream event bus", "live", len(snapshot))
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(upstreamReadTimeout))
+		data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		var p EventPayload
+		if err := json.Unmarshal(data, &p); err != nil {
+			slog.Warn("skipping invalid upstream event", "error", err)
+			continue
+		}
+		u.apply(p)
+	}
+}
+
+func (u *UpstreamClient) apply(p EventPayload) {
+	channel := strings.ToLower(p.Channel)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if p.Type == EventStreamStarted {
+		delete(u.clips, channel)
+	}
+	if p.Clips != nil {
+		u.clips[channel] = p.Clips
+	}
+	switch p.Type {
+	case EventStreamStarted, EventStreamUpdated, EventGameChanged, EventTagsChanged:
+		u.streams[channel] = p
+	case EventStreamEnded:
+		delete(u.streams, channel)
+	}
+}
+
+func (u *UpstreamClient) Clips(channel string) []ClipInfo {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.clips[strings.ToLower(channel)]
+}
+
+func (u *UpstreamClient) Source(channel string) StreamSource {
+	return upstreamSource{client: u, channel: strings.ToLower(channel)}
+}
+
+type upstreamSource struct {
+	client  *UpstreamClient
+	channel string
+}
+
+func (s upstreamSource) lookup() (EventPayload, bool, error) {
+	u := s.client
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.connected {
+		return EventPayload{}, false, errUpstreamDisconnected
+	}
+	p, ok := u.streams[s.channel]
+	return p, ok, nil
+}
+
+func (s upstreamSource) Stream(ctx context.Context) (*StreamInfo, error) {
+	p, live, err := s.lookup()
+	if err != nil || !live {
+		return nil, err
+	}
+	return &StreamInfo{
+		Channel:   p.Channel,
+		URL:       p.URL,
+		Title:     p.Title,
+		Game:      p.Game,
+		Viewers:   p.Viewers,
+		Uptime:    formatDuration(clock.Now().Sub(p.StartedAt), s.client.cfg.Language),
+		Tags:      p.Tags,
+		StartedAt: p.StartedAt,
+		Mature:    p.Mature,
+	}, nil
+}
+
+func (s upstreamSource) BroadcasterID(ctx context.Context) (string, error) {
+	p, live, err := s.lookup()
+	if err != nil {
+		return "", err
+	}
+	if !live || p.Broadcaster == "" {
+		return "", fmt.Errorf("upstream has no broadcaster ID for %s", s.channel)
+	}
+	return p.Broadcaster, nil
+}
+
+func streamSourceFor(cfg *Config) StreamSource {
+	switch {
+	case upstream != nil:
+		return upstream.Source(cfg.Twitch.Channel)
+	case streamHub != nil:
+		return streamHub.Source(cfg.Twitch.Channel)
+	}
+	return twitchSource{cfg}
+}
+
+func disableHelixFeatures(cfg *Config) {
+	features := []struct {
+		name    string
+		enabled *bool
+	}{
+		{"teaser", &cfg.Teaser.Enabled},
+		{"squad", &cfg.Squad.Enabled},
+		{"auto_clip", &cfg.AutoClip.Enabled},
+		{"chat_modes", &cfg.ChatModes.Enabled},
+		{"moderation", &cfg.Moderation.Enabled},
+		{"chat_bridge.say", &cfg.ChatBridge.Say},
+		{"follower_goal", &cfg.FollowerGoal.Enabled},
+		{"quiet", &cfg.Quiet.Enabled},
+	}
+	var disabled []string
+	for _, f := range features {
+		if *f.enabled {
+			*f.enabled = false
+			disabled = append(disabled, f.name)
+		}
+	}
+	if len(cfg.Multistream.Partners) > 0 {
+		cfg.Multistream.Partners = nil
+		disabled = append(disabled, "multistream.partners")
+	}
+	if len(disabled) > 0 {
+		slog.Warn("features that call the twitch API are disabled while upstream.url is set", "features", disabled)
+	}
+}
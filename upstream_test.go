@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServeEnricherAddsMatureAndClips(t *testing.T) {
+	helix := newFakeHelix(t)
+	start := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	useManualClock(t, start.Add(30*time.Minute))
+	helix.SetContentLabels("42", "MatureGame")
+	helix.SetClips("42", TwitchClip{URL: "https://clips.twitch.tv/abc", Title: "Funny moment", CreatorName: "viewer", ViewCount: 7})
+
+	cfg := loadTestConfig(t, testConfigJSON)
+	feed := &EventFeed{}
+	events, unsubscribe := feed.Subscribe()
+	defer unsubscribe()
+	enricher := newServeEnricher(cfg, feed)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	info := &StreamInfo{Channel: "somechannel", Title: "Title", StartedAt: start}
+	session := &StreamSession{BroadcasterID: "42", StartTime: start}
+	publish := func(typ EventType, at time.Duration) EventPayload {
+		t.Helper()
+		ev := Event{Type: typ, Time: start.Add(at), Channel: "somechannel", Session: session}
+		if typ != EventStreamEnded {
+			ev.Info = info
+		}
+		enricher.Handle(ctx, ev)
+		select {
+		case p := <-events:
+			return p
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no payload for %s", typ)
+			return EventPayload{}
+		}
+	}
+	requests := func() (int, int) {
+		return len(helix.Requests("/helix/channels")), len(helix.Requests("/helix/clips"))
+	}
+
+	enricher.Handle(ctx, Event{Type: EventStreamStarted, Time: start, Channel: "somechannel", Info: info, Session: session})
+	if labels, clips := requests(); labels+clips != 0 {
+		t.Fatalf("Handle called the twitch API on the bus: %d labels, %d clips", labels, clips)
+	}
+	go enricher.Run(ctx)
+	if p := <-events; !p.Mature || p.Clips != nil {
+		t.Fatalf("start payload %+v", p)
+	}
+
+	if p := publish(EventStreamUpdated, 30*time.Second); !p.Mature || !info.Mature || p.Clips != nil {
+		t.Fatalf("first update %+v, info mature %v", p, info.Mature)
+	}
+	if p := publish(EventStreamUpdated, 70*time.Second); len(p.Clips) != 1 || p.Clips[0].Views != 7 {
+		t.Fatalf("due update clips %+v", p.Clips)
+	}
+	if p := publish(EventStreamUpdated, 90*time.Second); len(p.Clips) != 1 {
+		t.Fatalf("cached clips %+v", p.Clips)
+	}
+	if labels, clips := requests(); labels != 1 || clips != 1 {
+		t.Fatalf("after updates: %d labels, %d clips requests", labels, clips)
+	}
+
+	p := publish(EventStreamEnded, 100*time.Second)
+	if len(p.Clips) != 1 || p.Clips[0].URL != "https://clips.twitch.tv/abc" {
+		t.Fatalf("end payload clips %+v", p.Clips)
+	}
+	if labels, clips := requests(); labels != 1 || clips != 2 {
+		t.Fatalf("after end: %d labels, %d clips requests", labels, clips)
+	}
+}
+
+func TestUpstreamModeSkipsHelix(t *testing.T) {
+	helix := newFakeHelix(t)
+	bot := newFakeBotAPI(t)
+	start := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	c := useManualClock(t, start)
+
+	cfg := loadTestConfig(t, `{
+		"twitch": {"channel": "somechannel"},
+		"telegram": {"bot_token": "123:abc", "chat_id": -100500},
+		"language": "en",
+		"check_interval_seconds": 60,
+		"update_interval_minutes": 1,
+		"upstream": {"url": "ws://127.0.0.1:1/bus"},
+		"mature": {"enabled": true, "badge": "18+"},
+		"squad": {"enabled": true},
+		"multistream": {"partners": ["partner"]}
+	}`)
+	disableHelixFeatures(cfg)
+	if cfg.Squad.Enabled || cfg.Multistream.Partners != nil {
+		t.Fatalf("helix features left enabled: squad %v, partners %v", cfg.Squad.Enabled, cfg.Multistream.Partners)
+	}
+	upstream = newUpstreamClient(cfg, monitoredChannels(cfg))
+	t.Cleanup(func() { upstream = nil })
+	upstream.mu.Lock()
+	upstream.connected = true
+	upstream.streams = map[string]EventPayload{}
+	upstream.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	bus := &EventBus{}
+	bus.Subscribe(newTelegramNotifier(cfg, nil, nil, nil, nil).Handle)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		monitorLoop(ctx, cfg, streamSourceFor(cfg), bus, newMonitorControl())
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	tick := func() {
+		c.BlockUntil(1)
+		c.Advance(time.Duration(cfg.CheckInterval) * time.Second)
+	}
+	payload := EventPayload{Channel: "somechannel", Broadcaster: "42", Title: "First title", Game: "Just Chatting", StartedAt: start, Mature: true}
+
+	c.BlockUntil(1)
+	payload.Type = EventStreamStarted
+	upstream.apply(payload)
+	tick()
+	sent := bot.WaitFor(t, 1, "sendPhoto")[0]
+	if !strings.Contains(sent.Params["caption"], "First title") || !strings.Contains(sent.Params["caption"], "18+") {
+		t.Fatalf("unexpected announcement %v", sent.Params)
+	}
+
+	payload.Type = EventStreamUpdated
+	payload.Clips = []ClipInfo{{URL: "https://clips.twitch.tv/abc", Title: "Funny moment"}}
+	upstream.apply(payload)
+	tick()
+	edit := bot.WaitFor(t, 1, "editMessageCaption", "editMessageMedia")[0]
+	if !strings.Contains(edit.Params["caption"]+edit.Params["media"], "clips.twitch.tv/abc") {
+		t.Fatalf("update has no upstream clips %v", edit.Params)
+	}
+
+	payload.Type = EventStreamEnded
+	upstream.apply(payload)
+	tick()
+	edits := bot.WaitFor(t, 2, "editMessageCaption", "editMessageMedia")
+	end := edits[len(edits)-1]
+	if !strings.Contains(end.Params["caption"], "OFFLINE") || !strings.Contains(end.Params["caption"], "clips.twitch.tv/abc") {
+		t.Fatalf("unexpected end message %v", end.Params)
+	}
+
+	if reqs := append(helix.Requests("/helix/"), helix.Requests("/oauth2/")...); len(reqs) != 0 {
+		t.Fatalf("upstream mode called the twitch API: %v", reqs)
+	}
+}
+
+func TestUpstreamClientSendsTokenInHeader(t *testing.T) {
+	bus := newBusServer("secret", &EventFeed{}, map[string]*LiveState{})
+	requests := make(chan *http.Request, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.Clone(context.Background())
+		bus.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	endpoint := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	if conn, err := dialWebSocket(endpoint, nil, time.Second); err == nil {
+		conn.Close()
+		t.Fatal("connected without a token")
+	}
+	<-requests
+
+	cfg := loadTestConfig(t, `{"twitch": {"channel": "somechannel"}, "upstream": {"url": "`+endpoint+`", "token": "secret"}}`)
+	client := newUpstreamClient(cfg, monitoredChannels(cfg))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	r := <-requests
+	if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+		t.Fatalf("Authorization = %q", got)
+	}
+	if strings.Contains(r.URL.RawQuery, "secret") {
+		t.Fatalf("token leaked into the query: %s", r.URL.RawQuery)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		client.mu.Lock()
+		connected := client.connected
+		client.mu.Unlock()
+		if connected {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("client did not connect")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}